@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const annotationKeyPattern = `^[\w.-]{1,64}$`
+
+var annotationKeyRegex = regexp.MustCompile(annotationKeyPattern)
+
+// Annotation is a user value attached to satellites with given name, e.g. a note or a contract reference. Name is
+// the identity of satellites, so annotations outlive closing and reopening of satellites and sync never changes
+// them.
+type Annotation struct {
+	Name    string `db:"_name" json:"name"`
+	Key     string `db:"_key" json:"key"`
+	Value   string `db:"_value" json:"value"`
+	Updated string `db:"_updated" json:"updated"`
+}
+
+// checkAnnotationKey returns error if the key cannot be used as annotation key.
+func checkAnnotationKey(key string) error {
+	if !annotationKeyRegex.MatchString(key) {
+		return fmt.Errorf("annotation key %q doesn't match regex '%s'", key, annotationKeyPattern)
+	}
+	return nil
+}
+
+// groupAnnotations returns annotation values grouped by satellite name and key.
+func groupAnnotations(annotations []Annotation) map[string]map[string]string {
+	byName := make(map[string]map[string]string)
+	for _, annotation := range annotations {
+		if byName[annotation.Name] == nil {
+			byName[annotation.Name] = make(map[string]string)
+		}
+		byName[annotation.Name][annotation.Key] = annotation.Value
+	}
+	return byName
+}
+
+// attachAnnotations sets annotations to satellites of the list by their names.
+func attachAnnotations(list []Satellite, annotations []Annotation) {
+	byName := groupAnnotations(annotations)
+	for i := range list {
+		list[i].Annotations = byName[list[i].GetName()]
+	}
+}
+
+// attachVersionAnnotations sets current annotations to satellite versions of the list by their names.
+func attachVersionAnnotations(list []SatelliteVersion, annotations []Annotation) {
+	byName := groupAnnotations(annotations)
+	for i := range list {
+		list[i].Annotations = byName[list[i].GetName()]
+	}
+}
+
+// GetAnnotation returns annotation value with given key, empty string if there is no such annotation.
+func (ptr *Satellite) GetAnnotation(key string) string {
+	return ptr.Annotations[key]
+}
+
+// FormatAnnotations returns annotations of the satellite as key=value pairs ordered by key.
+func (ptr *Satellite) FormatAnnotations() string {
+	keys := make([]string, 0, len(ptr.Annotations))
+	for key := range ptr.Annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+ptr.Annotations[key])
+	}
+	return strings.Join(pairs, "; ")
+}
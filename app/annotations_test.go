@@ -0,0 +1,79 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func testAnnotations(t *testing.T, repository SatelliteRepository) {
+	status := syncTestRepository(t, repository, []Satellite{makeSat("one", 1), makeSat("two", 2)}, true, 100)
+	require.True(t, status.Succeeded())
+
+	require.NoError(t, repository.SaveAnnotation(Annotation{Name: "one", Key: "contract", Value: "C-1",
+		Updated: "2020-01-01 00:00:00"}))
+	require.NoError(t, repository.SaveAnnotation(Annotation{Name: "one", Key: "note", Value: "old",
+		Updated: "2020-01-01 00:00:00"}))
+	require.NoError(t, repository.SaveAnnotation(Annotation{Name: "one", Key: "note", Value: "leased",
+		Updated: "2020-01-02 00:00:00"}))
+
+	// closing and reopening the satellite must keep its annotations
+	status = syncTestRepository(t, repository, []Satellite{makeSat("two", 2)}, true, 100)
+	require.True(t, status.Succeeded())
+	status = syncTestRepository(t, repository, []Satellite{makeSat("one", 1), makeSat("two", 2)}, true, 100)
+	require.True(t, status.Succeeded())
+
+	active, err := repository.LoadActive()
+	require.NoError(t, err)
+	require.Len(t, active, 2)
+	assert.Equal(t, map[string]string{"contract": "C-1", "note": "leased"}, active[0].Annotations)
+	assert.Equal(t, "contract=C-1; note=leased", active[0].FormatAnnotations())
+	assert.Nil(t, active[1].Annotations)
+
+	versions, err := repository.LoadAsOf(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, "leased", versions[0].GetAnnotation("note"))
+
+	deleted, err := repository.DeleteAnnotation("one", "contract")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = repository.DeleteAnnotation("one", "contract")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	annotations, err := repository.LoadAnnotations()
+	require.NoError(t, err)
+	assert.Equal(t, []Annotation{{Name: "one", Key: "note", Value: "leased", Updated: "2020-01-02 00:00:00"}},
+		annotations)
+}
+
+func TestSQLRepositoryAnnotations(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	testAnnotations(t, repository)
+}
+
+func TestFileRepositoryAnnotations(t *testing.T) {
+	for _, name := range []string{"satellites.json", "satellites.csv"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			repository, path, cleanup := openTestFileRepository(t, name)
+			defer cleanup()
+
+			testAnnotations(t, repository)
+
+			reopened, err := openFileRepository(&FileProperties{Path: path})
+			require.NoError(t, err)
+			assert.Equal(t, repository.state.Annotations, reopened.state.Annotations)
+		})
+	}
+}
+
+func TestCheckAnnotationKey(t *testing.T) {
+	assert.NoError(t, checkAnnotationKey("contract.id"))
+	assert.Error(t, checkAnnotationKey(""))
+	assert.Error(t, checkAnnotationKey("two words"))
+}
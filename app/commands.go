@@ -0,0 +1,471 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runCommand runs command given in args, sync is the default one.
+func runCommand(args []string) {
+	command := "sync"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "sync":
+		syncCommand(args)
+	case "history":
+		historyCommand(args)
+	case "migrate":
+		migrateCommand(args)
+	case "tags":
+		tagsCommand(args)
+	case "asof":
+		asOfCommand(args)
+	case "runs":
+		runsCommand(args)
+	case "annotate":
+		annotateCommand(args)
+	case "export":
+		exportCommand(args)
+	case "look-angles":
+		lookAnglesCommand(args)
+	case "usals":
+		usalsCommand(args)
+	case "site":
+		siteCommand(args)
+	case "slots":
+		slotsCommand(args)
+	case "satcat":
+		satcatCommand(args)
+	default:
+		log.Fatalf("unknown command %s, available commands: sync, history, migrate, tags, asof, runs, annotate, "+
+			"export, look-angles, usals, site, slots, satcat", command)
+	}
+}
+
+// syncCommand syncs database with online pages. Unknown arguments are refused, so a misplaced flag never runs
+// sync without it.
+func syncCommand(args []string) {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	forced := flags.Bool("force", *force, "apply changes even if safety guard limits are exceeded")
+	_ = flags.Parse(args)
+	if flags.NArg() > 0 {
+		log.Fatalf("unexpected arguments %v, usage: sat-parser sync [--force]", flags.Args())
+	}
+
+	syncSatellites(*forced)
+}
+
+// historyCommand prints timeline of field changes of the satellite with given name.
+func historyCommand(args []string) {
+	if len(args) != 1 {
+		log.Fatal("usage: sat-parser history <name>")
+	}
+
+	records := LoadSatelliteHistory(args[0])
+	if len(records) == 0 {
+		fmt.Printf("no changes of %s found\n", args[0])
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "CHANGED\tFIELD\tOLD VALUE\tNEW VALUE\tRUN")
+	for _, record := range records {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n",
+			record.Changed, record.Field, record.OldValue, record.NewValue, record.RunID)
+	}
+	_ = writer.Flush()
+}
+
+// migrateCommand applies or reverts schema migrations or prints their status.
+func migrateCommand(args []string) {
+	usage := "usage: sat-parser migrate up|down [steps]|status"
+	if len(args) == 0 {
+		log.Fatal(usage)
+	}
+
+	migrator, ok := getUncheckedRepository().(Migrator)
+	if !ok {
+		log.Fatalf("storage %s does not support migrations", getProperties().Storage.Driver)
+	}
+
+	switch args[0] {
+	case "up":
+		count, err := migrator.MigrateUp()
+		if err != nil {
+			log.WithError(err).Fatal("migration failed")
+		}
+		log.Infof("%d migrations applied", count)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				log.Fatal(usage)
+			}
+		}
+		count, err := migrator.MigrateDown(steps)
+		if err != nil {
+			log.WithError(err).Fatal("migration failed")
+		}
+		log.Infof("%d migrations reverted", count)
+
+	case "status":
+		statuses, err := migrator.MigrationStatus()
+		if err != nil {
+			log.WithError(err).Fatal("cannot get migration status")
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(writer, "VERSION\tDESCRIPTION\tAPPLIED")
+		for _, status := range statuses {
+			applied := status.Applied
+			if applied == "" {
+				applied = "pending"
+			}
+			_, _ = fmt.Fprintf(writer, "%d\t%s\t%s\n", status.Version, status.Description, applied)
+		}
+		_ = writer.Flush()
+
+	default:
+		log.Fatal(usage)
+	}
+}
+
+// tagsCommand prints tags of the satellite with given name or adds and removes its manual tags.
+func tagsCommand(args []string) {
+	usage := "usage: sat-parser tags <name> [add|remove <tag>...]"
+	if len(args) != 1 && len(args) < 3 {
+		log.Fatal(usage)
+	}
+
+	var sat *Satellite
+	satellites := LoadDbSatellites()
+	for i := range satellites {
+		if satellites[i].GetName() == args[0] {
+			sat = &satellites[i]
+			break
+		}
+	}
+	if sat == nil {
+		log.Fatalf("active satellite %s not found", args[0])
+	}
+
+	if len(args) > 1 {
+		manualTags := sat.GetManualTags()
+		switch args[1] {
+		case "add":
+			manualTags = append(manualTags, args[2:]...)
+		case "remove":
+			removed := make(map[string]bool)
+			for _, tag := range args[2:] {
+				removed[tag] = true
+			}
+			var kept []string
+			for _, tag := range manualTags {
+				if !removed[tag] {
+					kept = append(kept, tag)
+				}
+			}
+			manualTags = kept
+		default:
+			log.Fatal(usage)
+		}
+
+		if formatTags(manualTags) != sat.ManualTags {
+			if err := getRepository().SaveManualTags(sat.GetName(), manualTags); err != nil {
+				log.WithError(err).Fatal("cannot save manual tags")
+			}
+			sat.SetManualTags(manualTags)
+		}
+	}
+
+	fmt.Printf("tags: %s\nmanual tags: %s\n", sat.Tags, sat.ManualTags)
+}
+
+// asOfCommand prints satellites which were active at given time, all of them or the one with given name.
+func asOfCommand(args []string) {
+	if len(args) != 1 && len(args) != 2 {
+		log.Fatal("usage: sat-parser asof <time> [name]")
+	}
+
+	moment, err := ParseMoment(args[0])
+	if err != nil {
+		log.WithError(err).Fatal("wrong time")
+	}
+
+	var versions []SatelliteVersion
+	for _, version := range LoadCatalogueAsOf(moment) {
+		if len(args) == 1 || version.GetName() == args[1] {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		fmt.Printf("no satellites active at %s found\n", formatMoment(moment))
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "POSITION\tNAME\tBAND\tURL\tTAGS\tVALID FROM\tVALID TO\tANNOTATIONS")
+	for _, version := range versions {
+		validTo := "now"
+		if version.ValidTo != nil {
+			validTo = *version.ValidTo
+		}
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", formatOrbitalPosition(version.GetPosition()),
+			version.GetName(), version.GetBand(), version.GetURL(), version.Tags, version.ValidFrom, validTo,
+			version.FormatAnnotations())
+	}
+	_ = writer.Flush()
+}
+
+// runsCommand prints the latest sync runs or details of the run with given id.
+func runsCommand(args []string) {
+	if len(args) > 1 {
+		log.Fatal("usage: sat-parser runs [run id]")
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer func() { _ = writer.Flush() }()
+
+	if len(args) == 0 {
+		runs := LoadSyncRuns(20)
+		_, _ = fmt.Fprintln(writer, "RUN\tSTARTED\tSTATUS\tPARSED\tERRORS\tINSERTED\tCLOSED\tUPDATED\tFAILED")
+		for _, run := range runs {
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\n", run.RunID, run.Started, run.Status,
+				run.Parsed, run.ParseErrors, run.Inserted, run.Closed, run.Updated, run.Failed)
+		}
+		return
+	}
+
+	run := LoadSyncRun(args[0])
+	if run == nil {
+		log.Fatalf("run %s not found", args[0])
+	}
+
+	_, _ = fmt.Fprintf(writer, "run:\t%s\nstarted:\t%s\nfinished:\t%s\nstatus:\t%s\nrevision:\t%s\n"+
+		"config hash:\t%s\nparsed:\t%d\nparse errors:\t%d\ninserted:\t%d\nclosed:\t%d\nupdated:\t%d\n"+
+		"relocated:\t%d\nfailed:\t%d\n", run.RunID, run.Started, run.Finished, run.Status, run.Revision,
+		run.ConfigHash, run.Parsed, run.ParseErrors, run.Inserted, run.Closed, run.Updated, run.Relocated, run.Failed)
+
+	pages, err := run.GetPages()
+	if err != nil {
+		log.WithError(err).Fatal("cannot read pages of the run")
+	}
+	_, _ = fmt.Fprintln(writer, "\nPAGE\tSTATUS\tBYTES\tROWS\tPARSED\tERROR")
+	for _, page := range pages {
+		_, _ = fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%d\t%s\n", page.URL, page.Status, page.Bytes, page.Rows,
+			page.Parsed, page.Error)
+	}
+}
+
+// annotateCommand prints annotations of satellites with given name or sets and removes one of them. Only active
+// satellites can get new annotations, existing ones are kept while satellites are closed.
+func annotateCommand(args []string) {
+	usage := "usage: sat-parser annotate <name> [set <key> <value>...|unset <key>]"
+	if len(args) != 1 && !(len(args) >= 4 && args[1] == "set") && !(len(args) == 3 && args[1] == "unset") {
+		log.Fatal(usage)
+	}
+	name := args[0]
+
+	if len(args) > 1 {
+		key := args[2]
+		if err := checkAnnotationKey(key); err != nil {
+			log.WithError(err).Fatal("wrong annotation key")
+		}
+
+		switch args[1] {
+		case "set":
+			found := false
+			for _, sat := range LoadDbSatellites() {
+				if sat.GetName() == name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				log.Fatalf("active satellite %s not found", name)
+			}
+
+			annotation := Annotation{Name: name, Key: key, Value: strings.Join(args[3:], " "),
+				Updated: formatMoment(time.Now())}
+			if err := getRepository().SaveAnnotation(annotation); err != nil {
+				log.WithError(err).Fatal("cannot save annotation")
+			}
+
+		case "unset":
+			deleted, err := getRepository().DeleteAnnotation(name, key)
+			if err != nil {
+				log.WithError(err).Fatal("cannot delete annotation")
+			}
+			if !deleted {
+				log.Warnf("annotation %s of %s not found", key, name)
+			}
+		}
+	}
+
+	annotations := LoadSatelliteAnnotations(name)
+	if len(annotations) == 0 {
+		fmt.Printf("no annotations of %s found\n", name)
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "KEY\tVALUE\tUPDATED")
+	for _, annotation := range annotations {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\n", annotation.Key, annotation.Value, annotation.Updated)
+	}
+	_ = writer.Flush()
+}
+
+// observerFlags adds command line options of the observer location with defaults from observer settings.
+func observerFlags(flags *flag.FlagSet) *Observer {
+	observer := getProperties().Observer
+	flags.Float64Var(&observer.Latitude, "lat", observer.Latitude, "latitude in degrees, north is positive")
+	flags.Float64Var(&observer.Longitude, "lon", observer.Longitude, "longitude in degrees, east is positive")
+	flags.Float64Var(&observer.Altitude, "alt", observer.Altitude, "altitude in meters")
+	flags.Float64Var(&observer.Declination, "declination", observer.Declination,
+		"magnetic declination in degrees, east is positive")
+	flags.Float64Var(&observer.MinElevation, "min-elevation", observer.MinElevation,
+		"minimal elevation of visible satellites in degrees")
+	return &observer
+}
+
+// lookAnglesCommand prints directions from the observer to every active satellite.
+func lookAnglesCommand(args []string) {
+	flags := flag.NewFlagSet("look-angles", flag.ExitOnError)
+	observer := observerFlags(flags)
+	visibleOnly := flags.Bool("visible", false, "print visible satellites only")
+	_ = flags.Parse(args)
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "POSITION\tNAME\tAZIMUTH\tMAGNETIC\tELEVATION\tSKEW\tVISIBLE")
+	for _, sat := range LoadDbSatellites() {
+		angle := observer.LookAngles(sat.GetPosition())
+		if *visibleOnly && !angle.Visible {
+			continue
+		}
+
+		visible := "no"
+		if angle.Visible {
+			visible = "yes"
+		}
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%.1f\t%.1f\t%.1f\t%.1f\t%s\n", formatOrbitalPosition(sat.GetPosition()),
+			sat.GetName(), angle.Azimuth, angle.MagneticAzimuth, angle.Elevation, angle.Skew, visible)
+	}
+	_ = writer.Flush()
+}
+
+// usalsCommand prints USALS motor angles from the observer to every visible active satellite.
+func usalsCommand(args []string) {
+	flags := flag.NewFlagSet("usals", flag.ExitOnError)
+	observer := observerFlags(flags)
+	_ = flags.Parse(args)
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "POSITION\tNAME\tMOTOR ANGLE")
+	for _, sat := range LoadDbSatellites() {
+		if observer.LookAngles(sat.GetPosition()).Visible {
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\n", formatOrbitalPosition(sat.GetPosition()), sat.GetName(),
+				formatMotorAngle(observer.MotorAngle(sat.GetPosition())))
+		}
+	}
+	_ = writer.Flush()
+}
+
+// siteCommand renders active satellites from storage into a static catalogue site.
+func siteCommand(args []string) {
+	flags := flag.NewFlagSet("site", flag.ExitOnError)
+	output := flags.String("output", "site", "directory of the site")
+	format := flags.String("format", siteFormatHTML, "format of pages: html or markdown")
+	runs := flags.Int("runs", 20, "count of the latest sync runs on the recent changes page")
+	withTransponders := flags.Bool("transponders", false, "collect transponders from satellite pages")
+	_ = flags.Parse(args)
+
+	catalogue := LoadSiteCatalogue(*runs, *withTransponders)
+	count, err := WriteSite(*output, *format, catalogue)
+	if err != nil {
+		log.WithError(err).Fatal("cannot write site")
+	}
+	log.Infof("%d pages of %d satellites written to %s", count, len(catalogue.Satellites), *output)
+}
+
+// slotsCommand prints active satellites grouped into orbital slots with occupancy changes over the latest runs.
+func slotsCommand(args []string) {
+	flags := flag.NewFlagSet("slots", flag.ExitOnError)
+	tolerance := flags.Float64("tolerance", getProperties().Slots.Tolerance,
+		"max distance in degrees between satellites of a slot")
+	format := flags.String("format", slotsFormatText, "output format: text or json")
+	runs := flags.Int("runs", 20, "count of the latest sync runs to show occupancy changes, zero disables them")
+	_ = flags.Parse(args)
+
+	slots := ClusterSlots(LoadDbSatellites(), *tolerance, getProperties().Slots.InclinedTag)
+	if *runs > 0 {
+		AddSlotOccupancy(slots, LoadSlotSnapshots(*runs), *tolerance)
+	}
+	if err := WriteSlots(os.Stdout, slots, *format); err != nil {
+		log.WithError(err).Fatal("cannot write slots")
+	}
+}
+
+// satcatCommand matches active satellites to SATCAT objects and saves accepted identifiers, or lists saved ones.
+func satcatCommand(args []string) {
+	usage := "usage: sat-parser satcat match [-dry-run] <file> | list"
+	if len(args) == 0 {
+		log.Fatal(usage)
+	}
+
+	switch args[0] {
+	case "match":
+		flags := flag.NewFlagSet("satcat match", flag.ExitOnError)
+		dryRun := flags.Bool("dry-run", false, "print matches without saving them")
+		_ = flags.Parse(args[1:])
+		if flags.NArg() != 1 {
+			log.Fatal(usage)
+		}
+
+		entries := LoadSatcatFile(flags.Arg(0))
+		result := getSatcatMatcher().Match(LoadDbSatellites(), entries, time.Now())
+		if err := WriteSatcatResult(os.Stdout, result); err != nil {
+			log.WithError(err).Fatal("cannot write matches")
+		}
+		if *dryRun {
+			return
+		}
+		if err := getRepository().SaveIdentifiers(result.Matched); err != nil {
+			log.WithError(err).Fatal("cannot save identifiers")
+		}
+		log.Infof("%d identifiers saved, %d satellites need review, %d satellites have no candidates",
+			len(result.Matched), len(result.Ambiguous), len(result.Unmatched))
+
+	case "list":
+		identifiers, err := getRepository().LoadIdentifiers()
+		if err != nil {
+			log.WithError(err).Fatal("critical error, shutting down ...")
+		}
+		if len(identifiers) == 0 {
+			fmt.Println("no identifiers found")
+			return
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(writer, "NAME\tNORAD\tCOSPAR\tSATCAT NAME\tCONFIDENCE\tMATCHED")
+		for _, identifier := range identifiers {
+			_, _ = fmt.Fprintf(writer, "%s\t%d\t%s\t%s\t%.2f\t%s\n", identifier.Name, identifier.NoradID,
+				identifier.CosparID, identifier.SatcatName, identifier.Confidence, normalizeMoment(identifier.Matched))
+		}
+		_ = writer.Flush()
+
+	default:
+		log.Fatal(usage)
+	}
+}
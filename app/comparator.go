@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math"
+	"strings"
+)
+
+// NumberComparison holds options of numeric field comparison.
+type NumberComparison struct {
+	Ignore    bool    `hocon:"node=ignore,default=false"`
+	Tolerance float64 `hocon:"node=tolerance,default=0"`
+}
+
+// Equal returns true if the field is ignored or values differ not more than tolerance.
+func (ptr *NumberComparison) Equal(a, b float64) bool {
+	return ptr.Ignore || math.Abs(a-b) <= ptr.Tolerance
+}
+
+// EqualPositions returns true if the field is ignored or orbital positions differ not more than tolerance. The
+// difference is measured across the antimeridian if it is shorter, so 180 and -180 are the same position.
+func (ptr *NumberComparison) EqualPositions(a, b float64) bool {
+	return ptr.Ignore || math.Abs(positionDelta(a, b)) <= ptr.Tolerance
+}
+
+// TextComparison holds options of text field comparison.
+type TextComparison struct {
+	Ignore         bool `hocon:"node=ignore,default=false"`
+	IgnoreCase     bool `hocon:"node=ignoreCase,default=false"`
+	CollapseSpaces bool `hocon:"node=collapseSpaces,default=false"`
+}
+
+// normalize trims the value, collapses inner whitespaces and lowers case if needed.
+func (ptr *TextComparison) normalize(value string) string {
+	if ptr.CollapseSpaces {
+		value = strings.Join(strings.Fields(value), " ")
+	}
+	if ptr.IgnoreCase {
+		value = strings.ToLower(value)
+	}
+	return value
+}
+
+// Equal returns true if the field is ignored or normalized values are the same.
+func (ptr *TextComparison) Equal(a, b string) bool {
+	return ptr.Ignore || ptr.normalize(a) == ptr.normalize(b)
+}
+
+// Comparator compares satellites field by field ignoring insignificant differences. Zero value compares
+// all fields strictly. Satellites are matched by name before comparison, so name is never compared.
+type Comparator struct {
+	Position NumberComparison `hocon:"node=position"`
+	URL      TextComparison   `hocon:"node=url"`
+	Band     TextComparison   `hocon:"node=band"`
+	Tags     TextComparison   `hocon:"node=tags"`
+}
+
+// Differences returns names of meaningfully changed fields between a and b.
+func (ptr *Comparator) Differences(a, b *Satellite) []string {
+	var fields []string
+	if !ptr.Position.EqualPositions(a.GetPosition(), b.GetPosition()) {
+		fields = append(fields, fieldPosition)
+	}
+	if !ptr.URL.Equal(a.GetURL(), b.GetURL()) {
+		fields = append(fields, fieldURL)
+	}
+	if !ptr.Band.Equal(a.GetBand(), b.GetBand()) {
+		fields = append(fields, fieldBand)
+	}
+	if !ptr.Tags.Equal(a.Tags, b.Tags) {
+		fields = append(fields, fieldTags)
+	}
+	return fields
+}
+
+// Equal returns true if there are no meaningful differences between a and b.
+func (ptr *Comparator) Equal(a, b *Satellite) bool {
+	return len(ptr.Differences(a, b)) == 0
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNumberComparison(t *testing.T) {
+	tests := []struct {
+		name       string
+		comparison NumberComparison
+		a, b       float64
+		equal      bool
+	}{
+		{"strict same", NumberComparison{}, 13, 13, true},
+		{"strict different", NumberComparison{}, 13, 13.0000001, false},
+		{"float noise within tolerance", NumberComparison{Tolerance: 0.001}, 13.2, 13.199999809265137, true},
+		{"on the tolerance border", NumberComparison{Tolerance: 0.5}, 13, 13.5, true},
+		{"beyond tolerance", NumberComparison{Tolerance: 0.001}, 13, 13.1, false},
+		{"negative values", NumberComparison{Tolerance: 0.001}, -30, -30.0001, true},
+		{"ignored", NumberComparison{Ignore: true}, 13, 19.2, true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.equal, test.comparison.Equal(test.a, test.b), test.name)
+		assert.Equal(t, test.equal, test.comparison.Equal(test.b, test.a), test.name+" (swapped)")
+	}
+}
+
+func TestNumberComparisonOfPositions(t *testing.T) {
+	tests := []struct {
+		name       string
+		comparison NumberComparison
+		a, b       float64
+		equal      bool
+	}{
+		{"strict same", NumberComparison{}, 13, 13, true},
+		{"beyond tolerance", NumberComparison{Tolerance: 0.001}, 13, 13.1, false},
+		{"antimeridian", NumberComparison{}, 180, -180, true},
+		{"across antimeridian within tolerance", NumberComparison{Tolerance: 0.5}, 179.8, -179.9, true},
+		{"across antimeridian beyond tolerance", NumberComparison{Tolerance: 0.1}, 179.8, -179.9, false},
+		{"ignored", NumberComparison{Ignore: true}, 13, 19.2, true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.equal, test.comparison.EqualPositions(test.a, test.b), test.name)
+		assert.Equal(t, test.equal, test.comparison.EqualPositions(test.b, test.a), test.name+" (swapped)")
+	}
+}
+
+func TestTextComparison(t *testing.T) {
+	tests := []struct {
+		name       string
+		comparison TextComparison
+		a, b       string
+		equal      bool
+	}{
+		{"strict same", TextComparison{}, "Ku", "Ku", true},
+		{"strict case", TextComparison{}, "Ku", "KU", false},
+		{"strict spaces", TextComparison{}, "C Ku", "C  Ku", false},
+		{"ignore case", TextComparison{IgnoreCase: true}, "Ku", "KU", true},
+		{"ignore case keeps spaces", TextComparison{IgnoreCase: true}, "C Ku", "c  ku", false},
+		{"collapse spaces", TextComparison{CollapseSpaces: true}, " C \t Ku ", "C Ku", true},
+		{"collapse spaces keeps case", TextComparison{CollapseSpaces: true}, "C Ku", "c ku", false},
+		{"collapse spaces does not join words", TextComparison{CollapseSpaces: true}, "C Ku", "CKu", false},
+		{"both", TextComparison{IgnoreCase: true, CollapseSpaces: true}, " c  KU", "C Ku ", true},
+		{"both different", TextComparison{IgnoreCase: true, CollapseSpaces: true}, "C Ku", "C Ka", false},
+		{"ignored", TextComparison{Ignore: true}, "C", "Ku", true},
+		{"empty values", TextComparison{CollapseSpaces: true}, "", "  ", true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.equal, test.comparison.Equal(test.a, test.b), test.name)
+		assert.Equal(t, test.equal, test.comparison.Equal(test.b, test.a), test.name+" (swapped)")
+	}
+}
+
+func TestComparatorDifferences(t *testing.T) {
+	comparator := Comparator{
+		Position: NumberComparison{Tolerance: 0.001},
+		Band:     TextComparison{IgnoreCase: true, CollapseSpaces: true},
+	}
+
+	initial := makeSat("one", 13.2)
+	initial.SetBand("Ku")
+
+	noise := initial
+	noise.SetPosition(13.2000001)
+	noise.Band = "ku "
+	assert.Empty(t, comparator.Differences(&initial, &noise))
+	assert.True(t, comparator.Equal(&initial, &noise))
+
+	changed := initial
+	changed.SetPosition(13)
+	changed.URL = "https://www.base.com/one.html"
+	changed.SetBand("C")
+	assert.Equal(t, []string{fieldPosition, fieldURL, fieldBand}, comparator.Differences(&initial, &changed))
+	assert.False(t, comparator.Equal(&initial, &changed))
+
+	east, west := makeSat("antimeridian", 180), makeSat("antimeridian", -180)
+	assert.True(t, comparator.Equal(&east, &west), "180 and -180 must be the same position")
+}
+
+func TestComparatorZeroValueIsStrict(t *testing.T) {
+	comparator := Comparator{}
+
+	initial := makeSat("one", 13.2)
+	changed := initial
+	changed.Band = " "
+
+	assert.Equal(t, []string{fieldBand}, comparator.Differences(&initial, &changed))
+}
+
+func TestComparatorIgnoresSourceAndName(t *testing.T) {
+	comparator := Comparator{}
+
+	initial := makeSat("one", 13.2)
+	changed := initial
+	changed.SetSource("europe")
+	changed.Name = "One"
+
+	assert.True(t, comparator.Equal(&initial, &changed))
+}
+
+func TestFindChangedWithComparator(t *testing.T) {
+	comparator := Comparator{Position: NumberComparison{Tolerance: 0.001}}
+
+	noisy := makeSat("noisy", 13.2)
+	noisyNew := noisy
+	noisyNew.SetPosition(13.199999809265137)
+	moved := makeSat("moved", 13)
+	movedNew := moved
+	movedNew.SetPosition(9)
+
+	list1 := []Satellite{noisy, moved}
+	list2 := []Satellite{noisyNew, movedNew}
+
+	changed := FindChanged(&list1, &list2, &comparator)
+
+	if assert.Len(t, changed, 1) {
+		assert.Equal(t, moved, changed[0][0])
+		assert.Equal(t, movedNew, changed[0][1])
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+)
+
+// enigma2 codes of transponder parameters, unknown values are written as auto.
+var (
+	enigma2Polarizations = map[string]int{"H": 0, "V": 1, "L": 2, "R": 3}
+	enigma2FECs          = map[string]int{"": 0, "auto": 0, "1/2": 1, "2/3": 2, "3/4": 3, "5/6": 4, "7/8": 5,
+		"8/9": 6, "3/5": 7, "4/5": 8, "9/10": 9}
+	enigma2Systems     = map[string]int{systemDVBS: 0, systemDVBS2: 1}
+	enigma2Modulations = map[string]int{"": 0, "QPSK": 1, "8PSK": 2, "QAM16": 3, "16APSK": 4, "32APSK": 5}
+)
+
+type enigma2Satellites struct {
+	XMLName    xml.Name     `xml:"satellites"`
+	Satellites []enigma2Sat `xml:"sat"`
+}
+
+type enigma2Sat struct {
+	Name         string               `xml:"name,attr"`
+	Flags        int                  `xml:"flags,attr"`
+	Position     int                  `xml:"position,attr"`
+	Transponders []enigma2Transponder `xml:"transponder"`
+}
+
+type enigma2Transponder struct {
+	Frequency    int64 `xml:"frequency,attr"`
+	SymbolRate   int64 `xml:"symbol_rate,attr"`
+	Polarization int   `xml:"polarization,attr"`
+	FEC          int   `xml:"fec_inner,attr"`
+	System       int   `xml:"system,attr"`
+	Modulation   int   `xml:"modulation,attr"`
+}
+
+// enigma2Position returns orbital position in signed tenths of a degree, east positions are positive.
+func enigma2Position(position float64) int {
+	return int(math.Round(position * 10))
+}
+
+// formatShortPosition returns position rounded to tenths of a degree as receivers name it, e.g. 13.0E. It is built
+// from the position in tenths to match it after rounding.
+func formatShortPosition(position float64) string {
+	tenths, hemisphere := enigma2Position(position), "E"
+	if tenths < 0 {
+		tenths, hemisphere = -tenths, "W"
+	}
+	return fmt.Sprintf("%d.%d%s", tenths/10, tenths%10, hemisphere)
+}
+
+// enigma2Name returns satellite name prefixed with its position as Enigma2 images name satellites, e.g. 13.0E
+// Hot Bird 13B.
+func enigma2Name(sat *Satellite) string {
+	return formatShortPosition(sat.GetPosition()) + " " + sat.GetName()
+}
+
+func newEnigma2Transponder(transponder *Transponder) (enigma2Transponder, error) {
+	polarization, ok := enigma2Polarizations[transponder.Polarization]
+	if !ok {
+		return enigma2Transponder{}, fmt.Errorf("unknown polarization %s", transponder.Polarization)
+	}
+	system, ok := enigma2Systems[transponder.System]
+	if !ok {
+		return enigma2Transponder{}, fmt.Errorf("unknown system %s", transponder.System)
+	}
+
+	return enigma2Transponder{
+		Frequency:    transponder.Frequency,
+		SymbolRate:   transponder.SymbolRate,
+		Polarization: polarization,
+		FEC:          enigma2FECs[transponder.FEC],
+		System:       system,
+		Modulation:   enigma2Modulations[transponder.Modulation],
+	}, nil
+}
+
+// WriteEnigma2 writes satellites with their transponders found by satellite names in satellites.xml format of
+// Enigma2 images. Satellites are written in the order of the list.
+func WriteEnigma2(writer io.Writer, list []Satellite, transponders map[string][]Transponder) error {
+	document := enigma2Satellites{Satellites: make([]enigma2Sat, 0, len(list))}
+	for i := range list {
+		sat := &list[i]
+		enigma2Sat := enigma2Sat{Name: enigma2Name(sat), Position: enigma2Position(sat.GetPosition())}
+		for _, transponder := range transponders[sat.GetName()] {
+			enigma2Transponder, err := newEnigma2Transponder(&transponder)
+			if err != nil {
+				return fmt.Errorf("wrong transponder %d of %s: %w", transponder.Frequency, sat.GetName(), err)
+			}
+			enigma2Sat.Transponders = append(enigma2Sat.Transponders, enigma2Transponder)
+		}
+		document.Satellites = append(document.Satellites, enigma2Sat)
+	}
+
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "\t")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, "\n")
+	return err
+}
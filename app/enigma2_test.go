@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files of exporters")
+
+// assertGolden compares content with the golden file in testdata, -update flag rewrites the file.
+func assertGolden(t *testing.T, name string, content []byte) {
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		require.NoError(t, ioutil.WriteFile(path, content, 0644))
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(content))
+}
+
+func makeBandSat(name string, position float64, band string) Satellite {
+	sat := makeSat(name, position)
+	sat.SetBand(band)
+	return sat
+}
+
+func testExportList() []Satellite {
+	return []Satellite{
+		makeBandSat("Intelsat 14", -45, "C/Ku"),
+		makeBandSat("Eutelsat 5 West B", -5, "Ku"),
+		makeBandSat("Hot Bird 13E", 13, "Ku"),
+		makeBandSat("Astra 1KR & <1L>", 19.2, "Ku"),
+		makeBandSat("Express AMU1", 36.05, "Ka"),
+	}
+}
+
+func TestWriteEnigma2(t *testing.T) {
+	transponders := map[string][]Transponder{
+		"Hot Bird 13E": {
+			{Frequency: 10719000, Polarization: "V", SymbolRate: 27500000, FEC: "5/6", System: systemDVBS,
+				Modulation: "QPSK"},
+			{Frequency: 11013000, Polarization: "H", SymbolRate: 29900000, FEC: "3/4", System: systemDVBS2,
+				Modulation: "8PSK"},
+		},
+	}
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteEnigma2(&buffer, testExportList(), transponders))
+	assertGolden(t, "enigma2_satellites.xml", buffer.Bytes())
+}
+
+func TestWriteEnigma2Filtered(t *testing.T) {
+	filter := &ExportFilter{MinPosition: -10, MaxPosition: 30, Band: regexp.MustCompile("(?i)^ku$")}
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteEnigma2(&buffer, filter.Filter(testExportList()), nil))
+	assertGolden(t, "enigma2_filtered.xml", buffer.Bytes())
+}
+
+func TestWriteEnigma2WrongTransponder(t *testing.T) {
+	transponders := map[string][]Transponder{"Hot Bird 13E": {{Frequency: 10719000, Polarization: "X"}}}
+	assert.Error(t, WriteEnigma2(&bytes.Buffer{}, testExportList(), transponders))
+}
+
+func TestEnigma2Position(t *testing.T) {
+	assert.Equal(t, 192, enigma2Position(19.2))
+	assert.Equal(t, -300, enigma2Position(-30))
+	assert.Equal(t, 361, enigma2Position(36.05))
+}
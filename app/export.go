@@ -0,0 +1,284 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ExportFilter selects satellites to export. Positions are an inclusive range in degrees, east positions are
+// positive. Nil band regex matches all bands.
+type ExportFilter struct {
+	MinPosition float64
+	MaxPosition float64
+	Band        *regexp.Regexp
+}
+
+// Matches returns true if the satellite passes the filter.
+func (ptr *ExportFilter) Matches(sat *Satellite) bool {
+	return sat.GetPosition() >= ptr.MinPosition && sat.GetPosition() <= ptr.MaxPosition &&
+		(ptr.Band == nil || ptr.Band.MatchString(sat.GetBand()))
+}
+
+// Filter returns satellites of the list which pass the filter keeping their order.
+func (ptr *ExportFilter) Filter(list []Satellite) []Satellite {
+	var filtered []Satellite
+	for i := range list {
+		if ptr.Matches(&list[i]) {
+			filtered = append(filtered, list[i])
+		}
+	}
+	return filtered
+}
+
+// exportFlags holds command line options common for all export formats.
+type exportFlags struct {
+	*flag.FlagSet
+	output      *string
+	minPosition *float64
+	maxPosition *float64
+	band        *string
+}
+
+func newExportFlags(format, defaultOutput string) *exportFlags {
+	flags := &exportFlags{FlagSet: flag.NewFlagSet("export "+format, flag.ExitOnError)}
+	flags.output = flags.String("output", defaultOutput, "path of written file, - writes to standard output")
+	flags.minPosition = flags.Float64("min-position", -180, "minimal position in degrees, west positions are negative")
+	flags.maxPosition = flags.Float64("max-position", 180, "maximal position in degrees, west positions are negative")
+	flags.band = flags.String("band", "", "regex of exported bands, e.g. (?i)ku")
+	return flags
+}
+
+// filter returns filter set by command line options.
+func (ptr *exportFlags) filter() (*ExportFilter, error) {
+	filter := &ExportFilter{MinPosition: *ptr.minPosition, MaxPosition: *ptr.maxPosition}
+	if filter.MinPosition > filter.MaxPosition {
+		return nil, fmt.Errorf("min position %s is greater than max position %s",
+			formatPosition(filter.MinPosition), formatPosition(filter.MaxPosition))
+	}
+	if *ptr.band != "" {
+		band, err := regexp.Compile(*ptr.band)
+		if err != nil {
+			return nil, fmt.Errorf("wrong band regex: %w", err)
+		}
+		filter.Band = band
+	}
+	return filter, nil
+}
+
+// write writes exported content to the output file atomically or to standard output.
+func (ptr *exportFlags) write(write func(writer io.Writer) error) error {
+	if *ptr.output == "-" {
+		return write(os.Stdout)
+	}
+	return writeFileAtomically(*ptr.output, write)
+}
+
+// exportCommand writes active satellites from storage in given format.
+func exportCommand(args []string) {
+	usage := "usage: sat-parser export enigma2|dvbv5|legacy|diseqc|geojson|kml|sqlite|xlsx [options]"
+	if len(args) == 0 {
+		log.Fatal(usage)
+	}
+
+	switch args[0] {
+	case "enigma2":
+		exportEnigma2(args[1:])
+	case scanFormatDVBv5, scanFormatLegacy:
+		exportScanTables(args[0], args[1:])
+	case "diseqc":
+		exportDiSEqC(args[1:])
+	case "geojson", "kml":
+		exportMap(args[0], args[1:])
+	case "sqlite":
+		exportSQLite(args[1:])
+	case "xlsx":
+		exportXLSX(args[1:])
+	default:
+		log.Fatalf("unknown export format %s, available formats: enigma2, dvbv5, legacy, diseqc, geojson, kml, "+
+			"sqlite, xlsx",
+			args[0])
+	}
+}
+
+// collectTransponders loads transponders of satellites from their pages, satellites which pages cannot be loaded
+// are exported without transponders.
+func collectTransponders(satellites []Satellite) map[string][]Transponder {
+	transponders, errorz := CollectTransponders(satellites, int(getProperties().Parser.TransponderWorkers))
+	for _, err := range errorz {
+		log.Warn(err)
+	}
+	return transponders
+}
+
+// exportEnigma2 writes satellites.xml of Enigma2 images. Transponders are not stored, so they are collected from
+// satellite pages on demand.
+func exportEnigma2(args []string) {
+	flags := newExportFlags("enigma2", "satellites.xml")
+	withTransponders := flags.Bool("transponders", false, "collect transponders from satellite pages")
+	_ = flags.Parse(args)
+	filter, err := flags.filter()
+	if err != nil {
+		log.WithError(err).Fatal("wrong export options")
+	}
+
+	satellites := filter.Filter(LoadDbSatellites())
+	var transponders map[string][]Transponder
+	if *withTransponders {
+		transponders = collectTransponders(satellites)
+	}
+	if err := flags.write(func(writer io.Writer) error {
+		return WriteEnigma2(writer, satellites, transponders)
+	}); err != nil {
+		log.WithError(err).Fatal("cannot export satellites")
+	}
+	log.Infof("%d satellites exported to %s", len(satellites), *flags.output)
+}
+
+// exportScanTables writes initial scan table of every satellite with known transponders to the output directory.
+func exportScanTables(format string, args []string) {
+	flags := newExportFlags(format, "scan-tables")
+	_ = flags.Parse(args)
+	filter, err := flags.filter()
+	if err != nil {
+		log.WithError(err).Fatal("wrong export options")
+	}
+
+	write := WriteDVBv5Scan
+	if format == scanFormatLegacy {
+		write = WriteLegacyScan
+	}
+
+	satellites := filter.Filter(LoadDbSatellites())
+	transponders := collectTransponders(satellites)
+	if err := os.MkdirAll(*flags.output, 0755); err != nil {
+		log.WithError(err).Fatal("cannot create output directory")
+	}
+
+	written := 0
+	for i := range satellites {
+		sat := &satellites[i]
+		if len(transponders[sat.GetName()]) == 0 {
+			log.Warnf("%s has no known transponders, scan table is not written", sat.GetName())
+			continue
+		}
+
+		path := filepath.Join(*flags.output, scanFileName(sat))
+		if err := writeFileAtomically(path, func(writer io.Writer) error {
+			return write(writer, sat, transponders[sat.GetName()])
+		}); err != nil {
+			log.WithError(err).Fatal("cannot export scan tables")
+		}
+		written++
+	}
+	log.Infof("%d scan tables exported to %s", written, *flags.output)
+}
+
+// exportDiSEqC writes table of DiSEqC 1.2 motor positions of satellites visible from the observer.
+func exportDiSEqC(args []string) {
+	flags := newExportFlags("diseqc", "diseqc.csv")
+	format := flags.String("format", diseqcFormatCSV, "format of the table: csv or vdr")
+	observer := observerFlags(flags.FlagSet)
+	_ = flags.Parse(args)
+	filter, err := flags.filter()
+	if err != nil {
+		log.WithError(err).Fatal("wrong export options")
+	}
+
+	slots, err := MakeDiSEqCSlots(filter.Filter(LoadDbSatellites()), observer)
+	if err != nil {
+		log.WithError(err).Fatal("cannot assign motor positions")
+	}
+	if err := flags.write(func(writer io.Writer) error {
+		return WriteDiSEqCSlots(writer, slots, *format)
+	}); err != nil {
+		log.WithError(err).Fatal("cannot export motor positions")
+	}
+	log.Infof("%d motor positions exported to %s", len(slots), *flags.output)
+}
+
+// exportMap writes satellites as map points in GeoJSON or KML format with optional horizon of the observer.
+func exportMap(format string, args []string) {
+	flags := newExportFlags(format, "satellites."+format)
+	withHorizon := flags.Bool("horizon", false, "add visibility horizon of the observer as a polygon")
+	observer := observerFlags(flags.FlagSet)
+	_ = flags.Parse(args)
+	filter, err := flags.filter()
+	if err != nil {
+		log.WithError(err).Fatal("wrong export options")
+	}
+
+	write := WriteGeoJSON
+	if format == "kml" {
+		write = WriteKML
+	}
+
+	satellites := filter.Filter(LoadDbSatellites())
+	var horizon []geoPoint
+	if *withHorizon {
+		horizon = observer.Horizon()
+	}
+	if err := flags.write(func(writer io.Writer) error {
+		return write(writer, satellites, horizon)
+	}); err != nil {
+		log.WithError(err).Fatal("cannot export satellites")
+	}
+	log.Infof("%d satellites exported to %s", len(satellites), *flags.output)
+}
+
+// exportSQLite writes active and closed satellites with their history and tags to a standalone SQLite file.
+func exportSQLite(args []string) {
+	flags := newExportFlags("sqlite", "satellites.sqlite")
+	_ = flags.Parse(args)
+	filter, err := flags.filter()
+	if err != nil {
+		log.WithError(err).Fatal("wrong export options")
+	}
+	if *flags.output == "-" {
+		log.Fatal("SQLite file cannot be written to standard output")
+	}
+
+	snapshot := LoadSQLiteSnapshot()
+	var satellites []StoredSatellite
+	for _, sat := range snapshot.Satellites {
+		if filter.Matches(&sat.Satellite) {
+			satellites = append(satellites, sat)
+		}
+	}
+	snapshot.Satellites = satellites
+
+	if err := WriteSQLiteExport(*flags.output, snapshot); err != nil {
+		log.WithError(err).Fatal("cannot export satellites")
+	}
+	log.Infof("%d satellites exported to %s", len(satellites), *flags.output)
+}
+
+// exportXLSX writes a workbook with a sheet of satellites per region and changes of the latest sync.
+func exportXLSX(args []string) {
+	flags := newExportFlags("xlsx", "satellites.xlsx")
+	withChanges := flags.Bool("changes", true, "add sheet of changes made by the latest sync")
+	_ = flags.Parse(args)
+	filter, err := flags.filter()
+	if err != nil {
+		log.WithError(err).Fatal("wrong export options")
+	}
+
+	satellites := filter.Filter(LoadDbSatellites())
+	var changes *SyncChanges
+	if *withChanges {
+		if changes = LoadLatestSyncChanges(); changes == nil {
+			log.Warn("there are no sync runs with applied changes, changes sheet is not written")
+		}
+	}
+	sheets := MakeXLSXSheets(satellites, changes)
+	if err := flags.write(func(writer io.Writer) error {
+		return WriteXLSX(writer, sheets)
+	}); err != nil {
+		log.WithError(err).Fatal("cannot export satellites")
+	}
+	log.Infof("%d satellites exported to %s", len(satellites), *flags.output)
+}
@@ -0,0 +1,1021 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	fileFormatJSON = ".json"
+	fileFormatCSV  = ".csv"
+)
+
+// fileSatellite is a stored satellite row, closed satellites are kept with their closing time.
+type fileSatellite struct {
+	ID         int64   `json:"id"`
+	Name       string  `json:"name"`
+	Position   float64 `json:"position"`
+	URL        string  `json:"url"`
+	Band       string  `json:"band"`
+	Tags       string  `json:"tags"`
+	ManualTags string  `json:"manualTags"`
+	Source     string  `json:"source,omitempty"`
+	Active     bool    `json:"active"`
+	Closed     string  `json:"closed,omitempty"`
+}
+
+type fileHistoryRecord struct {
+	SatelliteID int64  `json:"satelliteId"`
+	Field       string `json:"field"`
+	OldValue    string `json:"oldValue"`
+	NewValue    string `json:"newValue"`
+	RunID       string `json:"runId"`
+	Changed     string `json:"changed"`
+}
+
+type fileRelocation struct {
+	SatelliteID  int64   `json:"satelliteId"`
+	FromPosition float64 `json:"fromPosition"`
+	ToPosition   float64 `json:"toPosition"`
+	Direction    string  `json:"direction"`
+	RunID        string  `json:"runId"`
+	Detected     string  `json:"detected"`
+}
+
+// fileVersion is a state of a satellite valid since ValidFrom until ValidTo, empty ValidTo means the current state.
+type fileVersion struct {
+	SatelliteID int64   `json:"satelliteId"`
+	Name        string  `json:"name"`
+	Position    float64 `json:"position"`
+	URL         string  `json:"url"`
+	Band        string  `json:"band"`
+	Tags        string  `json:"tags"`
+	ValidFrom   string  `json:"validFrom"`
+	ValidTo     string  `json:"validTo,omitempty"`
+}
+
+// satelliteVersion returns the version as it is returned by repositories.
+func (ptr *fileVersion) satelliteVersion() SatelliteVersion {
+	version := SatelliteVersion{Satellite: Satellite{ID: ptr.SatelliteID, Name: ptr.Name, URL: ptr.URL,
+		Position: ptr.Position, Band: ptr.Band, Tags: ptr.Tags}, ValidFrom: ptr.ValidFrom}
+	if ptr.ValidTo != "" {
+		validTo := ptr.ValidTo
+		version.ValidTo = &validTo
+	}
+	return version
+}
+
+// fileState is the whole content of file storage, rows are kept in order of insertion to keep diffs small.
+type fileState struct {
+	Satellites  []fileSatellite       `json:"satellites"`
+	History     []fileHistoryRecord   `json:"history"`
+	Relocations []fileRelocation      `json:"relocations"`
+	Versions    []fileVersion         `json:"versions"`
+	Runs        []SyncRun             `json:"runs"`
+	Annotations []Annotation          `json:"annotations"`
+	Identifiers []SatelliteIdentifier `json:"identifiers"`
+}
+
+// copy returns a copy of the state which can be changed without affecting the original one.
+func (ptr *fileState) copy() *fileState {
+	return &fileState{
+		Satellites:  append([]fileSatellite(nil), ptr.Satellites...),
+		History:     append([]fileHistoryRecord(nil), ptr.History...),
+		Relocations: append([]fileRelocation(nil), ptr.Relocations...),
+		Versions:    append([]fileVersion(nil), ptr.Versions...),
+		Runs:        append([]SyncRun(nil), ptr.Runs...),
+		Annotations: append([]Annotation(nil), ptr.Annotations...),
+		Identifiers: append([]SatelliteIdentifier(nil), ptr.Identifiers...),
+	}
+}
+
+// openVersion adds current version of the satellite.
+func (ptr *fileState) openVersion(sat *fileSatellite, validFrom string) {
+	ptr.Versions = append(ptr.Versions, fileVersion{SatelliteID: sat.ID, Name: sat.Name, Position: sat.Position,
+		URL: sat.URL, Band: sat.Band, Tags: sat.Tags, ValidFrom: validFrom})
+}
+
+// closeVersion closes current version of the satellite with given id.
+func (ptr *fileState) closeVersion(id int64, validTo string) {
+	for i := range ptr.Versions {
+		if ptr.Versions[i].SatelliteID == id && ptr.Versions[i].ValidTo == "" {
+			ptr.Versions[i].ValidTo = validTo
+		}
+	}
+}
+
+// findActive returns index of active satellite with given id or -1 if there is no such satellite.
+func (ptr *fileState) findActive(id int64) int {
+	for i := range ptr.Satellites {
+		if ptr.Satellites[i].ID == id && ptr.Satellites[i].Active {
+			return i
+		}
+	}
+	return -1
+}
+
+// findAnnotation returns index of annotation with given name and key or -1 if there is no such annotation.
+func (ptr *fileState) findAnnotation(name, key string) int {
+	for i := range ptr.Annotations {
+		if ptr.Annotations[i].Name == name && ptr.Annotations[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (ptr *fileState) nextID() int64 {
+	var id int64
+	for _, sat := range ptr.Satellites {
+		if sat.ID > id {
+			id = sat.ID
+		}
+	}
+	return id + 1
+}
+
+// fileRepository is a SatelliteRepository implementation which keeps satellites in JSON or CSV file. JSON storage
+// is one file, CSV storage keeps history, relocations and other tables in separate files next to satellites one
+// and checksums of all of them in the manifest file.
+type fileRepository struct {
+	path   string
+	format string
+	state  *fileState
+	// generation of CSV files listed in the manifest, every write creates files of the next generation
+	generation int64
+}
+
+func openFileRepository(properties *FileProperties) (*fileRepository, error) {
+	path := properties.Path
+	format := strings.ToLower(filepath.Ext(path))
+	if format != fileFormatJSON && format != fileFormatCSV {
+		return nil, fmt.Errorf("unknown format of file storage %s, available formats: %s, %s", path,
+			fileFormatJSON, fileFormatCSV)
+	}
+
+	repository := &fileRepository{path: path, format: format}
+	state, err := repository.read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file storage %s: %w", path, err)
+	}
+	// files written before versioning get versions of active satellites since the satellites file was modified,
+	// so read-only runs see the same versions until they are written with the next change
+	if len(state.Versions) == 0 {
+		var validFrom string
+		for i := range state.Satellites {
+			if !state.Satellites[i].Active {
+				continue
+			}
+			if validFrom == "" {
+				info, err := os.Stat(repository.generationPath("", repository.generation))
+				if err != nil {
+					return nil, fmt.Errorf("cannot read file storage %s: %w", path, err)
+				}
+				validFrom = formatMoment(info.ModTime())
+			}
+			state.openVersion(&state.Satellites[i], validFrom)
+		}
+	}
+
+	repository.state = state
+	return repository, nil
+}
+
+// siblingPath returns path of additional file of CSV storage, e.g. satellites_history.csv.
+func (ptr *fileRepository) siblingPath(suffix string) string {
+	return strings.TrimSuffix(ptr.path, filepath.Ext(ptr.path)) + suffix + filepath.Ext(ptr.path)
+}
+
+// generationPath returns path of CSV storage file of the generation, e.g. satellites_history.3.csv. Files of zero
+// generation have no number, they are written before generations were introduced.
+func (ptr *fileRepository) generationPath(suffix string, generation int64) string {
+	if generation == 0 {
+		return ptr.siblingPath(suffix)
+	}
+	extension := filepath.Ext(ptr.path)
+	return strings.TrimSuffix(ptr.path, extension) + suffix + "." + strconv.FormatInt(generation, 10) + extension
+}
+
+// hasGenerations returns true if there are satellites files of numbered generations.
+func (ptr *fileRepository) hasGenerations() (bool, error) {
+	extension := filepath.Ext(ptr.path)
+	prefix := strings.TrimSuffix(ptr.path, extension) + "."
+	matches, err := filepath.Glob(prefix + "*" + extension)
+	if err != nil {
+		return false, err
+	}
+	for _, match := range matches {
+		if _, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(match, prefix), extension), 10,
+			64); err == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// csvFile is one file of CSV storage with its part of the state. Suffix is appended to the name of the
+// satellites file, e.g. satellites_history.csv, the satellites file itself has no suffix.
+type csvFile struct {
+	suffix string
+	header []string
+	format func(state *fileState) [][]string
+	parse  func(state *fileState, row []string) error
+}
+
+// csvManifestSuffix is the suffix of the manifest file of CSV storage, it lists files of the current generation
+// with their checksums.
+const csvManifestSuffix = "_manifest"
+
+var csvManifestHeader = []string{"file", "rows", "sha256", "generation"}
+
+// csvFiles are files of CSV storage in order they are replaced, the satellites file goes last.
+var csvFiles = []csvFile{
+	{suffix: "_history", header: historyCSVHeader, format: func(state *fileState) [][]string {
+		rows := make([][]string, 0, len(state.History))
+		for _, record := range state.History {
+			rows = append(rows, formatHistoryCSV(&record))
+		}
+		return rows
+	}, parse: func(state *fileState, row []string) error {
+		record, err := parseHistoryCSV(row)
+		state.History = append(state.History, record)
+		return err
+	}},
+	{suffix: "_relocations", header: relocationCSVHeader, format: func(state *fileState) [][]string {
+		rows := make([][]string, 0, len(state.Relocations))
+		for _, relocation := range state.Relocations {
+			rows = append(rows, formatRelocationCSV(&relocation))
+		}
+		return rows
+	}, parse: func(state *fileState, row []string) error {
+		relocation, err := parseRelocationCSV(row)
+		state.Relocations = append(state.Relocations, relocation)
+		return err
+	}},
+	{suffix: "_versions", header: versionCSVHeader, format: func(state *fileState) [][]string {
+		rows := make([][]string, 0, len(state.Versions))
+		for _, version := range state.Versions {
+			rows = append(rows, formatVersionCSV(&version))
+		}
+		return rows
+	}, parse: func(state *fileState, row []string) error {
+		version, err := parseVersionCSV(row)
+		state.Versions = append(state.Versions, version)
+		return err
+	}},
+	{suffix: "_sync_runs", header: runCSVHeader, format: func(state *fileState) [][]string {
+		rows := make([][]string, 0, len(state.Runs))
+		for _, run := range state.Runs {
+			rows = append(rows, formatRunCSV(&run))
+		}
+		return rows
+	}, parse: func(state *fileState, row []string) error {
+		run, err := parseRunCSV(row)
+		state.Runs = append(state.Runs, run)
+		return err
+	}},
+	{suffix: "_annotations", header: annotationCSVHeader, format: func(state *fileState) [][]string {
+		rows := make([][]string, 0, len(state.Annotations))
+		for _, annotation := range state.Annotations {
+			rows = append(rows, formatAnnotationCSV(&annotation))
+		}
+		return rows
+	}, parse: func(state *fileState, row []string) error {
+		state.Annotations = append(state.Annotations, parseAnnotationCSV(row))
+		return nil
+	}},
+	{suffix: "_identifiers", header: identifierCSVHeader, format: func(state *fileState) [][]string {
+		rows := make([][]string, 0, len(state.Identifiers))
+		for _, identifier := range state.Identifiers {
+			rows = append(rows, formatIdentifierCSV(&identifier))
+		}
+		return rows
+	}, parse: func(state *fileState, row []string) error {
+		identifier, err := parseIdentifierCSV(row)
+		state.Identifiers = append(state.Identifiers, identifier)
+		return err
+	}},
+	{suffix: "", header: satelliteCSVHeader, format: func(state *fileState) [][]string {
+		rows := make([][]string, 0, len(state.Satellites))
+		for _, sat := range state.Satellites {
+			rows = append(rows, formatSatelliteCSV(&sat))
+		}
+		return rows
+	}, parse: func(state *fileState, row []string) error {
+		sat, err := parseSatelliteCSV(row)
+		state.Satellites = append(state.Satellites, sat)
+		return err
+	}},
+}
+
+// csvChecksum returns hex encoded SHA-256 of file content.
+func csvChecksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// read loads the state from files, missing files mean empty storage. CSV files are read from the generation
+// listed in the manifest and checked against it, files which are not listed are left by interrupted writes and
+// ignored. Storages written before manifests were introduced are read as is.
+func (ptr *fileRepository) read() (*fileState, error) {
+	state := &fileState{}
+	if ptr.format == fileFormatJSON {
+		return state, readFile(ptr.path, func(reader io.Reader) error {
+			return json.NewDecoder(reader).Decode(state)
+		})
+	}
+
+	manifestPath := ptr.siblingPath(csvManifestSuffix)
+	var checksums map[string]string
+	if err := readFile(manifestPath, func(reader io.Reader) error {
+		checksums = make(map[string]string)
+		return readCSV(reader, csvManifestHeader, func(row []string) (err error) {
+			checksums[row[0]] = row[2]
+			// manifests written before generations were introduced list files of zero generation
+			if row[3] != "" {
+				ptr.generation, err = strconv.ParseInt(row[3], 10, 64)
+			}
+			return err
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	if checksums == nil {
+		generations, err := ptr.hasGenerations()
+		if err != nil {
+			return nil, err
+		}
+		if generations {
+			return nil, fmt.Errorf("manifest %s is missing, restore it to find files of the current generation",
+				manifestPath)
+		}
+	}
+
+	for _, file := range csvFiles {
+		file := file
+		path := ptr.generationPath(file.suffix, ptr.generation)
+		checksum, listed := checksums[filepath.Base(path)]
+		if checksums != nil && !listed {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			content, err = nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if checksums != nil && checksum != csvChecksum(content) {
+			return nil, fmt.Errorf("%s does not match manifest %s, the file was changed by hand. Restore it from "+
+				"a backup", path, manifestPath)
+		}
+		if err := readCSV(bytes.NewReader(content), file.header, func(row []string) error {
+			return file.parse(state, row)
+		}); err != nil {
+			return nil, fmt.Errorf("cannot read %s: %w", path, err)
+		}
+	}
+	return state, nil
+}
+
+// write saves the state to files. CSV files of the next generation are written next to the current ones, which
+// stay untouched, and the manifest listing the new files is replaced the last. The replacement of the manifest
+// is atomic and switches readers to the new generation, so an interrupted write leaves the storage as it was.
+// Files of the previous generation are removed after the switch.
+func (ptr *fileRepository) write(state *fileState) error {
+	if ptr.format == fileFormatJSON {
+		return writeFileAtomically(ptr.path, func(writer io.Writer) error {
+			encoder := json.NewEncoder(writer)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(state)
+		})
+	}
+
+	generation := ptr.generation + 1
+	written := make([]string, 0, len(csvFiles))
+	switched := false
+	defer func() {
+		if !switched {
+			for _, path := range written {
+				_ = os.Remove(path)
+			}
+		}
+	}()
+
+	manifest := make([][]string, 0, len(csvFiles))
+	for _, file := range csvFiles {
+		rows := file.format(state)
+		var buffer bytes.Buffer
+		if err := writeCSV(&buffer, file.header, rows); err != nil {
+			return err
+		}
+
+		path := ptr.generationPath(file.suffix, generation)
+		if err := writeFileAtomically(path, func(writer io.Writer) error {
+			_, err := writer.Write(buffer.Bytes())
+			return err
+		}); err != nil {
+			return err
+		}
+		written = append(written, path)
+		manifest = append(manifest, []string{filepath.Base(path), strconv.Itoa(len(rows)),
+			csvChecksum(buffer.Bytes()), strconv.FormatInt(generation, 10)})
+	}
+
+	if err := writeFileAtomically(ptr.siblingPath(csvManifestSuffix), func(writer io.Writer) error {
+		return writeCSV(writer, csvManifestHeader, manifest)
+	}); err != nil {
+		return err
+	}
+	switched = true
+
+	for _, file := range csvFiles {
+		_ = os.Remove(ptr.generationPath(file.suffix, ptr.generation))
+	}
+	ptr.generation = generation
+	return nil
+}
+
+// readFile opens file and passes it to read function, missing file is not an error.
+func readFile(path string, read func(reader io.Reader) error) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	return read(file)
+}
+
+// writeFileAtomically writes content to a temporary file in the same directory and renames it to the path, so
+// readers see either old or new content and never a partially written file.
+func writeFileAtomically(path string, write func(writer io.Writer) error) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tempPath := file.Name()
+	defer func() { _ = os.Remove(tempPath) }()
+
+	if err := write(file); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("cannot write %s: %w", path, err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
+}
+
+// LoadActive returns all active satellites ordered by position and name.
+func (ptr *fileRepository) LoadActive() ([]Satellite, error) {
+	var satellites []Satellite
+	for _, sat := range ptr.state.Satellites {
+		if sat.Active {
+			satellites = append(satellites, Satellite{ID: sat.ID, Name: sat.Name, URL: sat.URL,
+				Position: sat.Position, Band: sat.Band, Tags: sat.Tags, ManualTags: sat.ManualTags,
+				Source: sat.Source})
+		}
+	}
+	sort.Sort(ByPosName(satellites))
+	attachAnnotations(satellites, ptr.state.Annotations)
+	return satellites, nil
+}
+
+// LoadHistory returns field changes of satellites with given name ordered by time.
+func (ptr *fileRepository) LoadHistory(name string) ([]HistoryRecord, error) {
+	ids := make(map[int64]bool)
+	for _, sat := range ptr.state.Satellites {
+		if sat.Name == name {
+			ids[sat.ID] = true
+		}
+	}
+
+	var records []HistoryRecord
+	for _, record := range ptr.state.History {
+		if ids[record.SatelliteID] {
+			records = append(records, HistoryRecord{SatelliteID: record.SatelliteID, Name: name,
+				Field: record.Field, OldValue: record.OldValue, NewValue: record.NewValue, RunID: record.RunID,
+				Changed: record.Changed})
+		}
+	}
+	return records, nil
+}
+
+// LoadAll returns active and closed satellites ordered by position and name.
+func (ptr *fileRepository) LoadAll() ([]StoredSatellite, error) {
+	satellites := make([]StoredSatellite, 0, len(ptr.state.Satellites))
+	for _, sat := range ptr.state.Satellites {
+		stored := StoredSatellite{Satellite: Satellite{ID: sat.ID, Name: sat.Name, URL: sat.URL,
+			Position: sat.Position, Band: sat.Band, Tags: sat.Tags, ManualTags: sat.ManualTags, Source: sat.Source}}
+		if sat.Active {
+			stored.Status = 1
+		} else {
+			closed := sat.Closed
+			stored.Closed = &closed
+		}
+		satellites = append(satellites, stored)
+	}
+	sort.SliceStable(satellites, func(i, j int) bool {
+		if satellites[i].Position != satellites[j].Position {
+			return satellites[i].Position < satellites[j].Position
+		}
+		return satellites[i].Name < satellites[j].Name
+	})
+	return satellites, nil
+}
+
+// LoadAllHistory returns field changes of all satellites in the order they were saved.
+func (ptr *fileRepository) LoadAllHistory() ([]HistoryRecord, error) {
+	names := make(map[int64]string, len(ptr.state.Satellites))
+	for _, sat := range ptr.state.Satellites {
+		names[sat.ID] = sat.Name
+	}
+
+	records := make([]HistoryRecord, 0, len(ptr.state.History))
+	for _, record := range ptr.state.History {
+		records = append(records, HistoryRecord{SatelliteID: record.SatelliteID, Name: names[record.SatelliteID],
+			Field: record.Field, OldValue: record.OldValue, NewValue: record.NewValue, RunID: record.RunID,
+			Changed: record.Changed})
+	}
+	return records, nil
+}
+
+// LoadRelocations returns relocations of all satellites in the order they were saved.
+func (ptr *fileRepository) LoadRelocations() ([]Relocation, error) {
+	names := make(map[int64]string, len(ptr.state.Satellites))
+	for _, sat := range ptr.state.Satellites {
+		names[sat.ID] = sat.Name
+	}
+
+	relocations := make([]Relocation, 0, len(ptr.state.Relocations))
+	for _, relocation := range ptr.state.Relocations {
+		relocations = append(relocations, Relocation{SatelliteID: relocation.SatelliteID,
+			Name: names[relocation.SatelliteID], FromPosition: relocation.FromPosition,
+			ToPosition: relocation.ToPosition, Direction: relocation.Direction, RunID: relocation.RunID,
+			Detected: relocation.Detected})
+	}
+	return relocations, nil
+}
+
+// LoadAsOf returns versions of satellites which were active at the moment ordered by position and name.
+func (ptr *fileRepository) LoadAsOf(moment time.Time) ([]SatelliteVersion, error) {
+	at := formatMoment(moment)
+	var versions []SatelliteVersion
+	for _, version := range ptr.state.Versions {
+		if version.ValidFrom > at || (version.ValidTo != "" && version.ValidTo <= at) {
+			continue
+		}
+		versions = append(versions, version.satelliteVersion())
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].Position != versions[j].Position {
+			return versions[i].Position < versions[j].Position
+		}
+		return versions[i].Name < versions[j].Name
+	})
+	attachVersionAnnotations(versions, ptr.state.Annotations)
+	return versions, nil
+}
+
+// LoadVersions returns all versions of all satellites ordered by satellite id, versions of one satellite are
+// kept in order they were opened.
+func (ptr *fileRepository) LoadVersions() ([]SatelliteVersion, error) {
+	versions := make([]SatelliteVersion, 0, len(ptr.state.Versions))
+	for _, version := range ptr.state.Versions {
+		versions = append(versions, version.satelliteVersion())
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].ID < versions[j].ID })
+	return versions, nil
+}
+
+// SaveRun saves log record of a sync run.
+func (ptr *fileRepository) SaveRun(run *SyncRun) error {
+	state := ptr.state.copy()
+	state.Runs = append(state.Runs, *run)
+	if err := ptr.write(state); err != nil {
+		return err
+	}
+	ptr.state = state
+	return nil
+}
+
+// LoadRuns returns given count of the latest sync runs, the latest first.
+func (ptr *fileRepository) LoadRuns(limit int) ([]SyncRun, error) {
+	runs := append([]SyncRun(nil), ptr.state.Runs...)
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Started > runs[j].Started })
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// LoadRun returns sync run with given id, nil if there is no such run.
+func (ptr *fileRepository) LoadRun(runID string) (*SyncRun, error) {
+	for _, run := range ptr.state.Runs {
+		if run.RunID == runID {
+			run := run
+			return &run, nil
+		}
+	}
+	return nil, nil
+}
+
+// SaveManualTags replaces manual tags of active satellites with given name.
+func (ptr *fileRepository) SaveManualTags(name string, tags []string) error {
+	state := ptr.state.copy()
+	found := false
+	for i := range state.Satellites {
+		if state.Satellites[i].Active && state.Satellites[i].Name == name {
+			state.Satellites[i].ManualTags = formatTags(tags)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("active satellite %s not found", name)
+	}
+
+	if err := ptr.write(state); err != nil {
+		return err
+	}
+	ptr.state = state
+	return nil
+}
+
+// LoadAnnotations returns annotations of all satellites ordered by name and key.
+func (ptr *fileRepository) LoadAnnotations() ([]Annotation, error) {
+	annotations := append([]Annotation(nil), ptr.state.Annotations...)
+	sort.SliceStable(annotations, func(i, j int) bool {
+		if annotations[i].Name != annotations[j].Name {
+			return annotations[i].Name < annotations[j].Name
+		}
+		return annotations[i].Key < annotations[j].Key
+	})
+	return annotations, nil
+}
+
+// SaveAnnotation inserts annotation or replaces value of existing one.
+func (ptr *fileRepository) SaveAnnotation(annotation Annotation) error {
+	state := ptr.state.copy()
+	i := state.findAnnotation(annotation.Name, annotation.Key)
+	if i < 0 {
+		state.Annotations = append(state.Annotations, annotation)
+	} else {
+		state.Annotations[i] = annotation
+	}
+
+	if err := ptr.write(state); err != nil {
+		return err
+	}
+	ptr.state = state
+	return nil
+}
+
+// DeleteAnnotation removes annotation with given key of satellites with given name.
+func (ptr *fileRepository) DeleteAnnotation(name, key string) (bool, error) {
+	state := ptr.state.copy()
+	i := state.findAnnotation(name, key)
+	if i < 0 {
+		return false, nil
+	}
+	state.Annotations = append(state.Annotations[:i], state.Annotations[i+1:]...)
+
+	if err := ptr.write(state); err != nil {
+		return false, err
+	}
+	ptr.state = state
+	return true, nil
+}
+
+// LoadIdentifiers returns catalogue identifiers of all satellites ordered by name.
+func (ptr *fileRepository) LoadIdentifiers() ([]SatelliteIdentifier, error) {
+	identifiers := append([]SatelliteIdentifier(nil), ptr.state.Identifiers...)
+	sort.SliceStable(identifiers, func(i, j int) bool {
+		return identifiers[i].Name < identifiers[j].Name
+	})
+	return identifiers, nil
+}
+
+// SaveIdentifiers inserts identifiers or replaces existing ones with the same names.
+func (ptr *fileRepository) SaveIdentifiers(identifiers []SatelliteIdentifier) error {
+	state := ptr.state.copy()
+	byName := make(map[string]int, len(state.Identifiers))
+	for i := range state.Identifiers {
+		byName[state.Identifiers[i].Name] = i
+	}
+	for _, identifier := range identifiers {
+		if i, found := byName[identifier.Name]; found {
+			state.Identifiers[i] = identifier
+			continue
+		}
+		byName[identifier.Name] = len(state.Identifiers)
+		state.Identifiers = append(state.Identifiers, identifier)
+	}
+
+	if err := ptr.write(state); err != nil {
+		return err
+	}
+	ptr.state = state
+	return nil
+}
+
+// Begin starts file transaction, changes are kept in memory until commit.
+func (ptr *fileRepository) Begin() (SatelliteTx, error) {
+	return &fileTx{repository: ptr, state: ptr.state.copy()}, nil
+}
+
+// fileTx is a SatelliteTx implementation which changes a copy of storage state and writes it on commit. Every
+// method checks all rows before changing anything, so a failed method leaves no changes behind.
+type fileTx struct {
+	repository *fileRepository
+	state      *fileState
+}
+
+func fileTimestamp() string {
+	return formatMoment(time.Now())
+}
+
+// Insert adds new active satellites and opens their first versions.
+func (ptr *fileTx) Insert(list []Satellite) error {
+	id := ptr.state.nextID()
+	opened := fileTimestamp()
+	for _, sat := range list {
+		ptr.state.Satellites = append(ptr.state.Satellites, fileSatellite{ID: id, Name: sat.Name,
+			Position: sat.Position, URL: sat.URL, Band: sat.Band, Tags: sat.Tags, Source: sat.Source, Active: true})
+		ptr.state.openVersion(&ptr.state.Satellites[len(ptr.state.Satellites)-1], opened)
+		id++
+	}
+	return nil
+}
+
+// Close marks active satellites closed by their ids and closes their current versions.
+func (ptr *fileTx) Close(list []Satellite) error {
+	indexes := make([]int, 0, len(list))
+	for _, sat := range list {
+		i := ptr.state.findActive(sat.ID)
+		if i < 0 {
+			return fmt.Errorf("active satellite %s with id %d not found", sat.Name, sat.ID)
+		}
+		indexes = append(indexes, i)
+	}
+
+	closed := fileTimestamp()
+	for _, i := range indexes {
+		ptr.state.Satellites[i].Active = false
+		ptr.state.Satellites[i].Closed = closed
+		ptr.state.closeVersion(ptr.state.Satellites[i].ID, closed)
+	}
+	return nil
+}
+
+// Update sets new values to active satellites by their ids, replaces their current versions with new ones and
+// saves history records of changed fields.
+func (ptr *fileTx) Update(pairs [][]Satellite) error {
+	indexes := make([]int, 0, len(pairs))
+	for _, pair := range pairs {
+		i := ptr.state.findActive(pair[0].ID)
+		if i < 0 {
+			return fmt.Errorf("active satellite %s with id %d not found", pair[0].Name, pair[0].ID)
+		}
+		indexes = append(indexes, i)
+	}
+
+	changed := fileTimestamp()
+	for n, pair := range pairs {
+		oldSat, newSat := pair[0], pair[1]
+		sat := &ptr.state.Satellites[indexes[n]]
+		sat.Name, sat.Position, sat.URL, sat.Band, sat.Tags, sat.Source =
+			newSat.Name, newSat.Position, newSat.URL, newSat.Band, newSat.Tags, newSat.Source
+		ptr.state.closeVersion(sat.ID, changed)
+		ptr.state.openVersion(sat, changed)
+
+		for _, record := range DiffSatellites(&oldSat, &newSat, &getProperties().Comparison) {
+			ptr.state.History = append(ptr.state.History, fileHistoryRecord{SatelliteID: record.SatelliteID,
+				Field: record.Field, OldValue: record.OldValue, NewValue: record.NewValue, RunID: record.RunID,
+				Changed: changed})
+		}
+	}
+	return nil
+}
+
+// SaveSources sets sources of active satellites by their ids.
+func (ptr *fileTx) SaveSources(list []Satellite) error {
+	indexes := make([]int, 0, len(list))
+	for _, sat := range list {
+		i := ptr.state.findActive(sat.ID)
+		if i < 0 {
+			return fmt.Errorf("active satellite %s with id %d not found", sat.Name, sat.ID)
+		}
+		indexes = append(indexes, i)
+	}
+
+	for n, i := range indexes {
+		ptr.state.Satellites[i].Source = list[n].Source
+	}
+	return nil
+}
+
+// SaveRelocations saves relocations of active satellites.
+func (ptr *fileTx) SaveRelocations(list []Relocation) error {
+	detected := fileTimestamp()
+	for _, relocation := range list {
+		ptr.state.Relocations = append(ptr.state.Relocations, fileRelocation{SatelliteID: relocation.SatelliteID,
+			FromPosition: relocation.FromPosition, ToPosition: relocation.ToPosition,
+			Direction: relocation.Direction, RunID: relocation.RunID, Detected: detected})
+	}
+	return nil
+}
+
+// Commit writes changed state to files.
+func (ptr *fileTx) Commit() error {
+	if err := ptr.repository.write(ptr.state); err != nil {
+		return err
+	}
+	ptr.repository.state = ptr.state
+	return nil
+}
+
+// Rollback discards changes.
+func (ptr *fileTx) Rollback() error {
+	return nil
+}
+
+var (
+	satelliteCSVHeader = []string{"id", "name", "position", "url", "band", "tags", "manual_tags", "active", "closed",
+		"source"}
+	historyCSVHeader    = []string{"satellite_id", "field", "old_value", "new_value", "run_id", "changed"}
+	relocationCSVHeader = []string{"satellite_id", "from_position", "to_position", "direction", "run_id", "detected"}
+	versionCSVHeader    = []string{"satellite_id", "name", "position", "url", "band", "tags", "valid_from", "valid_to"}
+	runCSVHeader        = []string{"run_id", "started", "finished", "revision", "config_hash", "status", "pages",
+		"parsed", "parse_errors", "inserted", "closed", "updated", "relocated", "failed"}
+	annotationCSVHeader = []string{"name", "key", "value", "updated"}
+	identifierCSVHeader = []string{"name", "norad_id", "cospar_id", "satcat_name", "confidence", "matched"}
+)
+
+// readCSV checks header of CSV content and passes every other row to parse function. New columns are appended
+// to the end of headers, so files written before have a shorter header and their rows get empty values of the
+// missing columns.
+func readCSV(reader io.Reader, header []string, parse func(row []string) error) error {
+	csvReader := csv.NewReader(reader)
+
+	rows, err := csvReader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	if len(rows[0]) > len(header) ||
+		strings.Join(rows[0], ",") != strings.Join(header[:len(rows[0])], ",") {
+		return fmt.Errorf("unexpected CSV header %v, expected %v", rows[0], header)
+	}
+
+	for i, row := range rows[1:] {
+		row = append(row, make([]string, len(header)-len(row))...)
+		if err := parse(row); err != nil {
+			return fmt.Errorf("wrong CSV row %d: %w", i+2, err)
+		}
+	}
+	return nil
+}
+
+func writeCSV(writer io.Writer, header []string, rows [][]string) error {
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+	return csvWriter.WriteAll(rows)
+}
+
+func formatSatelliteCSV(sat *fileSatellite) []string {
+	return []string{strconv.FormatInt(sat.ID, 10), sat.Name, formatPosition(sat.Position), sat.URL, sat.Band,
+		sat.Tags, sat.ManualTags, strconv.FormatBool(sat.Active), sat.Closed, sat.Source}
+}
+
+func parseSatelliteCSV(row []string) (fileSatellite, error) {
+	sat := fileSatellite{Name: row[1], URL: row[3], Band: row[4], Tags: row[5], ManualTags: row[6], Closed: row[8],
+		Source: row[9]}
+	var err error
+	if sat.ID, err = strconv.ParseInt(row[0], 10, 64); err != nil {
+		return sat, err
+	}
+	if sat.Position, err = strconv.ParseFloat(row[2], 64); err != nil {
+		return sat, err
+	}
+	sat.Active, err = strconv.ParseBool(row[7])
+	return sat, err
+}
+
+func formatHistoryCSV(record *fileHistoryRecord) []string {
+	return []string{strconv.FormatInt(record.SatelliteID, 10), record.Field, record.OldValue, record.NewValue,
+		record.RunID, record.Changed}
+}
+
+func parseHistoryCSV(row []string) (fileHistoryRecord, error) {
+	record := fileHistoryRecord{Field: row[1], OldValue: row[2], NewValue: row[3], RunID: row[4], Changed: row[5]}
+	var err error
+	record.SatelliteID, err = strconv.ParseInt(row[0], 10, 64)
+	return record, err
+}
+
+func formatRelocationCSV(relocation *fileRelocation) []string {
+	return []string{strconv.FormatInt(relocation.SatelliteID, 10), formatPosition(relocation.FromPosition),
+		formatPosition(relocation.ToPosition), relocation.Direction, relocation.RunID, relocation.Detected}
+}
+
+func parseRelocationCSV(row []string) (fileRelocation, error) {
+	relocation := fileRelocation{Direction: row[3], RunID: row[4], Detected: row[5]}
+	var err error
+	if relocation.SatelliteID, err = strconv.ParseInt(row[0], 10, 64); err != nil {
+		return relocation, err
+	}
+	if relocation.FromPosition, err = strconv.ParseFloat(row[1], 64); err != nil {
+		return relocation, err
+	}
+	relocation.ToPosition, err = strconv.ParseFloat(row[2], 64)
+	return relocation, err
+}
+
+func formatVersionCSV(version *fileVersion) []string {
+	return []string{strconv.FormatInt(version.SatelliteID, 10), version.Name, formatPosition(version.Position),
+		version.URL, version.Band, version.Tags, version.ValidFrom, version.ValidTo}
+}
+
+func parseVersionCSV(row []string) (fileVersion, error) {
+	version := fileVersion{Name: row[1], URL: row[3], Band: row[4], Tags: row[5], ValidFrom: row[6], ValidTo: row[7]}
+	var err error
+	if version.SatelliteID, err = strconv.ParseInt(row[0], 10, 64); err != nil {
+		return version, err
+	}
+	version.Position, err = strconv.ParseFloat(row[2], 64)
+	return version, err
+}
+
+func formatRunCSV(run *SyncRun) []string {
+	row := []string{run.RunID, run.Started, run.Finished, run.Revision, run.ConfigHash, run.Status, run.Pages}
+	for _, count := range []int64{run.Parsed, run.ParseErrors, run.Inserted, run.Closed, run.Updated,
+		run.Relocated, run.Failed} {
+		row = append(row, strconv.FormatInt(count, 10))
+	}
+	return row
+}
+
+func parseRunCSV(row []string) (SyncRun, error) {
+	run := SyncRun{RunID: row[0], Started: row[1], Finished: row[2], Revision: row[3], ConfigHash: row[4],
+		Status: row[5], Pages: row[6]}
+	for i, count := range []*int64{&run.Parsed, &run.ParseErrors, &run.Inserted, &run.Closed, &run.Updated,
+		&run.Relocated, &run.Failed} {
+		var err error
+		if *count, err = strconv.ParseInt(row[7+i], 10, 64); err != nil {
+			return run, err
+		}
+	}
+	return run, nil
+}
+
+func formatAnnotationCSV(annotation *Annotation) []string {
+	return []string{annotation.Name, annotation.Key, annotation.Value, annotation.Updated}
+}
+
+func parseAnnotationCSV(row []string) Annotation {
+	return Annotation{Name: row[0], Key: row[1], Value: row[2], Updated: row[3]}
+}
+
+func formatIdentifierCSV(identifier *SatelliteIdentifier) []string {
+	return []string{identifier.Name, strconv.FormatInt(identifier.NoradID, 10), identifier.CosparID,
+		identifier.SatcatName, strconv.FormatFloat(identifier.Confidence, 'f', -1, 64), identifier.Matched}
+}
+
+func parseIdentifierCSV(row []string) (SatelliteIdentifier, error) {
+	identifier := SatelliteIdentifier{Name: row[0], CosparID: row[2], SatcatName: row[3], Matched: row[5]}
+	var err error
+	if identifier.NoradID, err = strconv.ParseInt(row[1], 10, 64); err != nil {
+		return identifier, err
+	}
+	identifier.Confidence, err = strconv.ParseFloat(row[4], 64)
+	return identifier, err
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openTestFileRepository(t *testing.T, name string) (*fileRepository, string, func()) {
+	dir, err := ioutil.TempDir("", "sat-parser")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, name)
+	repository, err := openFileRepository(&FileProperties{Path: path})
+	require.NoError(t, err)
+
+	return repository, path, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestFileRepositorySync(t *testing.T) {
+	for _, name := range []string{"satellites.json", "satellites.csv"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			repository, path, cleanup := openTestFileRepository(t, name)
+			defer cleanup()
+
+			status := syncTestRepository(t, repository, []Satellite{makeSat("one", 1), makeSat("two", 2)}, true, 100)
+			assert.True(t, status.Succeeded())
+
+			moved := makeSat("two", 20)
+			moved.SetBand("Ku")
+			status = syncTestRepository(t, repository, []Satellite{moved, makeSat("three", 3)}, true, 100)
+			assert.Equal(t, SyncStatus{Inserted: 1, Closed: 1, Updated: 1, Relocated: 1}, *status)
+
+			reopened, err := openFileRepository(&FileProperties{Path: path})
+			require.NoError(t, err)
+			assert.Equal(t, []Satellite{makeSat("three", 3), moved}, loadTestActive(t, reopened))
+			assert.Equal(t, repository.state, reopened.state, "all rows must be written")
+			assert.Len(t, reopened.state.Satellites, 3, "closed satellites must be kept")
+
+			records, err := reopened.LoadHistory("two")
+			require.NoError(t, err)
+			assert.Len(t, records, 2)
+
+			relocations, err := reopened.LoadRelocations()
+			require.NoError(t, err)
+			if assert.Len(t, relocations, 1) {
+				assert.Equal(t, "two", relocations[0].Name)
+				assert.Equal(t, 20.0, relocations[0].ToPosition)
+				assert.Equal(t, driftEast, relocations[0].Direction)
+			}
+
+			files, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp"))
+			require.NoError(t, err)
+			assert.Empty(t, files, "temporary files must be renamed")
+		})
+	}
+}
+
+func TestFileRepositoryAtomicRollback(t *testing.T) {
+	repository, path, cleanup := openTestFileRepository(t, "satellites.json")
+	defer cleanup()
+
+	status := syncTestRepository(t, repository, []Satellite{makeSat("one", 1)}, true, 100)
+	require.True(t, status.Succeeded())
+	before, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	missing := makeSat("missing", 2)
+	missing.ID = 1000
+	plan := &SyncPlan{Inserts: []Satellite{makeSat("two", 2)}, Closes: []Satellite{missing}}
+	status = ApplySyncPlan(repository, plan, true, 100)
+	assert.True(t, status.RolledBack)
+	assert.Equal(t, 1, status.Failed)
+
+	after, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(before), string(after), "rolled back sync must not touch the file")
+	assert.Len(t, loadTestActive(t, repository), 1)
+}
+
+func TestFileRepositoryPartialCommit(t *testing.T) {
+	repository, _, cleanup := openTestFileRepository(t, "satellites.csv")
+	defer cleanup()
+
+	status := syncTestRepository(t, repository, []Satellite{makeSat("one", 1), makeSat("two", 2)}, true, 100)
+	require.True(t, status.Succeeded())
+
+	closes, err := repository.LoadActive()
+	require.NoError(t, err)
+	missing := makeSat("missing", 3)
+	missing.ID = 1000
+	closes = append(closes, missing)
+
+	status = ApplySyncPlan(repository, &SyncPlan{Closes: closes}, false, 10)
+	assert.Equal(t, 2, status.Closed, "failed batch must be retried row by row")
+	assert.Equal(t, 1, status.Failed)
+	assert.Empty(t, loadTestActive(t, repository))
+}
+
+func TestFileRepositoryManualTags(t *testing.T) {
+	repository, path, cleanup := openTestFileRepository(t, "satellites.csv")
+	defer cleanup()
+
+	status := syncTestRepository(t, repository, []Satellite{makeSat("one", 1)}, true, 100)
+	require.True(t, status.Succeeded())
+	require.NoError(t, repository.SaveManualTags("one", []string{"mine"}))
+	assert.Error(t, repository.SaveManualTags("missing", []string{"mine"}))
+
+	reopened, err := openFileRepository(&FileProperties{Path: path})
+	require.NoError(t, err)
+	active := loadTestActive(t, reopened)
+	if assert.Len(t, active, 1) {
+		assert.Equal(t, []string{"mine"}, active[0].GetManualTags())
+	}
+}
+
+func TestFileRepositorySources(t *testing.T) {
+	for _, name := range []string{"satellites.json", "satellites.csv"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			repository, path, cleanup := openTestFileRepository(t, name)
+			defer cleanup()
+
+			status := syncTestRepository(t, repository, []Satellite{makeSourcedSat("one", 1, "asia")}, true, 100)
+			require.True(t, status.Succeeded())
+			status = syncTestRepository(t, repository, []Satellite{makeSourcedSat("one", 1, "europe")}, true, 100)
+			assert.Equal(t, SyncStatus{}, *status, "moving to another page is not a change")
+
+			reopened, err := openFileRepository(&FileProperties{Path: path})
+			require.NoError(t, err)
+			assert.Equal(t, []Satellite{makeSourcedSat("one", 1, "europe")}, loadTestActive(t, reopened))
+		})
+	}
+}
+
+func TestFileRepositoryLoadAll(t *testing.T) {
+	repository, _, cleanup := openTestFileRepository(t, "satellites.json")
+	defer cleanup()
+
+	require.True(t, syncTestRepository(t, repository, []Satellite{makeSat("two", 2), makeSat("one", 1)}, true,
+		100).Succeeded())
+	require.True(t, syncTestRepository(t, repository, []Satellite{makeSat("one", 1.1)}, true, 100).Succeeded())
+
+	satellites, err := repository.LoadAll()
+	require.NoError(t, err)
+	if assert.Len(t, satellites, 2) {
+		assert.Equal(t, "one", satellites[0].GetName())
+		assert.True(t, satellites[0].IsActive())
+		assert.Nil(t, satellites[0].Closed)
+		assert.False(t, satellites[1].IsActive())
+		assert.NotNil(t, satellites[1].Closed)
+	}
+
+	records, err := repository.LoadAllHistory()
+	require.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "one", records[0].Name)
+	}
+}
+
+func TestFileRepositoryReadsCSVWithoutSources(t *testing.T) {
+	_, path, cleanup := openTestFileRepository(t, "satellites.csv")
+	defer cleanup()
+	require.NoError(t, ioutil.WriteFile(path, []byte("id,name,position,url,band,tags,manual_tags,active,closed\n"+
+		"1,one,1,,,,,true,\n"), 0644))
+
+	repository, err := openFileRepository(&FileProperties{Path: path})
+	require.NoError(t, err)
+	active := loadTestActive(t, repository)
+	if assert.Len(t, active, 1) {
+		assert.Equal(t, "one", active[0].GetName())
+		assert.Empty(t, active[0].GetSource())
+	}
+}
+
+func TestFileRepositoryCSVManifest(t *testing.T) {
+	repository, path, cleanup := openTestFileRepository(t, "satellites.csv")
+	defer cleanup()
+	require.True(t, syncTestRepository(t, repository, []Satellite{makeSat("one", 1)}, true, 100).Succeeded())
+	require.True(t, syncTestRepository(t, repository, []Satellite{makeSat("one", 1), makeSat("two", 2)}, true,
+		100).Succeeded())
+	assert.Equal(t, int64(2), repository.generation)
+
+	dir := filepath.Dir(path)
+	manifest, err := ioutil.ReadFile(filepath.Join(dir, "satellites_manifest.csv"))
+	require.NoError(t, err)
+	assert.Contains(t, string(manifest), "file,rows,sha256,generation\n")
+	assert.Contains(t, string(manifest), "\nsatellites.2.csv,2,")
+	assert.Contains(t, string(manifest), "\nsatellites_sync_runs.2.csv,0,")
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.csv"))
+	require.NoError(t, err)
+	assert.Len(t, files, len(csvFiles)+1, "files of the previous generation must be removed")
+
+	historyPath := filepath.Join(dir, "satellites_history.2.csv")
+	require.NoError(t, ioutil.WriteFile(historyPath,
+		[]byte("satellite_id,field,old_value,new_value,run_id,changed\n1,band,,Ku,run,2020-01-01 00:00:00\n"), 0644))
+	_, err = openFileRepository(&FileProperties{Path: path})
+	if assert.Error(t, err, "files which do not match manifest must be refused") {
+		assert.Contains(t, err.Error(), "satellites_history.2.csv does not match manifest")
+	}
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "satellites_manifest.csv")))
+	_, err = openFileRepository(&FileProperties{Path: path})
+	if assert.Error(t, err, "files of generations must not be read without manifest") {
+		assert.Contains(t, err.Error(), "satellites_manifest.csv is missing")
+	}
+}
+
+func TestFileRepositoryInterruptedCSVWrite(t *testing.T) {
+	repository, path, cleanup := openTestFileRepository(t, "satellites.csv")
+	defer cleanup()
+	require.True(t, syncTestRepository(t, repository, []Satellite{makeSat("one", 1)}, true, 100).Succeeded())
+	before := repository.state
+
+	// the versions file of the next generation cannot be written, so the write stops after other files are written
+	versionsPath := repository.generationPath("_versions", repository.generation+1)
+	require.NoError(t, os.Mkdir(versionsPath, 0755))
+	status := syncTestRepository(t, repository, []Satellite{makeSat("one", 1), makeSat("two", 2)}, true, 100)
+	assert.True(t, status.RolledBack)
+	assert.Equal(t, before, repository.state)
+	require.NoError(t, os.Remove(versionsPath))
+
+	// the process stopped before the manifest was replaced, files of the next generation are left
+	leftPath := repository.generationPath("", repository.generation+1)
+	require.NoError(t, ioutil.WriteFile(leftPath, []byte("id,name\n"), 0644))
+
+	reopened, err := openFileRepository(&FileProperties{Path: path})
+	require.NoError(t, err, "files which are not listed in the manifest must be ignored")
+	assert.Equal(t, before, reopened.state)
+	assert.Equal(t, repository.generation, reopened.generation)
+
+	require.True(t, syncTestRepository(t, reopened, []Satellite{makeSat("one", 1), makeSat("two", 2)}, true,
+		100).Succeeded(), "left files must be replaced by the next write")
+	reopened, err = openFileRepository(&FileProperties{Path: path})
+	require.NoError(t, err)
+	assert.Len(t, loadTestActive(t, reopened), 2)
+
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp"))
+	require.NoError(t, err)
+	assert.Empty(t, files, "temporary files must be removed")
+}
+
+func TestFileRepositoryReadsCSVOfZeroGeneration(t *testing.T) {
+	_, path, cleanup := openTestFileRepository(t, "satellites.csv")
+	defer cleanup()
+	content := []byte("id,name,position,url,band,tags,manual_tags,active,closed,source\n1,one,1,,,,,true,,\n")
+	require.NoError(t, ioutil.WriteFile(path, content, 0644))
+	require.NoError(t, ioutil.WriteFile(strings.TrimSuffix(path, ".csv")+"_manifest.csv",
+		[]byte("file,rows,sha256\nsatellites.csv,1,"+csvChecksum(content)+"\n"), 0644))
+
+	repository, err := openFileRepository(&FileProperties{Path: path})
+	require.NoError(t, err, "manifest without generations must be read")
+	assert.Len(t, loadTestActive(t, repository), 1)
+
+	require.NoError(t, repository.SaveManualTags("one", []string{"mine"}))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "files of zero generation must be replaced by numbered ones")
+	reopened, err := openFileRepository(&FileProperties{Path: path})
+	require.NoError(t, err)
+	assert.Equal(t, repository.state, reopened.state)
+}
+
+func TestOpenFileRepositoryErrors(t *testing.T) {
+	_, err := openFileRepository(&FileProperties{Path: "satellites.xml"})
+	assert.Error(t, err, "unknown format must be refused")
+
+	_, path, cleanup := openTestFileRepository(t, "satellites.csv")
+	defer cleanup()
+	require.NoError(t, ioutil.WriteFile(path, []byte("name,position\none,1\n"), 0644))
+
+	_, err = openFileRepository(&FileProperties{Path: path})
+	assert.Error(t, err, "unexpected CSV header must be refused")
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+const horizonStep = 5 // degrees of azimuth between horizon polygon vertices
+
+// geoPoint is a point on the Earth surface, degrees of longitude and latitude.
+type geoPoint struct {
+	Longitude float64
+	Latitude  float64
+}
+
+// Horizon returns polygon of sub-satellite points of the geostationary altitude visible from the observer above
+// minimal elevation. The equator part inside the polygon is the visible arc. Longitudes are continuous, so they
+// can exceed 180 degrees. Observers far from the equator see over the pole, their polygon goes along the map edge
+// through the pole then. The first vertex is repeated at the end to close the polygon.
+func (ptr *Observer) Horizon() []geoPoint {
+	latitude, longitude := radians(ptr.Latitude), radians(ptr.Longitude)
+	x, y, z := ptr.ecef()
+	elevation := radians(ptr.MinElevation)
+
+	var points []geoPoint
+	previousLongitude := ptr.Longitude
+	for azimuth := 0; azimuth <= 360; azimuth += horizonStep {
+		// direction of the sight line in local east, north, up axes turned to Earth-centered ones
+		east := math.Cos(elevation) * math.Sin(radians(float64(azimuth)))
+		north := math.Cos(elevation) * math.Cos(radians(float64(azimuth)))
+		up := math.Sin(elevation)
+		dx := -math.Sin(longitude)*east - math.Sin(latitude)*math.Cos(longitude)*north +
+			math.Cos(latitude)*math.Cos(longitude)*up
+		dy := math.Cos(longitude)*east - math.Sin(latitude)*math.Sin(longitude)*north +
+			math.Cos(latitude)*math.Sin(longitude)*up
+		dz := math.Cos(latitude)*north + math.Sin(latitude)*up
+
+		// the sight line crosses the geostationary sphere at distance t, the observer is inside the sphere
+		b := x*dx + y*dy + z*dz
+		c := x*x + y*y + z*z - geostationaryRadius*geostationaryRadius
+		t := -b + math.Sqrt(b*b-c)
+		px, py, pz := x+t*dx, y+t*dy, z+t*dz
+
+		pointLongitude := degrees(math.Atan2(py, px))
+		for pointLongitude-previousLongitude > 180 {
+			pointLongitude -= 360
+		}
+		for pointLongitude-previousLongitude < -180 {
+			pointLongitude += 360
+		}
+		previousLongitude = pointLongitude
+		points = append(points, geoPoint{Longitude: round6(pointLongitude),
+			Latitude: round6(degrees(math.Asin(pz / geostationaryRadius)))})
+	}
+
+	first, last := points[0], points[len(points)-1]
+	if math.Abs(last.Longitude-first.Longitude) < 180 {
+		points[len(points)-1] = first
+		return points
+	}
+	// the boundary goes around the pole, so it is shifted to be centered on the observer and the polygon is closed
+	// along the pole
+	shift := math.Round(((first.Longitude+last.Longitude)/2-ptr.Longitude)/360) * 360
+	for i := range points {
+		points[i].Longitude = round6(points[i].Longitude - shift)
+	}
+	first, last = points[0], points[len(points)-1]
+	pole := math.Copysign(90, ptr.Latitude)
+	return append(points, geoPoint{Longitude: last.Longitude, Latitude: pole},
+		geoPoint{Longitude: first.Longitude, Latitude: pole}, first)
+}
+
+// round6 rounds coordinate to 6 digits after the point, about 10 cm on the ground.
+func round6(value float64) float64 {
+	return math.Round(value*1e6) / 1e6
+}
+
+// geoProperties returns properties of the satellite shown on maps.
+func geoProperties(sat *Satellite) [][2]string {
+	return [][2]string{
+		{"name", sat.GetName()},
+		{"position", formatOrbitalPosition(sat.GetPosition())},
+		{"band", sat.GetBand()},
+		{"url", sat.GetURL()},
+		{"tags", formatTags(sat.GetAllTags())},
+	}
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   geoJSONGeometry   `json:"geometry"`
+	Properties map[string]string `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// WriteGeoJSON writes satellites as points at their sub-satellite longitudes, the horizon polygon is added if it is
+// not nil.
+func WriteGeoJSON(writer io.Writer, list []Satellite, horizon []geoPoint) error {
+	collection := geoJSONFeatureCollection{Type: "FeatureCollection", Features: make([]geoJSONFeature, 0, len(list))}
+	for i := range list {
+		properties := make(map[string]string)
+		for _, property := range geoProperties(&list[i]) {
+			properties[property[0]] = property[1]
+		}
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "Point", Coordinates: []float64{list[i].GetPosition(), 0}},
+			Properties: properties,
+		})
+	}
+
+	if horizon != nil {
+		ring := make([][]float64, 0, len(horizon))
+		for _, point := range horizon {
+			ring = append(ring, []float64{point.Longitude, point.Latitude})
+		}
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "Polygon", Coordinates: [][][]float64{ring}},
+			Properties: map[string]string{"name": "horizon"},
+		})
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(collection)
+}
+
+type kmlDocument struct {
+	XMLName    xml.Name       `xml:"kml"`
+	Namespace  string         `xml:"xmlns,attr"`
+	Name       string         `xml:"Document>name"`
+	Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string      `xml:"name"`
+	Description string      `xml:"description,omitempty"`
+	Data        []kmlData   `xml:"ExtendedData>Data,omitempty"`
+	Point       *kmlPoint   `xml:"Point,omitempty"`
+	Polygon     *kmlPolygon `xml:"Polygon,omitempty"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlPolygon struct {
+	Coordinates string `xml:"outerBoundaryIs>LinearRing>coordinates"`
+}
+
+// kmlCoordinates returns KML coordinates tuple of the point on the ground.
+func kmlCoordinates(longitude, latitude float64) string {
+	return strconv.FormatFloat(longitude, 'f', -1, 64) + "," + strconv.FormatFloat(latitude, 'f', -1, 64) + ",0"
+}
+
+// WriteKML writes satellites as placemarks at their sub-satellite longitudes, the horizon polygon is added if it is
+// not nil.
+func WriteKML(writer io.Writer, list []Satellite, horizon []geoPoint) error {
+	document := kmlDocument{Namespace: "http://www.opengis.net/kml/2.2", Name: "Satellites",
+		Placemarks: make([]kmlPlacemark, 0, len(list))}
+	for i := range list {
+		sat := &list[i]
+		placemark := kmlPlacemark{
+			Name:        sat.GetName(),
+			Description: fmt.Sprintf("%s %s", formatOrbitalPosition(sat.GetPosition()), sat.GetBand()),
+			Point:       &kmlPoint{Coordinates: kmlCoordinates(sat.GetPosition(), 0)},
+		}
+		for _, property := range geoProperties(sat) {
+			placemark.Data = append(placemark.Data, kmlData{Name: property[0], Value: property[1]})
+		}
+		document.Placemarks = append(document.Placemarks, placemark)
+	}
+
+	if horizon != nil {
+		polygon := &kmlPolygon{}
+		for i, point := range horizon {
+			if i > 0 {
+				polygon.Coordinates += " "
+			}
+			polygon.Coordinates += kmlCoordinates(point.Longitude, point.Latitude)
+		}
+		document.Placemarks = append(document.Placemarks, kmlPlacemark{Name: "horizon", Polygon: polygon})
+	}
+
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, "\n")
+	return err
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func TestHorizon(t *testing.T) {
+	observer := &Observer{Longitude: 10, MinElevation: 5}
+	horizon := observer.Horizon()
+
+	require.Len(t, horizon, 360/horizonStep+1)
+	assert.Equal(t, horizon[0], horizon[len(horizon)-1], "polygon must be closed")
+
+	// satellites at the polygon edge on the equator are seen at the minimal elevation
+	east := horizon[90/horizonStep]
+	assert.InDelta(t, 0, east.Latitude, 0.001)
+	assert.InDelta(t, 5, observer.LookAngles(east.Longitude).Elevation, 0.001)
+	for _, point := range horizon {
+		assert.InDelta(t, observer.Longitude, point.Longitude, 90)
+	}
+}
+
+func TestHorizonAroundPole(t *testing.T) {
+	observer := &Observer{Latitude: 51.5074, Longitude: -0.1278, MinElevation: 5}
+	horizon := observer.Horizon()
+
+	require.Len(t, horizon, 360/horizonStep+4)
+	assert.Equal(t, horizon[0], horizon[len(horizon)-1], "polygon must be closed")
+	assert.Equal(t, 90.0, horizon[len(horizon)-2].Latitude, "polygon must go through the pole")
+	assert.InDelta(t, 360, math.Abs(horizon[len(horizon)-2].Longitude-horizon[len(horizon)-3].Longitude), 0.001)
+
+	// the southern vertex lies below the equator, so the visible arc crosses the whole polygon
+	south := horizon[180/horizonStep]
+	assert.Less(t, south.Latitude, 0.0)
+	assert.InDelta(t, observer.Longitude, south.Longitude, 0.001)
+}
+
+func TestWriteGeoJSON(t *testing.T) {
+	list := testExportList()
+	list[2].SetTags([]string{"hotbird"})
+	horizon := (&Observer{Latitude: 51.5074, Longitude: -0.1278, MinElevation: 5}).Horizon()
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteGeoJSON(&buffer, list, horizon))
+	assertGolden(t, "satellites.geojson", buffer.Bytes())
+
+	var collection geoJSONFeatureCollection
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &collection))
+	assert.Len(t, collection.Features, len(list)+1)
+}
+
+func TestWriteKML(t *testing.T) {
+	list := testExportList()
+	list[2].SetTags([]string{"hotbird"})
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteKML(&buffer, list, []geoPoint{{1, 2}, {3, 4}, {5, 2}, {1, 2}}))
+	assertGolden(t, "satellites.kml", buffer.Bytes())
+}
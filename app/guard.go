@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sort"
+)
+
+// SyncPlan holds all changes which are going to be applied to database during one run.
+type SyncPlan struct {
+	DbList      []Satellite
+	OnlineList  []Satellite
+	Inserts     []Satellite
+	Closes      []Satellite
+	Updates     [][]Satellite
+	Relocations []Relocation
+	// Sources are stored satellites with new sources, a satellite moved to another page is not changed. Updated
+	// satellites are not listed, their update already saves new sources.
+	Sources []Satellite
+}
+
+// pageStats holds counts of parsed, stored and changed satellites of one source page.
+type pageStats struct {
+	parsed  int
+	stored  int
+	inserts int
+	closes  int
+	updates int
+}
+
+// MakeSyncPlan finds new, absent and changed satellites between database and online lists.
+func MakeSyncPlan(dbList, onlineList *[]Satellite) *SyncPlan {
+	plan := &SyncPlan{
+		DbList:     *dbList,
+		OnlineList: *onlineList,
+		Inserts:    FindNewElements(dbList, onlineList),
+		Closes:     FindAbsent(dbList, onlineList),
+		Updates:    FindChanged(dbList, onlineList, &getProperties().Comparison),
+	}
+	plan.Relocations = FindRelocations(&plan.Updates, getProperties().Relocation.Tolerance)
+	plan.Sources = withoutUpdated(FindSourceChanges(dbList, onlineList), plan.Updates)
+	return plan
+}
+
+// withoutUpdated returns satellites of the list which are not updated by any of update pairs.
+func withoutUpdated(list []Satellite, updates [][]Satellite) []Satellite {
+	updated := make(map[string]bool, len(updates))
+	for _, pair := range updates {
+		updated[pair[1].GetName()] = true
+	}
+
+	var result []Satellite
+	for _, sat := range list {
+		if !updated[sat.GetName()] {
+			result = append(result, sat)
+		}
+	}
+	return result
+}
+
+// Report logs every change of the plan, it is used to show what would have been done when sync is refused.
+func (ptr *SyncPlan) Report() {
+	log.Warnf("sync plan: %d to insert, %d to close, %d to update (%d relocations)",
+		len(ptr.Inserts), len(ptr.Closes), len(ptr.Updates), len(ptr.Relocations))
+	for _, sat := range ptr.Inserts {
+		log.Warnf("would insert %v", sat)
+	}
+	for _, sat := range ptr.Closes {
+		log.Warnf("would close %v", sat)
+	}
+	for _, pair := range ptr.Updates {
+		log.Warnf("would update %v with new values %v", pair[0], pair[1])
+	}
+	for _, relocation := range ptr.Relocations {
+		log.Warnf("would record relocation: %v", relocation)
+	}
+}
+
+// Summary logs run status totals and every relocation of the plan as a separate line.
+func (ptr *SyncPlan) Summary(status *SyncStatus) {
+	for _, err := range status.Errors {
+		log.Error(err)
+	}
+
+	if status.RolledBack {
+		log.Errorf("sync rolled back: %d out of %d changes failed", status.Failed,
+			len(ptr.Inserts)+len(ptr.Closes)+len(ptr.Updates)+len(ptr.Relocations))
+		return
+	}
+
+	log.Infof("sync finished: %d inserted, %d closed, %d updated, %d relocated, %d failed",
+		status.Inserted, status.Closed, status.Updated, status.Relocated, status.Failed)
+	for _, relocation := range ptr.Relocations {
+		log.Infof("relocation: %v", relocation)
+	}
+}
+
+// pageStats groups plan changes by source page. Stored satellites keep the page they were parsed from, so closes
+// of a page which comes back empty are counted against it. Satellites without known source, i.e. stored before
+// sources were kept and not synced since, are skipped and limited by run limits only.
+func (ptr *SyncPlan) pageStats() map[string]*pageStats {
+	pages := make(map[string]*pageStats)
+	get := func(source string) *pageStats {
+		stats, found := pages[source]
+		if !found {
+			stats = &pageStats{}
+			pages[source] = stats
+		}
+		return stats
+	}
+
+	for _, sat := range ptr.OnlineList {
+		get(sat.GetSource()).parsed++
+	}
+	for _, sat := range ptr.DbList {
+		get(sat.GetSource()).stored++
+	}
+	for _, sat := range ptr.Inserts {
+		get(sat.GetSource()).inserts++
+	}
+	for _, sat := range ptr.Closes {
+		get(sat.GetSource()).closes++
+	}
+	for _, pair := range ptr.Updates {
+		get(pair[1].GetSource()).updates++
+	}
+
+	delete(pages, "")
+	return pages
+}
+
+// CheckGuard checks the plan against run and page limits and returns descriptions of all exceeded limits.
+// Run percentages are calculated from the count of active satellites in database. Page percentages of inserts
+// and updates are calculated from the count of satellites parsed from the page, of closes - from the count of
+// stored satellites of the page.
+func CheckGuard(plan *SyncPlan, runLimits, pageLimits *ChangeLimits) []string {
+	stored := len(plan.DbList)
+	violations := checkLimits("run", runLimits,
+		pageStats{parsed: stored, stored: stored,
+			inserts: len(plan.Inserts), closes: len(plan.Closes), updates: len(plan.Updates)})
+
+	pages := plan.pageStats()
+	sources := make([]string, 0, len(pages))
+	for source := range pages {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		violations = append(violations, checkLimits("page "+source, pageLimits, *pages[source])...)
+	}
+	return violations
+}
+
+func checkLimits(scope string, limits *ChangeLimits, stats pageStats) []string {
+	var violations []string
+	violations = checkLimit(violations, scope, "inserts", stats.inserts, stats.parsed,
+		limits.MaxInserts, limits.MaxInsertsPercent)
+	violations = checkLimit(violations, scope, "closes", stats.closes, stats.stored,
+		limits.MaxCloses, limits.MaxClosesPercent)
+	violations = checkLimit(violations, scope, "updates", stats.updates, stats.parsed,
+		limits.MaxUpdates, limits.MaxUpdatesPercent)
+	return violations
+}
+
+// checkLimit appends violation descriptions if count exceeds max or maxPercent of base. Zero limits are ignored,
+// as well as percent limit when base is zero (e.g. the very first run).
+func checkLimit(violations []string, scope, kind string, count, base int, max int64, maxPercent float64) []string {
+	if max > 0 && int64(count) > max {
+		violations = append(violations, fmt.Sprintf("%s: %d %s exceed limit of %d", scope, count, kind, max))
+	}
+
+	if maxPercent > 0 && base > 0 {
+		if percent := float64(count) * 100 / float64(base); percent > maxPercent {
+			violations = append(violations, fmt.Sprintf("%s: %d %s (%.1f%% of %d) exceed limit of %.1f%%",
+				scope, count, kind, percent, base, maxPercent))
+		}
+	}
+	return violations
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func makeSourcedSat(name string, position float64, source string) Satellite {
+	sat := makeSat(name, position)
+	sat.SetSource(source)
+	return sat
+}
+
+func TestGuardNoLimits(t *testing.T) {
+	dbList := []Satellite{makeSat("one", 1), makeSat("two", 2)}
+	var onlineList []Satellite
+
+	plan := MakeSyncPlan(&dbList, &onlineList)
+
+	assert.Len(t, plan.Closes, 2)
+	assert.Empty(t, CheckGuard(plan, &ChangeLimits{}, &ChangeLimits{}))
+}
+
+func TestGuardRunAbsoluteLimit(t *testing.T) {
+	dbList := []Satellite{makeSat("one", 1), makeSat("two", 2), makeSat("three", 3)}
+	onlineList := []Satellite{makeSat("one", 1)}
+
+	plan := MakeSyncPlan(&dbList, &onlineList)
+
+	assert.Empty(t, CheckGuard(plan, &ChangeLimits{MaxCloses: 2}, &ChangeLimits{}))
+	assert.Len(t, CheckGuard(plan, &ChangeLimits{MaxCloses: 1}, &ChangeLimits{}), 1)
+}
+
+func TestGuardRunPercentLimit(t *testing.T) {
+	dbList := []Satellite{makeSat("one", 1), makeSat("two", 2), makeSat("three", 3), makeSat("four", 4)}
+	onlineList := []Satellite{makeSat("one", 1), makeSat("two", 2), makeSat("three", 3),
+		makeSat("five", 5), makeSat("six", 6)}
+
+	plan := MakeSyncPlan(&dbList, &onlineList)
+
+	assert.Empty(t, CheckGuard(plan, &ChangeLimits{MaxClosesPercent: 25, MaxInsertsPercent: 50}, &ChangeLimits{}))
+	assert.Len(t, CheckGuard(plan, &ChangeLimits{MaxClosesPercent: 20, MaxInsertsPercent: 40}, &ChangeLimits{}), 2)
+}
+
+func TestGuardPercentLimitOnEmptyDb(t *testing.T) {
+	var dbList []Satellite
+	onlineList := []Satellite{makeSat("one", 1), makeSat("two", 2)}
+
+	plan := MakeSyncPlan(&dbList, &onlineList)
+
+	assert.Empty(t, CheckGuard(plan, &ChangeLimits{MaxInsertsPercent: 1}, &ChangeLimits{}),
+		"percent limits must not be checked on the first run")
+}
+
+func TestGuardPageLimit(t *testing.T) {
+	dbList := []Satellite{makeSat("one", 1), makeSat("two", 2)}
+	onlineList := []Satellite{
+		makeSourcedSat("one", 1, "asia"), makeSourcedSat("two", 3, "asia"),
+		makeSourcedSat("three", 3, "europe"), makeSourcedSat("four", 4, "europe")}
+
+	plan := MakeSyncPlan(&dbList, &onlineList)
+
+	violations := CheckGuard(plan, &ChangeLimits{}, &ChangeLimits{MaxInserts: 1, MaxUpdatesPercent: 50})
+	assert.Equal(t, []string{"page europe: 2 inserts exceed limit of 1"}, violations)
+}
+
+func TestGuardPageCloseLimit(t *testing.T) {
+	dbList := []Satellite{
+		makeSourcedSat("one", 1, "asia"), makeSourcedSat("two", 2, "asia"),
+		makeSourcedSat("three", 3, "europe"), makeSourcedSat("four", 4, "europe"),
+		makeSourcedSat("five", 5, "europe")}
+	onlineList := []Satellite{makeSourcedSat("one", 1, "asia"), makeSourcedSat("two", 2, "asia")}
+
+	plan := MakeSyncPlan(&dbList, &onlineList)
+
+	assert.Empty(t, CheckGuard(plan, &ChangeLimits{MaxClosesPercent: 70}, &ChangeLimits{}),
+		"run limit alone must let the empty page through")
+	assert.Equal(t, []string{"page europe: 3 closes (100.0% of 3) exceed limit of 30.0%"},
+		CheckGuard(plan, &ChangeLimits{MaxClosesPercent: 70}, &ChangeLimits{MaxClosesPercent: 30}))
+}
+
+func TestGuardPageCloseLimitOfStoredSources(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	onlineList := []Satellite{makeSourcedSat("one", 1, "asia"), makeSourcedSat("two", 2, "europe"),
+		makeSourcedSat("three", 3, "europe")}
+	require.True(t, syncTestRepository(t, repository, onlineList, true, 100).Succeeded())
+
+	dbList, err := repository.LoadActive()
+	require.NoError(t, err)
+	onlineList = onlineList[:1]
+	plan := MakeSyncPlan(&dbList, &onlineList)
+
+	assert.Equal(t, []string{"page europe: 2 closes (100.0% of 2) exceed limit of 30.0%"},
+		CheckGuard(plan, &ChangeLimits{}, &ChangeLimits{MaxClosesPercent: 30}),
+		"stored satellites must be counted against the page they were parsed from")
+}
+
+func TestChangedSourceIgnored(t *testing.T) {
+	dbList := []Satellite{makeSat("one", 1)}
+	onlineList := []Satellite{makeSourcedSat("one", 1, "asia")}
+
+	assert.Empty(t, FindChanged(&dbList, &onlineList, &Comparator{}))
+}
+
+func TestSyncPlanSourcesOfUpdated(t *testing.T) {
+	dbList := []Satellite{makeSourcedSat("one", 1, "asia"), makeSourcedSat("two", 2, "asia")}
+	moved := makeSourcedSat("one", 1, "europe")
+	moved.SetBand("Ku")
+	onlineList := []Satellite{moved, makeSourcedSat("two", 2, "europe")}
+
+	plan := MakeSyncPlan(&dbList, &onlineList)
+	require.Len(t, plan.Updates, 1)
+	assert.Equal(t, "europe", plan.Updates[0][1].GetSource())
+	assert.Equal(t, []Satellite{makeSourcedSat("two", 2, "europe")}, plan.Sources,
+		"source of the updated satellite is saved by its update")
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strconv"
+)
+
+const (
+	fieldPosition = "position"
+	fieldURL      = "url"
+	fieldBand     = "band"
+	fieldTags     = "tags"
+)
+
+// HistoryRecord is a struct to hold one changed field of a satellite.
+type HistoryRecord struct {
+	SatelliteID int64  `db:"_satellite_id"`
+	Name        string `db:"_name"`
+	Field       string `db:"_field"`
+	OldValue    string `db:"_old_value"`
+	NewValue    string `db:"_new_value"`
+	RunID       string `db:"_run_id"`
+	Changed     string `db:"_changed"`
+}
+
+func formatPosition(position float64) string {
+	return strconv.FormatFloat(position, 'f', -1, 64)
+}
+
+// DiffSatellites returns history records for every field which the comparator treats as changed between old and
+// new satellites, so insignificant differences are stored silently like they are on unchanged satellites. Records
+// refer to the stored old satellite.
+func DiffSatellites(oldSat, newSat *Satellite, comparator *Comparator) []HistoryRecord {
+	values := map[string][2]string{
+		fieldPosition: {formatPosition(oldSat.GetPosition()), formatPosition(newSat.GetPosition())},
+		fieldURL:      {oldSat.GetURL(), newSat.GetURL()},
+		fieldBand:     {oldSat.GetBand(), newSat.GetBand()},
+		fieldTags:     {oldSat.Tags, newSat.Tags},
+	}
+
+	var records []HistoryRecord
+	for _, field := range comparator.Differences(oldSat, newSat) {
+		records = append(records, HistoryRecord{
+			SatelliteID: oldSat.GetID(),
+			Name:        newSat.GetName(),
+			Field:       field,
+			OldValue:    values[field][0],
+			NewValue:    values[field][1],
+			RunID:       runID,
+		})
+	}
+	return records
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDiffSatellitesNoChanges(t *testing.T) {
+	sat := makeSat("one", 1)
+	changed := sat
+	changed.SetSource("asia")
+
+	assert.Empty(t, DiffSatellites(&sat, &changed, &Comparator{}))
+}
+
+func TestDiffSatellitesUsesComparator(t *testing.T) {
+	initial := makeSat("one", 13)
+	initial.URL = "https://www.lyngsat.com/Hot-Bird-13E.html"
+	changed := initial
+	changed.SetPosition(13.0004)
+	changed.URL = "https://www.lyngsat.com/hot-bird-13e.html"
+	changed.SetBand("Ku")
+
+	comparator := &Comparator{Position: NumberComparison{Tolerance: 0.001}, URL: TextComparison{IgnoreCase: true}}
+	assert.Equal(t, []HistoryRecord{{Name: "one", Field: fieldBand, OldValue: "", NewValue: "Ku", RunID: runID}},
+		DiffSatellites(&initial, &changed, comparator),
+		"fields the comparator treats as equal must not be recorded")
+}
+
+func TestDiffSatellites(t *testing.T) {
+	initial := makeSat("one", 13)
+	changed := initial
+	changed.SetPosition(13.2)
+	changed.SetBand("Ku")
+
+	records := DiffSatellites(&initial, &changed, &Comparator{})
+
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, HistoryRecord{Name: "one", Field: fieldPosition, OldValue: "13", NewValue: "13.2",
+			RunID: runID}, records[0])
+		assert.Equal(t, HistoryRecord{Name: "one", Field: fieldBand, OldValue: "", NewValue: "Ku",
+			RunID: runID}, records[1])
+	}
+}
+
+func TestFailedHistoryRollsBackUpdate(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	require.True(t, syncTestRepository(t, repository, []Satellite{makeSat("one", 1)}, true, 100).Succeeded())
+	_, err := repository.db.Exec(`DROP TABLE "satellites_history"`)
+	require.NoError(t, err)
+
+	status := syncTestRepository(t, repository, []Satellite{makeSat("one", 2)}, false, 100)
+	assert.False(t, status.Succeeded(), "update must fail when its history cannot be written")
+	assert.Equal(t, []Satellite{makeSat("one", 1)}, loadTestActive(t, repository),
+		"update must not be stored without its history")
+}
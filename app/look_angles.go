@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math"
+)
+
+const (
+	// geostationaryRadius is the distance from the Earth center to geostationary satellites in km
+	geostationaryRadius = 42164.17
+	// WGS84 ellipsoid semi-major axis in km and squared eccentricity
+	earthRadius       = 6378.137
+	earthEccentricity = 0.00669437999014
+)
+
+// Observer is a location on the Earth surface. Latitude and longitude are in degrees, north and east are
+// positive, altitude is in meters above the ellipsoid. Declination is a magnetic declination in degrees, east
+// is positive. Satellites lower than MinElevation degrees are not visible.
+type Observer struct {
+	Latitude     float64 `hocon:"node=latitude,default=0"`
+	Longitude    float64 `hocon:"node=longitude,default=0"`
+	Altitude     float64 `hocon:"node=altitude,default=0"`
+	Declination  float64 `hocon:"node=declination,default=0"`
+	MinElevation float64 `hocon:"node=minElevation,default=5"`
+}
+
+// LookAngle holds directions to aim a dish at a satellite. Angles are in degrees, azimuths are clockwise from the
+// north. Positive skew means clockwise rotation of LNB looking at the satellite from behind the dish.
+type LookAngle struct {
+	Azimuth         float64
+	MagneticAzimuth float64
+	Elevation       float64
+	Skew            float64
+	Visible         bool
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
+// normalizeAzimuth returns the same direction in range [0, 360).
+func normalizeAzimuth(azimuth float64) float64 {
+	azimuth = math.Mod(azimuth, 360)
+	if azimuth < 0 {
+		azimuth += 360
+	}
+	return azimuth
+}
+
+// ecef returns Earth-centered coordinates of the observer in km.
+func (ptr *Observer) ecef() (x, y, z float64) {
+	latitude, longitude := radians(ptr.Latitude), radians(ptr.Longitude)
+	altitude := ptr.Altitude / 1000
+	n := earthRadius / math.Sqrt(1-earthEccentricity*math.Pow(math.Sin(latitude), 2))
+	x = (n + altitude) * math.Cos(latitude) * math.Cos(longitude)
+	y = (n + altitude) * math.Cos(latitude) * math.Sin(longitude)
+	z = (n*(1-earthEccentricity) + altitude) * math.Sin(latitude)
+	return x, y, z
+}
+
+// LookAngles returns directions from the observer to the geostationary satellite at given orbital position
+// in degrees, east positions are positive.
+func (ptr *Observer) LookAngles(position float64) LookAngle {
+	latitude, longitude := radians(ptr.Latitude), radians(ptr.Longitude)
+	x, y, z := ptr.ecef()
+	dx := geostationaryRadius*math.Cos(radians(position)) - x
+	dy := geostationaryRadius*math.Sin(radians(position)) - y
+	dz := -z
+
+	// range vector in local east, north, up axes
+	east := -math.Sin(longitude)*dx + math.Cos(longitude)*dy
+	north := -math.Sin(latitude)*math.Cos(longitude)*dx - math.Sin(latitude)*math.Sin(longitude)*dy +
+		math.Cos(latitude)*dz
+	up := math.Cos(latitude)*math.Cos(longitude)*dx + math.Cos(latitude)*math.Sin(longitude)*dy +
+		math.Sin(latitude)*dz
+
+	azimuth := normalizeAzimuth(degrees(math.Atan2(east, north)))
+	elevation := degrees(math.Atan2(up, math.Hypot(east, north)))
+
+	var skew float64
+	if delta := math.Sin(longitude - radians(position)); delta != 0 {
+		skew = degrees(math.Atan(delta / math.Tan(latitude)))
+	}
+
+	return LookAngle{
+		Azimuth:         azimuth,
+		MagneticAzimuth: normalizeAzimuth(azimuth - ptr.Declination),
+		Elevation:       elevation,
+		Skew:            skew,
+		Visible:         elevation >= ptr.MinElevation,
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLookAngles(t *testing.T) {
+	// reference values of the spherical model used by dish pointing tables, the ellipsoid differs by hundredths
+	london := Observer{Latitude: 51.5074, Longitude: -0.1278, Declination: -0.5, MinElevation: 5}
+	angle := london.LookAngles(28.2)
+	assert.InDelta(t, 145.4, angle.Azimuth, 0.1)
+	assert.InDelta(t, 145.9, angle.MagneticAzimuth, 0.1)
+	assert.InDelta(t, 25.4, angle.Elevation, 0.1)
+	assert.InDelta(t, -20.7, angle.Skew, 0.1)
+	assert.True(t, angle.Visible)
+
+	sydney := Observer{Latitude: -33.8688, Longitude: 151.2093}
+	angle = sydney.LookAngles(156)
+	assert.InDelta(t, 8.6, angle.Azimuth, 0.1, "southern observers look to the north")
+	assert.InDelta(t, 50.3, angle.Elevation, 0.1)
+
+	equator := Observer{MinElevation: 5}
+	angle = equator.LookAngles(0)
+	assert.InDelta(t, 90, angle.Elevation, 0.001)
+	assert.Equal(t, 0.0, angle.Skew)
+
+	angle = equator.LookAngles(100)
+	assert.Less(t, angle.Elevation, 0.0)
+	assert.False(t, angle.Visible, "satellites behind the horizon are not visible")
+}
+
+func TestNormalizeAzimuth(t *testing.T) {
+	assert.Equal(t, 350.0, normalizeAzimuth(-10))
+	assert.Equal(t, 10.0, normalizeAzimuth(370))
+}
@@ -1,31 +1,49 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
 	log "github.com/sirupsen/logrus"
+	"time"
 )
 
-func getOnlineList(ch chan []Satellite) {
-	onlineList, errorz := parseOnline()
+var (
+	// force given before the command name applies to the default sync, e.g. sat-parser --force
+	force = flag.Bool("force", false, "apply changes even if safety guard limits are exceeded")
+	runID = newRunID()
 
-	log.Infof("online parsing finished, satellites count - %d", len(onlineList))
+	revision = "unknown" // set by build flags
+)
 
-	if errorzLen := len(errorz); errorzLen > 0 {
-		for _, err := range errorz {
-			log.Error(err)
-		}
+// newRunID generates unique identifier of the current run, e.g. 20200301T120000Z-1a2b3c4d.
+func newRunID() string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
 
-		log.Fatalf("some errors [%d] occurred during parsing, check them at first", errorzLen)
-	}
+// onlineResult holds satellites parsed from all pages, stats of the pages and occurred errors.
+type onlineResult struct {
+	satellites []Satellite
+	pages      []PageStats
+	errorz     []error
+}
+
+func getOnlineList(ch chan onlineResult) {
+	onlineList, pages, errorz := parseOnline()
 
-	ch <- onlineList
+	log.Infof("online parsing finished, satellites count - %d", len(onlineList))
+
+	ch <- onlineResult{satellites: onlineList, pages: pages, errorz: errorz}
 }
 
 func getDBList(ch chan []Satellite) {
 	ch <- LoadDbSatellites()
 }
 
-func getLists() (onlineList []Satellite, dbList []Satellite) {
-	chOnline, chDB := make(chan []Satellite), make(chan []Satellite)
+func getLists() (online onlineResult, dbList []Satellite) {
+	chOnline, chDB := make(chan onlineResult), make(chan []Satellite)
 	defer func() {
 		close(chOnline)
 		close(chDB)
@@ -38,25 +56,63 @@ func getLists() (onlineList []Satellite, dbList []Satellite) {
 }
 
 func main() {
+	flag.Parse()
+
 	level, err := log.ParseLevel(getProperties().LogLevel)
 	if err == nil {
 		log.SetLevel(level)
 	}
 
-	onlineList, dbList := getLists()
+	runCommand(flag.Args())
+}
+
+// syncSatellites loads online and database lists and applies found changes to database, forced sync applies them
+// even if safety guard limits are exceeded. Every run is recorded in the runs log, refused and failed ones too.
+func syncSatellites(forced bool) {
+	log.Infof("sync started, run %s, revision %s", runID, revision)
+	run := NewSyncRun()
+
+	online, dbList := getLists()
+	onlineList := online.satellites
+	run.SetPages(online.pages)
+	run.Parsed = int64(len(onlineList))
+	run.ParseErrors = int64(len(online.errorz))
+
+	if errorzLen := len(online.errorz); errorzLen > 0 {
+		for _, err := range online.errorz {
+			log.Error(err)
+		}
 
-	newItems := FindNewElements(&dbList, &onlineList)
-	if len(newItems) > 0 {
-		InsertSatellites(&newItems)
+		SaveSyncRun(run, runParseFailed)
+		log.Fatalf("some errors [%d] occurred during parsing, check them at first", errorzLen)
+	}
+
+	for _, report := range ApplyOverrides(&onlineList, getOverrides(), time.Now()) {
+		log.Warn(report)
 	}
+	ApplyTagRules(&onlineList, getTagRules())
 
-	absentItems := FindAbsent(&dbList, &onlineList)
-	if len(absentItems) > 0 {
-		MarkSatellitesClosed(&absentItems)
+	plan := MakeSyncPlan(&dbList, &onlineList)
+	guard := &getProperties().Guard
+	if violations := CheckGuard(plan, &guard.Run, &guard.Page); len(violations) > 0 {
+		for _, violation := range violations {
+			log.Warn(violation)
+		}
+
+		if !forced {
+			plan.Report()
+			SaveSyncRun(run, runRefused)
+			log.Fatalf("safety guard refused sync, %d limits exceeded. Use --force to apply changes anyway",
+				len(violations))
+		}
+		log.Warn("safety guard limits exceeded, applying changes anyway because of --force")
 	}
 
-	changedItems := FindChanged(&dbList, &onlineList)
-	if len(changedItems) > 0 {
-		UpdateSatellites(&changedItems)
+	status := ApplySyncPlan(getRepository(), plan, getProperties().Sync.Atomic, int(getProperties().Sync.BatchSize))
+	plan.Summary(status)
+	run.SetSyncStatus(status)
+	SaveSyncRun(run, runSucceeded)
+	if !status.Succeeded() {
+		log.Fatal("sync finished with errors")
 	}
 }
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+)
+
+// migration is one versioned schema change. Statements may contain table placeholders, see sqlTables.
+type migration struct {
+	version     int
+	description string
+	up          []string
+	down        []string
+	// legacy statements are applied instead of up statements if the satellites table was created before
+	// migrations, i.e. it exists and has no _id column
+	legacy []string
+}
+
+// MigrationStatus is a struct to hold state of one migration.
+type MigrationStatus struct {
+	Version     int    `db:"_version"`
+	Description string `db:"_description"`
+	Applied     string `db:"_applied"` // empty if the migration is not applied
+}
+
+// Migrator is implemented by storages with versioned schema.
+type Migrator interface {
+	// SchemaVersion returns version of the last applied migration, zero for empty schema.
+	SchemaVersion() (int, error)
+	// LatestSchemaVersion returns version of the last known migration.
+	LatestSchemaVersion() int
+	// MigrateUp applies all pending migrations and returns count of applied ones.
+	MigrateUp() (int, error)
+	// MigrateDown reverts given count of the last applied migrations and returns count of reverted ones.
+	MigrateDown(steps int) (int, error)
+	// MigrationStatus returns states of all known and applied migrations ordered by version.
+	MigrationStatus() ([]MigrationStatus, error)
+	// VerifySchema returns error if the schema does not match applied migrations.
+	VerifySchema() error
+}
+
+// checkSchema refuses storages with outdated or unknown schema, outdated schema is migrated if autoMigrate is set.
+func checkSchema(repository SatelliteRepository, autoMigrate bool) error {
+	migrator, ok := repository.(Migrator)
+	if !ok {
+		return nil
+	}
+
+	version, err := migrator.SchemaVersion()
+	if err != nil {
+		return fmt.Errorf("cannot get schema version: %w", err)
+	}
+
+	if version > 0 {
+		if err := migrator.VerifySchema(); err != nil {
+			return err
+		}
+	}
+
+	latest := migrator.LatestSchemaVersion()
+	if version > latest {
+		return fmt.Errorf("schema version %d is newer than supported %d, update sat-parser", version, latest)
+	}
+
+	if version < latest {
+		if !autoMigrate {
+			return fmt.Errorf("schema version %d is older than required %d, run 'sat-parser migrate up'",
+				version, latest)
+		}
+
+		log.Infof("schema version %d is older than required %d, migrating ...", version, latest)
+		if _, err := migrator.MigrateUp(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SchemaVersion returns version of the last applied migration, zero for empty schema.
+func (ptr *sqlRepository) SchemaVersion() (int, error) {
+	applied, err := ptr.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(applied) == 0 {
+		return 0, nil
+	}
+	return applied[len(applied)-1].Version, nil
+}
+
+// LatestSchemaVersion returns version of the last known migration.
+func (ptr *sqlRepository) LatestSchemaVersion() int {
+	migrations := ptr.dialect.migrations
+	return migrations[len(migrations)-1].version
+}
+
+func (ptr *sqlRepository) appliedMigrations() ([]MigrationStatus, error) {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	var applied []MigrationStatus
+	if err := ptr.db.SelectContext(ctx, &applied, ptr.stmts.selectMigrations); err != nil {
+		return nil, fmt.Errorf("cannot load applied migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// MigrateUp applies all pending migrations and returns count of applied ones.
+func (ptr *sqlRepository) MigrateUp() (int, error) {
+	version, err := ptr.SchemaVersion()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range ptr.dialect.migrations {
+		if m.version <= version {
+			continue
+		}
+
+		stmts := m.up
+		if m.legacy != nil && ptr.hasLegacyTable() {
+			log.Infof("table %s was created before migrations, it is upgraded", ptr.table)
+			stmts = m.legacy
+		}
+
+		log.Infof("applying migration %d: %s ...", m.version, m.description)
+		if err := ptr.runMigration(stmts, ptr.stmts.insertMigration, m.version, m.description); err != nil {
+			return count, fmt.Errorf("cannot apply migration %d: %w", m.version, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// MigrateDown reverts given count of the last applied migrations and returns count of reverted ones.
+func (ptr *sqlRepository) MigrateDown(steps int) (int, error) {
+	count := 0
+	for ; count < steps; count++ {
+		version, err := ptr.SchemaVersion()
+		if err != nil {
+			return count, err
+		}
+		if version == 0 {
+			break
+		}
+
+		m, found := ptr.findMigration(version)
+		if !found {
+			return count, fmt.Errorf("cannot revert unknown migration %d", version)
+		}
+
+		log.Infof("reverting migration %d: %s ...", m.version, m.description)
+		if err := ptr.runMigration(m.down, ptr.stmts.deleteMigration, m.version); err != nil {
+			return count, fmt.Errorf("cannot revert migration %d: %w", m.version, err)
+		}
+	}
+	return count, nil
+}
+
+// VerifySchema returns error if migrations are applied to the satellites table created before them. Such table
+// has no _id column, which identifies satellites in all statements, and migrations cannot fix it anymore.
+func (ptr *sqlRepository) VerifySchema() error {
+	if ptr.hasLegacyTable() {
+		return fmt.Errorf("table %s has no _id column, it was created before migrations and not upgraded by them. "+
+			"Restore the table from a backup made before the upgrade and run 'sat-parser migrate up'", ptr.table)
+	}
+	return nil
+}
+
+// hasLegacyTable returns true if the satellites table exists and has no _id column, like tables created by
+// sat-parser before migrations. Columns are probed by queries, so every dialect is checked the same way.
+func (ptr *sqlRepository) hasLegacyTable() bool {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	if _, err := ptr.db.ExecContext(ctx, ptr.stmts.probeTable); err != nil {
+		return false
+	}
+	_, err := ptr.db.ExecContext(ctx, ptr.stmts.probeIdentity)
+	return err != nil
+}
+
+func (ptr *sqlRepository) findMigration(version int) (migration, bool) {
+	for _, m := range ptr.dialect.migrations {
+		if m.version == version {
+			return m, true
+		}
+	}
+	return migration{}, false
+}
+
+// runMigration executes statements and records the result in one transaction. MySQL commits DDL statements
+// implicitly, so a failed migration may be left partially applied there. Migrations are not limited by query
+// timeout, schema changes of large tables may take long.
+func (ptr *sqlRepository) runMigration(stmts []string, record string, args ...interface{}) error {
+	expander := ptr.dialect.expander(ptr.table)
+
+	tx, err := ptr.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(expander.Replace(stmt)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(record, args...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// MigrationStatus returns states of all known and applied migrations ordered by version.
+func (ptr *sqlRepository) MigrationStatus() ([]MigrationStatus, error) {
+	applied, err := ptr.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	appliedMap := make(map[int]MigrationStatus, len(applied))
+	for _, status := range applied {
+		appliedMap[status.Version] = status
+	}
+
+	var statuses []MigrationStatus
+	for _, m := range ptr.dialect.migrations {
+		status := MigrationStatus{Version: m.version, Description: m.description}
+		if appliedStatus, found := appliedMap[m.version]; found {
+			status.Applied = appliedStatus.Applied
+			delete(appliedMap, m.version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	// applied migrations which are unknown to this version of sat-parser
+	for _, status := range applied {
+		if _, found := appliedMap[status.Version]; found {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
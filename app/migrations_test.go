@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMigrationVersionsAreSequential(t *testing.T) {
+	for _, dialect := range []*sqlDialect{mysqlDialect, postgresDialect, sqliteDialect} {
+		for i, m := range dialect.migrations {
+			assert.Equal(t, i+1, m.version, dialect.name)
+			assert.NotEmpty(t, m.up, dialect.name)
+			assert.NotEmpty(t, m.down, dialect.name)
+		}
+		assert.Equal(t, len(mysqlDialect.migrations), len(dialect.migrations),
+			"all dialects must have the same migrations")
+	}
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	latest := repository.LatestSchemaVersion()
+	version, err := repository.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+
+	count, err := repository.MigrateUp()
+	require.NoError(t, err)
+	assert.Zero(t, count, "nothing to apply on the latest schema")
+
+	count, err = repository.MigrateDown(1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	statuses, err := repository.MigrationStatus()
+	require.NoError(t, err)
+	require.Len(t, statuses, latest)
+	assert.NotEmpty(t, statuses[0].Applied)
+	assert.Empty(t, statuses[latest-1].Applied)
+
+	assert.Error(t, checkSchema(repository, false), "outdated schema must be refused")
+	assert.NoError(t, checkSchema(repository, true), "outdated schema must be migrated")
+
+	count, err = repository.MigrateDown(latest + 1)
+	require.NoError(t, err)
+	assert.Equal(t, latest, count)
+
+	_, err = repository.LoadActive()
+	assert.Error(t, err, "satellites table must be dropped")
+}
+
+func TestMigrateLegacyTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sat-parser")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "test.db")
+
+	// the table as sat-parser created it before migrations, satellites were identified by names
+	db, err := sqlx.Open(sqliteDialect.driver, path)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE "satellites" (_name TEXT NOT NULL, _position REAL NOT NULL, _url TEXT NOT NULL, ` +
+		"_band TEXT NOT NULL, _tags TEXT NOT NULL, _status INTEGER NOT NULL DEFAULT 1, _closed TIMESTAMP NULL)")
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO "satellites" (_name, _position, _url, _band, _tags, _status, _closed) VALUES ` +
+		"('one', 1, '', '', '', 1, NULL), ('gone', 2, '', '', '', 0, '2020-01-01 00:00:00'), " +
+		"('three', 3, '', '', '', 1, NULL)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	repository, err := openSQLRepository(sqliteDialect, &DatabaseProperties{URL: path, Table: "satellites"})
+	require.NoError(t, err)
+	defer func() { _ = repository.db.Close() }()
+	assert.True(t, repository.hasLegacyTable())
+	require.NoError(t, checkSchema(repository, true))
+	assert.False(t, repository.hasLegacyTable())
+
+	all, err := repository.LoadAll()
+	require.NoError(t, err)
+	var ids []string
+	for _, sat := range all {
+		ids = append(ids, fmt.Sprintf("%d %s %d", sat.ID, sat.GetName(), sat.Status))
+	}
+	assert.Equal(t, []string{"1 one 1", "2 gone 0", "3 three 1"}, ids, "ids must be numbered in order of rows")
+
+	versions, err := repository.LoadAsOf(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, versions, 2, "versions of active satellites must be opened")
+
+	moved := makeSat("three", 3)
+	moved.SetBand("Ku")
+	status := syncTestRepository(t, repository, []Satellite{makeSat("one", 1), moved}, true, 100)
+	assert.Equal(t, SyncStatus{Updated: 1}, *status)
+	records, err := repository.LoadHistory("three")
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
+func TestCheckSchemaRefusesLegacyTableOfAppliedMigrations(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	_, err := repository.db.Exec(`DROP TABLE "satellites"`)
+	require.NoError(t, err)
+	_, err = repository.db.Exec(`CREATE TABLE "satellites" (_name TEXT NOT NULL, _position REAL NOT NULL, ` +
+		"_url TEXT NOT NULL, _band TEXT NOT NULL, _tags TEXT NOT NULL, _status INTEGER NOT NULL DEFAULT 1, " +
+		"_closed TIMESTAMP NULL)")
+	require.NoError(t, err)
+
+	assert.EqualError(t, checkSchema(repository, true), "table satellites has no _id column, it was created "+
+		"before migrations and not upgraded by them. Restore the table from a backup made before the upgrade and "+
+		"run 'sat-parser migrate up'")
+}
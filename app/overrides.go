@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"github.com/artemkaxboy/configuration"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"time"
+)
+
+const overridesPath = "overrides"
+
+// Override pins field values of the satellite with given source name, sync never overwrites pinned values with
+// parsed ones. Nil fields are not pinned, nil Expires means the override never expires.
+type Override struct {
+	SourceName string
+	Name       *string
+	Position   *float64
+	URL        *string
+	Band       *string
+	Expires    *time.Time
+}
+
+var (
+	overridesPtr *[]Override
+)
+
+// getOverrides loads overrides from the properties file if needed and returns them.
+func getOverrides() []Override {
+	if overridesPtr == nil {
+		overrides, err := loadOverrides(getConfig())
+		if err != nil {
+			log.WithError(err).Fatal("cannot load overrides")
+		}
+		overridesPtr = &overrides
+	}
+	return *overridesPtr
+}
+
+// loadOverrides reads overrides object, every key of the object is a satellite name as it is parsed from source
+// and its value holds pinned fields.
+func loadOverrides(config *configuration.Config) ([]Override, error) {
+	names, configs := getObjectConfigs(config, overridesPath)
+	var overrides []Override
+	for i, name := range names {
+		override, err := loadOverride(name, configs[i])
+		if err != nil {
+			return nil, fmt.Errorf("wrong override of %s: %w", name, err)
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides, nil
+}
+
+// loadOverride reads pinned fields of one satellite. Values are checked and normalized by satellite setters the
+// same way as parsed ones, e.g. relative URL gets the base URL.
+func loadOverride(name string, config *configuration.Config) (Override, error) {
+	override := Override{SourceName: name}
+	if !config.Root().IsObject() {
+		return override, fmt.Errorf("override must be an object of pinned fields")
+	}
+
+	var pinned Satellite
+	for _, key := range config.Root().GetObject().GetKeys() {
+		var err error
+		switch key {
+		case "name":
+			if err = pinned.SetName(config.GetString(key)); err == nil {
+				override.Name = &pinned.Name
+			}
+		case fieldPosition:
+			var position *float64
+			if position, err = getFloat(config, key); err == nil {
+				pinned.SetPosition(*position)
+				override.Position = &pinned.Position
+			}
+		case fieldURL:
+			if err = pinned.SetURL(config.GetString(key)); err == nil {
+				override.URL = &pinned.URL
+			}
+		case fieldBand:
+			pinned.SetBand(config.GetString(key))
+			override.Band = &pinned.Band
+		case "expires":
+			var expires time.Time
+			if expires, err = ParseMoment(config.GetString(key)); err == nil {
+				override.Expires = &expires
+			}
+		default:
+			err = fmt.Errorf("unknown field, available fields: name, position, url, band, expires")
+		}
+		if err != nil {
+			return override, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return override, nil
+}
+
+// Expired returns true if the override expired at the moment.
+func (ptr *Override) Expired(moment time.Time) bool {
+	return ptr.Expires != nil && !moment.Before(*ptr.Expires)
+}
+
+// apply sets pinned values to the satellite with its setters and returns descriptions of parsed values which
+// diverge from pinned ones and of pinned values refused by setters.
+func (ptr *Override) apply(sat *Satellite) []string {
+	var divergences []string
+	diverge := func(field, parsed, pinned string) {
+		if parsed != pinned {
+			divergences = append(divergences, fmt.Sprintf("%s: source %s %q diverges from override %q",
+				ptr.SourceName, field, parsed, pinned))
+		}
+	}
+	refuse := func(field, pinned string, err error) {
+		divergences = append(divergences, fmt.Sprintf("%s: override %s %q is not applied: %v", ptr.SourceName,
+			field, pinned, err))
+	}
+
+	// source name is the key of the override, so it always differs from the pinned one
+	if ptr.Name != nil {
+		if err := sat.SetName(*ptr.Name); err != nil {
+			refuse("name", *ptr.Name, err)
+		}
+	}
+	if ptr.Position != nil {
+		diverge(fieldPosition, formatPosition(sat.GetPosition()), formatPosition(*ptr.Position))
+		sat.SetPosition(*ptr.Position)
+	}
+	if ptr.URL != nil {
+		diverge(fieldURL, sat.GetURL(), *ptr.URL)
+		if err := sat.SetURL(*ptr.URL); err != nil {
+			refuse(fieldURL, *ptr.URL, err)
+		}
+	}
+	if ptr.Band != nil {
+		diverge(fieldBand, sat.GetBand(), *ptr.Band)
+		sat.SetBand(*ptr.Band)
+	}
+	return divergences
+}
+
+// ApplyOverrides sets pinned values to parsed satellites and returns reports about parsed values diverging from
+// pinned ones, expired overrides and overrides of satellites missing in source. Expired overrides are ignored.
+func ApplyOverrides(list *[]Satellite, overrides []Override, moment time.Time) []string {
+	bySourceName := make(map[string]int, len(*list))
+	for i := range *list {
+		bySourceName[(*list)[i].GetName()] = i
+	}
+
+	var reports []string
+	for _, override := range overrides {
+		if override.Expired(moment) {
+			reports = append(reports, fmt.Sprintf("%s: override expired at %s and is ignored, remove it",
+				override.SourceName, formatMoment(*override.Expires)))
+			continue
+		}
+
+		i, found := bySourceName[override.SourceName]
+		if !found {
+			reports = append(reports, fmt.Sprintf("%s: satellite is not found in source, override is not applied",
+				override.SourceName))
+			continue
+		}
+		reports = append(reports, override.apply(&(*list)[i])...)
+	}
+
+	sort.Sort(ByPosName(*list))
+	return reports
+}
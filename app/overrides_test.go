@@ -0,0 +1,98 @@
+package main
+
+import (
+	"github.com/artemkaxboy/configuration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const testOverrides = `overrides {
+  "Hotbird 13.0E" {
+    name: "Hot Bird 13E"
+    band: "Ku"
+  }
+  "ABS 7" {
+    position: 116.1
+    expires: "2020-03-01"
+  }
+  "Missing" {
+    band: "C"
+  }
+}`
+
+func TestLoadOverrides(t *testing.T) {
+	overrides, err := loadOverrides(configuration.ParseString(testOverrides))
+	require.NoError(t, err)
+	require.Len(t, overrides, 3)
+
+	assert.Equal(t, "Hotbird 13.0E", overrides[0].SourceName, "keys with dots must be kept")
+	assert.Equal(t, "Hot Bird 13E", *overrides[0].Name)
+	assert.Nil(t, overrides[0].Position)
+	assert.Equal(t, 116.1, *overrides[1].Position)
+	assert.Equal(t, "2020-03-01 00:00:00", formatMoment(*overrides[1].Expires))
+
+	_, err = loadOverrides(configuration.ParseString(`overrides { one { bnad: Ku } }`))
+	assert.Error(t, err, "unknown fields must be refused")
+}
+
+func TestApplyOverrides(t *testing.T) {
+	overrides, err := loadOverrides(configuration.ParseString(testOverrides))
+	require.NoError(t, err)
+
+	hotbird := makeSat("Hotbird 13.0E", 13)
+	hotbird.SetBand("Ka")
+	list := []Satellite{makeSat("ABS 7", 116), hotbird}
+
+	reports := ApplyOverrides(&list, overrides, time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC))
+
+	expected := makeSat("Hot Bird 13E", 13)
+	expected.SetBand("Ku")
+	assert.Equal(t, []Satellite{expected, makeSat("ABS 7", 116.1)}, list)
+	assert.Equal(t, []string{
+		`Hotbird 13.0E: source band "Ka" diverges from override "Ku"`,
+		`ABS 7: source position "116" diverges from override "116.1"`,
+		"Missing: satellite is not found in source, override is not applied",
+	}, reports)
+}
+
+func TestExpiredOverrideIgnored(t *testing.T) {
+	overrides, err := loadOverrides(configuration.ParseString(testOverrides))
+	require.NoError(t, err)
+
+	list := []Satellite{makeSat("ABS 7", 116)}
+	reports := ApplyOverrides(&list, overrides[1:2], time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, 116.0, list[0].GetPosition())
+	assert.Equal(t, []string{"ABS 7: override expired at 2020-03-01 00:00:00 and is ignored, remove it"}, reports)
+}
+
+func TestLoadOverridesValidatesValues(t *testing.T) {
+	overrides, err := loadOverrides(configuration.ParseString(`overrides {
+  one { name: " One (incl. 0.6°) ", url: "one.html", band: " Ku " }
+}`))
+	require.NoError(t, err)
+	require.Len(t, overrides, 1)
+	assert.Equal(t, "One", *overrides[0].Name, "name must be cleaned like a parsed one")
+	assert.Equal(t, baseURL+"one.html", *overrides[0].URL, "relative url must get base url")
+	assert.Equal(t, "Ku", *overrides[0].Band)
+
+	_, err = loadOverrides(configuration.ParseString(`overrides { one { name: " " } }`))
+	assert.Error(t, err, "empty name must be refused")
+	_, err = loadOverrides(configuration.ParseString(`overrides { one { url: "https://example.com/one.html" } }`))
+	assert.Error(t, err, "url which does not match satellite url pattern must be refused")
+}
+
+func TestApplyOverrideRefusedBySetters(t *testing.T) {
+	url, empty := "ftp://example.com/one", ""
+	list := []Satellite{makeSat("one", 1)}
+	reports := ApplyOverrides(&list, []Override{{SourceName: "one", Name: &empty, URL: &url}}, time.Now())
+
+	assert.Equal(t, []Satellite{makeSat("one", 1)}, list, "refused values must not be set")
+	if assert.Len(t, reports, 3) {
+		assert.Contains(t, reports[0], `one: override name "" is not applied`)
+		assert.Equal(t, `one: source url "" diverges from override "ftp://example.com/one"`, reports[1])
+		assert.Contains(t, reports[2], `one: override url "ftp://example.com/one" is not applied`)
+	}
+}
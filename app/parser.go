@@ -31,8 +31,9 @@ func containsVerdana(_ int, selection *goquery.Selection) bool {
 }
 
 // Parse extracts satellite items from given reader and sends them to given chData channel.
-// Occurred errors are sent to chErr channel. url string is used for tracing purposes only.
-func Parse(url string, reader io.Reader, chData chan Satellite, chErr chan error) {
+// Occurred errors are sent to chErr channel. url string is used for tracing purposes and as a source
+// of parsed satellites. Returns count of successfully parsed rows and count of all found rows.
+func Parse(url string, reader io.Reader, chData chan Satellite, chErr chan error) (parsed int, rows int) {
 	log.Infof("parsing started: %s", url)
 
 	document, err := goquery.NewDocumentFromReader(reader)
@@ -40,10 +41,11 @@ func Parse(url string, reader io.Reader, chData chan Satellite, chErr chan error
 		err = fmt.Errorf("error reading HTTP response body: %w", err)
 		log.Error(err)
 		chErr <- err
-		return
+		return 0, 0
 	}
 
 	satellite := Satellite{}
+	satellite.SetSource(url)
 	gotPosition := false
 	doneCounter := 0
 
@@ -106,6 +108,8 @@ func Parse(url string, reader io.Reader, chData chan Satellite, chErr chan error
 				return
 			}
 
+			satellite.SetInclination(nameTd.Text())
+
 			a := nameTd.Find("a")
 			url, exists := a.Attr("href")
 			if !exists {
@@ -128,22 +132,26 @@ func Parse(url string, reader io.Reader, chData chan Satellite, chErr chan error
 			doneCounter++
 		}).Length()
 	log.Infof("parsing finished: %s. %d out of %d satellites processed", url, doneCounter, allCounter)
+	return doneCounter, allCounter
 }
 
-func getResponse(url string) (*http.Response, error) {
+// getResponse loads the page. Status code is returned with error if the page cannot be got, the response body
+// is closed then.
+func getResponse(url string) (*http.Response, int, error) {
 	log.Printf("loading content of %s ...", url)
 	resp, err := http.Get(url)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	log.Printf("got response from %s", url)
 
 	if resp.StatusCode != 200 {
+		_ = resp.Body.Close()
 		err = fmt.Errorf("cannot get document (%s): status code is %d", url, resp.StatusCode)
-		return nil, err
+		return nil, resp.StatusCode, err
 	}
 
-	return resp, nil
+	return resp, resp.StatusCode, nil
 }
 
 func closeReader(response *http.Response) error {
@@ -160,16 +168,18 @@ func getUtf8Reader(response *http.Response) (io.Reader, error) {
 	return reader, nil
 }
 
-// parseOnline runs pages parsing in goroutines, compiles, sorts and returns satellites array.
-func parseOnline() ([]Satellite, []error) {
-	ch, chErr, chQuit := make(chan Satellite), make(chan error), make(chan int)
+// parseOnline runs pages parsing in goroutines, compiles, sorts and returns satellites array with stats of
+// loaded pages.
+func parseOnline() ([]Satellite, []PageStats, []error) {
+	ch, chErr, chPage, chQuit := make(chan Satellite), make(chan error), make(chan PageStats), make(chan int)
 	ongoing := 0
 
 	for _, url := range sourceUrls {
-		go parseOnlinePage(url, ch, chErr, chQuit)
+		go parseOnlinePage(url, ch, chErr, chPage, chQuit)
 	}
 
 	var satellites []Satellite
+	var pages []PageStats
 	var errorz []error
 WaiterLoop:
 	for {
@@ -178,6 +188,8 @@ WaiterLoop:
 			satellites = append(satellites, receivedSat)
 		case receivedErr := <-chErr:
 			errorz = append(errorz, receivedErr)
+		case receivedPage := <-chPage:
+			pages = append(pages, receivedPage)
 		case count := <-chQuit:
 			ongoing += count
 			if ongoing == 0 {
@@ -187,25 +199,39 @@ WaiterLoop:
 	}
 	close(ch)
 	close(chErr)
+	close(chPage)
 	close(chQuit)
 
 	sort.Sort(ByPosName(satellites))
+	sort.Slice(pages, func(i, j int) bool { return pages[i].URL < pages[j].URL })
 
-	return satellites, errorz
+	return satellites, pages, errorz
 }
 
-func parseOnlinePage(url string, chData chan Satellite, chErr chan error, chCounter chan int) {
+func parseOnlinePage(url string, chData chan Satellite, chErr chan error, chPage chan PageStats,
+	chCounter chan int) {
+
 	chCounter <- 1
 	defer func() {
 		chCounter <- -1
 	}()
 
-	httpResponse, err := getResponse(url)
+	stats := PageStats{URL: url}
+	defer func() {
+		chPage <- stats
+	}()
+
+	httpResponse, status, err := getResponse(url)
+	stats.Status = status
 	if err != nil {
+		stats.Error = err.Error()
 		chErr <- err
 		return
 	}
+	body := &countingReadCloser{ReadCloser: httpResponse.Body}
+	httpResponse.Body = body
 	defer func() {
+		stats.Bytes = body.count
 		if err := closeReader(httpResponse); err != nil {
 			chErr <- err
 		}
@@ -213,9 +239,10 @@ func parseOnlinePage(url string, chData chan Satellite, chErr chan error, chCoun
 
 	reader, err := getUtf8Reader(httpResponse)
 	if err != nil {
+		stats.Error = err.Error()
 		chErr <- err
 		return
 	}
 
-	Parse(url, reader, chData, chErr)
+	stats.Parsed, stats.Rows = Parse(url, reader, chData, chErr)
 }
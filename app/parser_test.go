@@ -147,6 +147,7 @@ func TestIncl(t *testing.T) {
 
 	assert.Equal(t, "ABS 7", satellites[0].GetName())
 	assert.Equal(t, getProperties().Parser.BaseURL+"ABS-7.html", satellites[0].GetURL())
+	assert.Equal(t, 0.6, satellites[0].GetInclination())
 }
 
 func TestLackOfPosition(t *testing.T) {
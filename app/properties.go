@@ -1,40 +1,138 @@
 package main
 
 import (
+	"github.com/artemkaxboy/configuration"
+	hoconConfig "github.com/artemkaxboy/configuration/hocon"
 	"github.com/artemkaxboy/go-hocon"
 	log "github.com/sirupsen/logrus"
 )
 
 // Properties struct is used for loading and providing access to configuration file.
 type Properties struct {
-	Mysql struct {
-		URL   string `hocon:"node=url"`
-		Table string `hocon:"node=table,default=satellites"`
-	} `hocon:"node=mysql"`
+	Storage struct {
+		Driver      string `hocon:"node=driver,default=mysql"`
+		AutoMigrate bool   `hocon:"node=autoMigrate,default=false"`
+	} `hocon:"node=storage"`
+
+	Mysql    DatabaseProperties `hocon:"node=mysql"`
+	Postgres DatabaseProperties `hocon:"node=postgres"`
+	Sqlite   DatabaseProperties `hocon:"node=sqlite"`
+	File     FileProperties     `hocon:"node=file"`
 
 	Parser struct {
 		BaseURL             string   `hocon:"node=baseUrl"`
 		SatelliteURLPattern string   `hocon:"node=satelliteUrlPattern"`
 		URLs                []string `hocon:"node=urls"`
+		TransponderWorkers  int64    `hocon:"node=transponderWorkers,default=4"`
 	} `hocon:"node=parser"`
 
+	Sync struct {
+		Atomic    bool  `hocon:"node=atomic,default=true"`
+		BatchSize int64 `hocon:"node=batchSize,default=100"`
+	} `hocon:"node=sync"`
+
+	Comparison Comparator `hocon:"node=comparison"`
+
+	Relocation struct {
+		Tolerance float64 `hocon:"node=tolerance,default=0.5"`
+	} `hocon:"node=relocation"`
+
+	Slots struct {
+		Tolerance   float64 `hocon:"node=tolerance,default=0.3"`
+		InclinedTag string  `hocon:"node=inclinedTag,default=inclined"`
+	} `hocon:"node=slots"`
+
+	Satcat struct {
+		MinConfidence float64 `hocon:"node=minConfidence,default=0.7"`
+		Margin        float64 `hocon:"node=margin,default=0.1"`
+		Tolerance     float64 `hocon:"node=tolerance,default=0.5"`
+	} `hocon:"node=satcat"`
+
+	Guard struct {
+		Run  ChangeLimits `hocon:"node=run"`
+		Page ChangeLimits `hocon:"node=page"`
+	} `hocon:"node=guard"`
+
+	Observer Observer `hocon:"node=observer"`
+
 	LogLevel string `hocon:"node=logLevel"`
 }
 
+// DatabaseProperties holds connection settings of SQL storage. Empty table means default satellites table.
+// Zero pool limits and timeouts mean no limit.
+type DatabaseProperties struct {
+	URL   string `hocon:"node=url,default="`
+	Table string `hocon:"node=table,default=satellites"`
+
+	MaxOpenConns           int64 `hocon:"node=maxOpenConns,default=10"`
+	MaxIdleConns           int64 `hocon:"node=maxIdleConns,default=2"`
+	ConnMaxLifetimeSeconds int64 `hocon:"node=connMaxLifetimeSeconds,default=300"`
+	QueryTimeoutSeconds    int64 `hocon:"node=queryTimeoutSeconds,default=30"`
+
+	// ConnectAttempts is a count of pings at startup, the delay between them starts with ConnectBackoffSeconds
+	// and doubles after every failed attempt.
+	ConnectAttempts       int64 `hocon:"node=connectAttempts,default=5"`
+	ConnectBackoffSeconds int64 `hocon:"node=connectBackoffSeconds,default=1"`
+}
+
+// FileProperties holds settings of file storage, format is chosen by extension of the path: .json or .csv.
+type FileProperties struct {
+	Path string `hocon:"node=path,default=satellites.json"`
+}
+
+// ChangeLimits holds maximum allowed counts of changes, absolute and in percents. Zero value means no limit.
+type ChangeLimits struct {
+	MaxInserts        int64   `hocon:"node=maxInserts,default=0"`
+	MaxInsertsPercent float64 `hocon:"node=maxInsertsPercent,default=0"`
+	MaxCloses         int64   `hocon:"node=maxCloses,default=0"`
+	MaxClosesPercent  float64 `hocon:"node=maxClosesPercent,default=0"`
+	MaxUpdates        int64   `hocon:"node=maxUpdates,default=0"`
+	MaxUpdatesPercent float64 `hocon:"node=maxUpdatesPercent,default=0"`
+}
+
 var (
-	props *Properties
+	props          *Properties
+	propertiesFile = "sat-parser.conf"
+	rawConfig      *configuration.Config
 )
 
 // getProperties loads configuration from file to Properties struct if needed and gives pointer to it
 func getProperties() *Properties {
 	if props == nil {
 		props = &Properties{}
-		if err := hocon.LoadConfigFile("sat-parser.conf", props); err != nil {
+		if err := hocon.LoadConfigFile(propertiesFile, props); err != nil {
 			log.WithError(err).Error("cannot load properties, falling back to example values")
-			if err := hocon.LoadConfigFile("sat-parser.conf.example", props); err != nil {
+			propertiesFile = "sat-parser.conf.example"
+			if err := hocon.LoadConfigFile(propertiesFile, props); err != nil {
 				log.WithError(err).Fatal("cannot load default properties")
 			}
 		}
 	}
 	return props
 }
+
+// getConfig parses the loaded properties file if needed and returns it. It is used for settings which go-hocon
+// cannot load, e.g. objects with arbitrary keys.
+func getConfig() *configuration.Config {
+	if rawConfig == nil {
+		getProperties()
+		rawConfig = configuration.LoadConfig(propertiesFile)
+	}
+	return rawConfig
+}
+
+// getObjectConfigs returns keys of the object at path and configs of their values in order of the file. Keys are
+// taken as is, so they may contain dots. Nothing is returned if there is no object at path.
+func getObjectConfigs(config *configuration.Config, path string) ([]string, []*configuration.Config) {
+	if !config.IsObject(path) {
+		return nil, nil
+	}
+
+	object := config.GetConfig(path).Root().GetObject()
+	keys := object.GetKeys()
+	configs := make([]*configuration.Config, 0, len(keys))
+	for _, key := range keys {
+		configs = append(configs, configuration.NewConfigFromRoot(hoconConfig.NewHoconRoot(object.GetKey(key))))
+	}
+	return keys, configs
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	driftEast = "east"
+	driftWest = "west"
+)
+
+// Relocation is a struct to hold a move of a satellite to another orbital position.
+type Relocation struct {
+	SatelliteID  int64   `db:"_satellite_id"`
+	Name         string  `db:"_name"`
+	FromPosition float64 `db:"_from_position"`
+	ToPosition   float64 `db:"_to_position"`
+	Direction    string  `db:"_direction"`
+	RunID        string  `db:"_run_id"`
+	Detected     string  `db:"_detected"`
+}
+
+// Distance returns absolute angular distance between from and to positions in degrees.
+func (ptr *Relocation) Distance() float64 {
+	return math.Abs(positionDelta(ptr.FromPosition, ptr.ToPosition))
+}
+
+func (ptr Relocation) String() string {
+	return fmt.Sprintf("%s moved from %s to %s (%s, %.1f°)", ptr.Name,
+		formatOrbitalPosition(ptr.FromPosition), formatOrbitalPosition(ptr.ToPosition), ptr.Direction, ptr.Distance())
+}
+
+// positionDelta returns signed shortest angular distance from one position to another, positive value means
+// eastward drift. The result is in range (-180, 180].
+func positionDelta(from, to float64) float64 {
+	delta := math.Mod(to-from, 360)
+	if delta > 180 {
+		delta -= 360
+	} else if delta <= -180 {
+		delta += 360
+	}
+	return delta
+}
+
+// FindRelocations returns relocations among changed pairs whose position moved farther than tolerance degrees.
+func FindRelocations(changed *[][]Satellite, tolerance float64) []Relocation {
+	var relocations []Relocation
+	for _, pair := range *changed {
+		oldSat, newSat := pair[0], pair[1]
+		delta := positionDelta(oldSat.GetPosition(), newSat.GetPosition())
+		if math.Abs(delta) <= tolerance {
+			continue
+		}
+
+		direction := driftEast
+		if delta < 0 {
+			direction = driftWest
+		}
+		relocations = append(relocations, Relocation{
+			SatelliteID:  oldSat.GetID(),
+			Name:         newSat.GetName(),
+			FromPosition: oldSat.GetPosition(),
+			ToPosition:   newSat.GetPosition(),
+			Direction:    direction,
+			RunID:        runID,
+		})
+	}
+	return relocations
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPositionDelta(t *testing.T) {
+	assert.InDelta(t, 6.2, positionDelta(13, 19.2), 1e-9)
+	assert.InDelta(t, -6.2, positionDelta(19.2, 13), 1e-9)
+	assert.InDelta(t, 2, positionDelta(179, -179), 1e-9, "crossing antimeridian eastward")
+	assert.InDelta(t, -2, positionDelta(-179, 179), 1e-9, "crossing antimeridian westward")
+}
+
+func TestFindRelocations(t *testing.T) {
+	moved := makeSat("moved", 13)
+	movedNew := moved
+	movedNew.SetPosition(9)
+	fixed := makeSat("fixed", 19.2)
+	fixedNew := fixed
+	fixedNew.SetPosition(19.3)
+	band := makeSat("band", 5)
+	bandNew := band
+	bandNew.SetBand("Ku")
+
+	changed := [][]Satellite{{moved, movedNew}, {fixed, fixedNew}, {band, bandNew}}
+
+	relocations := FindRelocations(&changed, 0.5)
+
+	if assert.Len(t, relocations, 1) {
+		assert.Equal(t, "moved", relocations[0].Name)
+		assert.Equal(t, 13.0, relocations[0].FromPosition)
+		assert.Equal(t, 9.0, relocations[0].ToPosition)
+		assert.Equal(t, driftWest, relocations[0].Direction)
+		assert.Equal(t, "moved moved from 13.0°E to 9.0°E (west, 4.0°)", relocations[0].String())
+	}
+}
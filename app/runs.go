@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+const (
+	runSucceeded   = "succeeded"
+	runFailed      = "failed"
+	runRolledBack  = "rolled back"
+	runRefused     = "refused"
+	runParseFailed = "parse failed"
+)
+
+// PageStats holds result of loading and parsing one page.
+type PageStats struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+	Bytes  int64  `json:"bytes"`
+	Rows   int    `json:"rows"`
+	Parsed int    `json:"parsed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SyncRun is a struct to hold log record of one sync run. Pages hold JSON list of PageStats.
+type SyncRun struct {
+	RunID       string `db:"_run_id" json:"runId"`
+	Started     string `db:"_started" json:"started"`
+	Finished    string `db:"_finished" json:"finished"`
+	Revision    string `db:"_revision" json:"revision"`
+	ConfigHash  string `db:"_config_hash" json:"configHash"`
+	Status      string `db:"_status" json:"status"`
+	Pages       string `db:"_pages" json:"pages"`
+	Parsed      int64  `db:"_parsed" json:"parsed"`
+	ParseErrors int64  `db:"_parse_errors" json:"parseErrors"`
+	Inserted    int64  `db:"_inserted" json:"inserted"`
+	Closed      int64  `db:"_closed" json:"closed"`
+	Updated     int64  `db:"_updated" json:"updated"`
+	Relocated   int64  `db:"_relocated" json:"relocated"`
+	Failed      int64  `db:"_failed" json:"failed"`
+}
+
+// NewSyncRun starts log record of the current run.
+func NewSyncRun() *SyncRun {
+	return &SyncRun{
+		RunID:      runID,
+		Started:    formatMoment(time.Now()),
+		Revision:   revision,
+		ConfigHash: configHash(),
+	}
+}
+
+// SetPages saves stats of loaded pages.
+func (ptr *SyncRun) SetPages(pages []PageStats) {
+	data, _ := json.Marshal(pages)
+	ptr.Pages = string(data)
+}
+
+// GetPages returns stats of loaded pages.
+func (ptr *SyncRun) GetPages() ([]PageStats, error) {
+	var pages []PageStats
+	if ptr.Pages == "" {
+		return pages, nil
+	}
+	err := json.Unmarshal([]byte(ptr.Pages), &pages)
+	return pages, err
+}
+
+// SetSyncStatus saves counts of applied and failed rows and status of the run.
+func (ptr *SyncRun) SetSyncStatus(status *SyncStatus) {
+	ptr.Inserted = int64(status.Inserted)
+	ptr.Closed = int64(status.Closed)
+	ptr.Updated = int64(status.Updated)
+	ptr.Relocated = int64(status.Relocated)
+	ptr.Failed = int64(status.Failed)
+
+	switch {
+	case status.RolledBack:
+		ptr.Status = runRolledBack
+	case status.Failed > 0:
+		ptr.Status = runFailed
+	default:
+		ptr.Status = runSucceeded
+	}
+}
+
+// Finish sets finishing time and status of the run if it is not set yet.
+func (ptr *SyncRun) Finish(status string) {
+	ptr.Finished = formatMoment(time.Now())
+	if ptr.Status == "" {
+		ptr.Status = status
+	}
+}
+
+// configHash returns SHA-256 of the loaded properties file to tell runs with different settings apart.
+func configHash() string {
+	getProperties()
+	data, err := ioutil.ReadFile(propertiesFile)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// countingReadCloser counts bytes read from underlying reader.
+type countingReadCloser struct {
+	io.ReadCloser
+	count int64
+}
+
+func (ptr *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := ptr.ReadCloser.Read(p)
+	ptr.count += int64(n)
+	return n, err
+}
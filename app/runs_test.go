@@ -0,0 +1,106 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testPage = `<table cellspacing=0 border>
+<tr>
+<td bgcolor="white" width=1><font size=2>&nbsp;</font></td><td width=70 rowspan=3 bgcolor=khaki align="center"><font face="Verdana"><font size=2>116.</font><font size=1>0</font><font size=2>&#176;E</font></td>
+<td width=180 bgcolor=khaki><font face="Arial"><font size=2><a href="ABS-7.html">ABS 7</a></td>
+<td width=20 bgcolor=khaki><font face="Arial"><font size=1> Ku</font></td>
+<td width=50 bgcolor=#ffffff align=center><font face="Verdana" size=1>120507</td>
+</tr>
+</table>`
+
+func TestParseOnlinePageStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/asia.html" {
+			http.NotFound(writer, request)
+			return
+		}
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = writer.Write([]byte(testPage))
+	}))
+	defer server.Close()
+
+	defer func(urls []string) { sourceUrls = urls }(sourceUrls)
+	sourceUrls = []string{server.URL + "/asia.html", server.URL + "/missing.html"}
+
+	satellites, pages, errorz := parseOnline()
+
+	assert.Len(t, satellites, 1)
+	assert.Len(t, errorz, 1)
+	assert.Equal(t, []PageStats{
+		{URL: server.URL + "/asia.html", Status: 200, Bytes: int64(len(testPage)), Rows: 1, Parsed: 1},
+		{URL: server.URL + "/missing.html", Status: 404, Error: errorz[0].Error()},
+	}, pages)
+}
+
+func TestSyncRunStatus(t *testing.T) {
+	run := NewSyncRun()
+	assert.Equal(t, runID, run.RunID)
+	assert.NotEmpty(t, run.ConfigHash)
+
+	run.SetSyncStatus(&SyncStatus{Inserted: 2, Failed: 1})
+	run.Finish(runSucceeded)
+	assert.Equal(t, runFailed, run.Status, "status of applied sync must be kept")
+	assert.Equal(t, int64(2), run.Inserted)
+
+	refused := NewSyncRun()
+	refused.Finish(runRefused)
+	assert.Equal(t, runRefused, refused.Status)
+	assert.NotEmpty(t, refused.Finished)
+}
+
+func testRunsLog(t *testing.T, repository SatelliteRepository) {
+	pages := []PageStats{{URL: "https://www.base.com/asia.html", Status: 200, Bytes: 1024, Rows: 10, Parsed: 9}}
+	first := SyncRun{RunID: "first", Started: "2020-03-01 12:00:00", Finished: "2020-03-01 12:00:10",
+		Status: runSucceeded, Revision: "abc", Inserted: 3}
+	first.SetPages(pages)
+	second := SyncRun{RunID: "second", Started: "2020-03-02 12:00:00", Finished: "2020-03-02 12:00:10",
+		Status: runRefused}
+	require.NoError(t, repository.SaveRun(&first))
+	require.NoError(t, repository.SaveRun(&second))
+
+	runs, err := repository.LoadRuns(1)
+	require.NoError(t, err)
+	if assert.Len(t, runs, 1) {
+		assert.Equal(t, "second", runs[0].RunID, "the latest run must be the first")
+	}
+
+	run, err := repository.LoadRun("first")
+	require.NoError(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, int64(3), run.Inserted)
+	assert.Equal(t, "abc", run.Revision)
+	loadedPages, err := run.GetPages()
+	require.NoError(t, err)
+	assert.Equal(t, pages, loadedPages)
+
+	run, err = repository.LoadRun("missing")
+	require.NoError(t, err)
+	assert.Nil(t, run)
+}
+
+func TestSQLRepositoryRuns(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	testRunsLog(t, repository)
+}
+
+func TestFileRepositoryRuns(t *testing.T) {
+	repository, path, cleanup := openTestFileRepository(t, "satellites.csv")
+	defer cleanup()
+
+	testRunsLog(t, repository)
+
+	reopened, err := openFileRepository(&FileProperties{Path: path})
+	require.NoError(t, err)
+	assert.Equal(t, repository.state.Runs, reopened.state.Runs)
+}
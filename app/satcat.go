@@ -0,0 +1,409 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/artemkaxboy/configuration"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+)
+
+const satcatAliasesPath = "satcat.aliases"
+
+// Confidence of a candidate is the score of the best matching pair of names corrected by its longitude or orbit.
+const (
+	satcatAliasScore    = 0.9 // configured alias equals SATCAT name
+	satcatNameScore     = 0.8 // normalised names are equal
+	satcatPartScore     = 0.6 // name equals a parenthesised part of SATCAT name, e.g. (IS-14)
+	satcatContainsScore = 0.4 // one normalised name contains the other one
+	satcatNearBonus     = 0.2 // longitude is within tolerance
+	satcatGeoBonus      = 0.1 // orbit period is geostationary
+	satcatFarPenalty    = 0.5 // longitude is farther than tolerance
+	satcatNotGeoPenalty = 0.3 // orbit period is not geostationary
+
+	// geostationary orbit period in minutes and allowed difference of SATCAT periods
+	satcatGeoPeriod          = 1436.1
+	satcatGeoPeriodTolerance = 6
+	// shorter normalised names are too common to be looked for inside other names
+	satcatMinContainedLength = 4
+)
+
+var satcatPartRegex = regexp.MustCompile(`\(([^)]*)\)`)
+
+// SatcatEntry is an object of the SATCAT file in CelesTrak format. SATCAT has no longitude, files extended with
+// LONGITUDE column, e.g. from GP data, give it to matching. Nil Period and Longitude mean unknown values.
+type SatcatEntry struct {
+	Name       string
+	CosparID   string
+	NoradID    int64
+	ObjectType string
+	Decay      string
+	Period     *float64
+	Longitude  *float64
+}
+
+// SatelliteIdentifier links satellites with given name to a SATCAT object. Name is the identity of satellites like
+// for annotations, Confidence is between 0 and 1.
+type SatelliteIdentifier struct {
+	Name       string  `db:"_name" json:"name"`
+	NoradID    int64   `db:"_norad_id" json:"noradId"`
+	CosparID   string  `db:"_cospar_id" json:"cosparId"`
+	SatcatName string  `db:"_satcat_name" json:"satcatName"`
+	Confidence float64 `db:"_confidence" json:"confidence"`
+	Matched    string  `db:"_matched" json:"matched"`
+}
+
+// SatcatCandidate is a SATCAT object which may be the satellite.
+type SatcatCandidate struct {
+	Entry      SatcatEntry
+	Confidence float64
+}
+
+// SatcatAmbiguity is a satellite which needs manual review, candidates are ordered by confidence.
+type SatcatAmbiguity struct {
+	Satellite  Satellite
+	Reason     string
+	Candidates []SatcatCandidate
+}
+
+// SatcatResult holds accepted matches, ambiguous cases and satellites without any candidate.
+type SatcatResult struct {
+	Matched   []SatelliteIdentifier
+	Ambiguous []SatcatAmbiguity
+	Unmatched []Satellite
+}
+
+// SatcatMatcher links satellites to SATCAT objects. Aliases are additional names of satellites by their names.
+// The best candidate is accepted if its confidence is not less than MinConfidence and exceeds confidence of
+// the next one by Margin at least. Tolerance is max distance in degrees between position and longitude.
+type SatcatMatcher struct {
+	Aliases       map[string][]string
+	MinConfidence float64
+	Margin        float64
+	Tolerance     float64
+}
+
+// satcatColumns are SATCAT columns used by matching, the first three are required.
+var satcatColumns = []string{"OBJECT_NAME", "OBJECT_ID", "NORAD_CAT_ID", "OBJECT_TYPE", "DECAY_DATE", "PERIOD",
+	"LONGITUDE"}
+
+// ReadSatcat reads SATCAT CSV content, columns are found by the header so their order does not matter.
+func ReadSatcat(reader io.Reader) ([]SatcatEntry, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	indexes := make(map[string]int, len(header))
+	for i, column := range header {
+		indexes[strings.ToUpper(strings.TrimSpace(column))] = i
+	}
+	for _, column := range satcatColumns[:3] {
+		if _, found := indexes[column]; !found {
+			return nil, fmt.Errorf("column %s not found in SATCAT header %v", column, header)
+		}
+	}
+
+	var entries []SatcatEntry
+	for line := 2; ; line++ {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		value := func(column string) string {
+			if i, found := indexes[column]; found && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		entry := SatcatEntry{Name: value("OBJECT_NAME"), CosparID: value("OBJECT_ID"),
+			ObjectType: value("OBJECT_TYPE"), Decay: value("DECAY_DATE")}
+		if entry.NoradID, err = strconv.ParseInt(value("NORAD_CAT_ID"), 10, 64); err != nil {
+			return nil, fmt.Errorf("wrong NORAD_CAT_ID at line %d: %w", line, err)
+		}
+		for _, field := range []struct {
+			column string
+			target **float64
+		}{{"PERIOD", &entry.Period}, {"LONGITUDE", &entry.Longitude}} {
+			if text := value(field.column); text != "" {
+				number, err := strconv.ParseFloat(text, 64)
+				if err != nil {
+					return nil, fmt.Errorf("wrong %s at line %d: %w", field.column, line, err)
+				}
+				*field.target = &number
+			}
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// normalizeSatName returns upper case letters and digits of the name, so "Hot Bird 13E" and "HOTBIRD-13E" match.
+func normalizeSatName(name string) string {
+	var builder strings.Builder
+	for _, char := range strings.ToUpper(name) {
+		if unicode.IsLetter(char) || unicode.IsDigit(char) {
+			builder.WriteRune(char)
+		}
+	}
+	return builder.String()
+}
+
+// splitSatcatName returns normalised SATCAT name without parenthesised parts and normalised parts themselves.
+func splitSatcatName(name string) (string, []string) {
+	var parts []string
+	for _, matches := range satcatPartRegex.FindAllStringSubmatch(name, -1) {
+		if part := normalizeSatName(matches[1]); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return normalizeSatName(satcatPartRegex.ReplaceAllString(name, "")), parts
+}
+
+// isActivePayload returns true if the object is a payload which has not decayed, unknown type is accepted.
+func (ptr *SatcatEntry) isActivePayload() bool {
+	return ptr.Decay == "" && (ptr.ObjectType == "" || ptr.ObjectType == "PAY")
+}
+
+// nameScore returns score of the best matching pair of satellite names and the SATCAT name, zero if no names
+// match. The first satellite name is its own one, others are aliases.
+func nameScore(names []string, satcatName string, parts []string) float64 {
+	var best float64
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		score := 0.0
+		switch {
+		case name == satcatName && i > 0:
+			score = satcatAliasScore
+		case name == satcatName:
+			score = satcatNameScore
+		case containsString(parts, name):
+			score = satcatPartScore
+		case len(name) >= satcatMinContainedLength && len(satcatName) >= satcatMinContainedLength &&
+			(strings.Contains(satcatName, name) || strings.Contains(name, satcatName)):
+			score = satcatContainsScore
+		}
+		best = math.Max(best, score)
+	}
+	return best
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// confidence returns confidence of the entry being the satellite, zero if names do not match.
+func (ptr *SatcatMatcher) confidence(sat *Satellite, names []string, entry *SatcatEntry) float64 {
+	satcatName, parts := splitSatcatName(entry.Name)
+	score := nameScore(names, satcatName, parts)
+	if score == 0 {
+		return 0
+	}
+
+	switch {
+	case entry.Longitude != nil:
+		if math.Abs(math.Remainder(sat.GetPosition()-*entry.Longitude, 360)) <= ptr.Tolerance {
+			score += satcatNearBonus
+		} else {
+			score -= satcatFarPenalty
+		}
+	case entry.Period != nil:
+		if math.Abs(*entry.Period-satcatGeoPeriod) <= satcatGeoPeriodTolerance {
+			score += satcatGeoBonus
+		} else {
+			score -= satcatNotGeoPenalty
+		}
+	}
+	return math.Round(math.Max(0, math.Min(1, score))*100) / 100
+}
+
+// candidates returns SATCAT objects which may be the satellite ordered by confidence and NORAD id.
+func (ptr *SatcatMatcher) candidates(sat *Satellite, entries []SatcatEntry) []SatcatCandidate {
+	names := []string{normalizeSatName(sat.GetName())}
+	for _, alias := range ptr.Aliases[sat.GetName()] {
+		names = append(names, normalizeSatName(alias))
+	}
+
+	var candidates []SatcatCandidate
+	for i := range entries {
+		if !entries[i].isActivePayload() {
+			continue
+		}
+		if confidence := ptr.confidence(sat, names, &entries[i]); confidence > 0 {
+			candidates = append(candidates, SatcatCandidate{Entry: entries[i], Confidence: confidence})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Confidence != candidates[j].Confidence {
+			return candidates[i].Confidence > candidates[j].Confidence
+		}
+		return candidates[i].Entry.NoradID < candidates[j].Entry.NoradID
+	})
+	return candidates
+}
+
+// Match links satellites of the list to SATCAT entries. A SATCAT object accepted for several satellites stays
+// with the most confident one only if it is ahead by margin, other satellites are sent to review.
+func (ptr *SatcatMatcher) Match(list []Satellite, entries []SatcatEntry, moment time.Time) *SatcatResult {
+	result := &SatcatResult{}
+	var accepted []SatcatAmbiguity
+	for _, sat := range list {
+		candidates := ptr.candidates(&sat, entries)
+		switch {
+		case len(candidates) == 0:
+			result.Unmatched = append(result.Unmatched, sat)
+		case candidates[0].Confidence < ptr.MinConfidence:
+			result.Ambiguous = append(result.Ambiguous, SatcatAmbiguity{Satellite: sat, Candidates: candidates,
+				Reason: fmt.Sprintf("confidence is below %s", formatPosition(ptr.MinConfidence))})
+		case len(candidates) > 1 && candidates[0].Confidence-candidates[1].Confidence < ptr.Margin:
+			result.Ambiguous = append(result.Ambiguous, SatcatAmbiguity{Satellite: sat, Candidates: candidates,
+				Reason: fmt.Sprintf("%d candidates with close confidence", len(candidates))})
+		default:
+			accepted = append(accepted, SatcatAmbiguity{Satellite: sat, Candidates: candidates})
+		}
+	}
+
+	byNorad := make(map[int64][]int)
+	for i := range accepted {
+		noradID := accepted[i].Candidates[0].Entry.NoradID
+		byNorad[noradID] = append(byNorad[noradID], i)
+	}
+	matched := formatMoment(moment)
+	for i, match := range accepted {
+		best := match.Candidates[0]
+		claims := byNorad[best.Entry.NoradID]
+		winner := -1
+		if len(claims) == 1 {
+			winner = i
+		} else {
+			sort.SliceStable(claims, func(a, b int) bool {
+				return accepted[claims[a]].Candidates[0].Confidence > accepted[claims[b]].Candidates[0].Confidence
+			})
+			if accepted[claims[0]].Candidates[0].Confidence-accepted[claims[1]].Candidates[0].Confidence >=
+				ptr.Margin {
+				winner = claims[0]
+			}
+		}
+
+		if winner != i {
+			match.Reason = fmt.Sprintf("NORAD %d is matched by %d satellites", best.Entry.NoradID, len(claims))
+			result.Ambiguous = append(result.Ambiguous, match)
+			continue
+		}
+		result.Matched = append(result.Matched, SatelliteIdentifier{Name: match.Satellite.GetName(),
+			NoradID: best.Entry.NoradID, CosparID: best.Entry.CosparID, SatcatName: best.Entry.Name,
+			Confidence: best.Confidence, Matched: matched})
+	}
+
+	sort.SliceStable(result.Ambiguous, func(i, j int) bool {
+		return result.Ambiguous[i].Satellite.GetName() < result.Ambiguous[j].Satellite.GetName()
+	})
+	return result
+}
+
+// loadSatcatAliases reads satcat.aliases object, every key is a satellite name and its value is an alias or a list
+// of aliases, e.g. names the satellite had before renaming.
+func loadSatcatAliases(config *configuration.Config) (map[string][]string, error) {
+	names, configs := getObjectConfigs(config, satcatAliasesPath)
+	aliases := make(map[string][]string, len(names))
+	for i, name := range names {
+		root := configs[i].Root()
+		switch {
+		case root.IsString():
+			aliases[name] = []string{root.GetString()}
+		case root.IsArray() && !root.IsObject():
+			aliases[name] = root.GetStringList()
+		default:
+			return nil, fmt.Errorf("aliases of %s must be a string or a list of strings", name)
+		}
+	}
+	return aliases, nil
+}
+
+// getSatcatMatcher returns matcher configured by satcat settings.
+func getSatcatMatcher() *SatcatMatcher {
+	aliases, err := loadSatcatAliases(getConfig())
+	if err != nil {
+		log.WithError(err).Fatal("cannot load SATCAT aliases")
+	}
+	settings := getProperties().Satcat
+	return &SatcatMatcher{Aliases: aliases, MinConfidence: settings.MinConfidence, Margin: settings.Margin,
+		Tolerance: settings.Tolerance}
+}
+
+// LoadSatcatFile reads SATCAT entries from the file.
+func LoadSatcatFile(path string) []SatcatEntry {
+	file, err := os.Open(path)
+	if err != nil {
+		log.WithError(err).Fatal("cannot open SATCAT file")
+	}
+	defer func() { _ = file.Close() }()
+
+	entries, err := ReadSatcat(file)
+	if err != nil {
+		log.WithError(err).Fatalf("cannot read SATCAT file %s", path)
+	}
+	log.Infof("%d SATCAT entries loaded from %s", len(entries), path)
+	return entries
+}
+
+// WriteSatcatResult prints accepted matches, ambiguous cases with their candidates and unmatched satellites.
+func WriteSatcatResult(writer io.Writer, result *SatcatResult) error {
+	tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tabWriter, "NAME\tNORAD\tCOSPAR\tSATCAT NAME\tCONFIDENCE")
+	for _, identifier := range result.Matched {
+		_, _ = fmt.Fprintf(tabWriter, "%s\t%d\t%s\t%s\t%.2f\n", identifier.Name, identifier.NoradID,
+			identifier.CosparID, identifier.SatcatName, identifier.Confidence)
+	}
+	if err := tabWriter.Flush(); err != nil {
+		return err
+	}
+
+	if len(result.Ambiguous) > 0 {
+		_, _ = fmt.Fprintf(writer, "\n%d ambiguous satellites need review:\n", len(result.Ambiguous))
+	}
+	for _, ambiguity := range result.Ambiguous {
+		_, _ = fmt.Fprintf(writer, "%s (%s): %s\n", ambiguity.Satellite.GetName(),
+			formatOrbitalPosition(ambiguity.Satellite.GetPosition()), ambiguity.Reason)
+		for _, candidate := range ambiguity.Candidates {
+			_, _ = fmt.Fprintf(tabWriter, "  %d\t%s\t%s\t%.2f\n", candidate.Entry.NoradID, candidate.Entry.CosparID,
+				candidate.Entry.Name, candidate.Confidence)
+		}
+		if err := tabWriter.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(result.Unmatched) > 0 {
+		names := make([]string, 0, len(result.Unmatched))
+		for _, sat := range result.Unmatched {
+			names = append(names, sat.GetName())
+		}
+		_, _ = fmt.Fprintf(writer, "\n%d satellites without candidates: %s\n", len(names), strings.Join(names, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"github.com/artemkaxboy/configuration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSatcat = `OBJECT_NAME,OBJECT_ID,NORAD_CAT_ID,OBJECT_TYPE,OPS_STATUS_CODE,OWNER,LAUNCH_DATE,LAUNCH_SITE,DECAY_DATE,PERIOD,INCLINATION,APOGEE,PERIGEE,RCS,DATA_STATUS_CODE,ORBIT_CENTER,ORBIT_TYPE
+HOTBIRD 13E,2006-007A,28946,PAY,+,EUTE,2006-03-11,FRGUI,,1436.09,0.05,35793,35780,,,EA,ORB
+ASTRA 1KR,2006-012A,29055,PAY,+,SES,2006-04-20,AFETR,,1436.11,0.04,35795,35778,,,EA,ORB
+ASTRA 1KR R/B,2006-012B,29056,R/B,D,SES,2006-04-20,AFETR,,1200.5,1.2,35000,300,,,EA,ORB
+INTELSAT 14 (IS-14),2009-064A,36097,PAY,+,ITSO,2009-11-23,AFETR,,1436.12,0.02,35790,35783,,,EA,ORB
+EUTELSAT 5 WEST B,2019-067A,44624,PAY,+,EUTE,2019-10-09,TTMTR,,1436.05,0.03,35791,35782,,,EA,ORB
+EUTELSAT 5 WEST B,2019-067B,44625,PAY,+,EUTE,2019-10-09,TTMTR,,1436.10,0.03,35791,35782,,,EA,ORB
+EXPRESS AMU1,2015-082A,41191,PAY,+,CIS,2015-12-24,TYMSC,,1436.08,0.01,35790,35783,,,EA,ORB
+OLD BIRD,1990-001A,20401,PAY,-,EUTE,1990-01-01,FRGUI,2001-01-01,,,,,,,EA,IMP
+`
+
+func testSatcatMatcher() *SatcatMatcher {
+	return &SatcatMatcher{MinConfidence: 0.7, Margin: 0.1, Tolerance: 0.5,
+		Aliases: map[string][]string{"Express AMU1": {"EXPRESS-AMU1"}}}
+}
+
+func TestReadSatcat(t *testing.T) {
+	entries, err := ReadSatcat(strings.NewReader(testSatcat))
+	require.NoError(t, err)
+	require.Len(t, entries, 8)
+
+	assert.Equal(t, "INTELSAT 14 (IS-14)", entries[3].Name)
+	assert.Equal(t, "2009-064A", entries[3].CosparID)
+	assert.Equal(t, int64(36097), entries[3].NoradID)
+	assert.Equal(t, 1436.12, *entries[3].Period)
+	assert.Nil(t, entries[3].Longitude)
+	assert.Nil(t, entries[7].Period)
+	assert.Equal(t, "2001-01-01", entries[7].Decay)
+
+	entries, err = ReadSatcat(strings.NewReader("NORAD_CAT_ID,LONGITUDE,OBJECT_NAME,OBJECT_ID\n1,13.1,A,B\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 13.1, *entries[0].Longitude, "columns must be found by header")
+
+	_, err = ReadSatcat(strings.NewReader("OBJECT_NAME,NORAD_CAT_ID\nA,1\n"))
+	assert.Error(t, err, "missing required columns must be refused")
+	_, err = ReadSatcat(strings.NewReader("OBJECT_NAME,OBJECT_ID,NORAD_CAT_ID\nA,B,one\n"))
+	assert.Error(t, err)
+}
+
+func TestNormalizeSatName(t *testing.T) {
+	assert.Equal(t, "HOTBIRD13E", normalizeSatName("Hot Bird 13E"))
+	assert.Equal(t, "HOTBIRD13E", normalizeSatName("HOTBIRD-13E"))
+
+	name, parts := splitSatcatName("INTELSAT 14 (IS-14)")
+	assert.Equal(t, "INTELSAT14", name)
+	assert.Equal(t, []string{"IS14"}, parts)
+}
+
+func TestSatcatMatch(t *testing.T) {
+	entries, err := ReadSatcat(strings.NewReader(testSatcat))
+	require.NoError(t, err)
+
+	list := testExportList()
+	list = append(list, makeSat("IS-14", -45), makeSat("Old Bird", 10), makeSat("Unknown", 1))
+	result := testSatcatMatcher().Match(list, entries, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	assert.Equal(t, []SatelliteIdentifier{
+		{Name: "Intelsat 14", NoradID: 36097, CosparID: "2009-064A", SatcatName: "INTELSAT 14 (IS-14)",
+			Confidence: 0.9, Matched: "2020-01-02 03:04:05"},
+		{Name: "Hot Bird 13E", NoradID: 28946, CosparID: "2006-007A", SatcatName: "HOTBIRD 13E",
+			Confidence: 0.9, Matched: "2020-01-02 03:04:05"},
+		{Name: "Express AMU1", NoradID: 41191, CosparID: "2015-082A", SatcatName: "EXPRESS AMU1",
+			Confidence: 1, Matched: "2020-01-02 03:04:05"},
+	}, result.Matched)
+
+	require.Len(t, result.Ambiguous, 3)
+	assert.Equal(t, "Astra 1KR & <1L>", result.Ambiguous[0].Satellite.GetName())
+	assert.Equal(t, "confidence is below 0.7", result.Ambiguous[0].Reason)
+	require.Len(t, result.Ambiguous[0].Candidates, 1, "rocket bodies must be skipped")
+	assert.Equal(t, 0.5, result.Ambiguous[0].Candidates[0].Confidence)
+
+	assert.Equal(t, "Eutelsat 5 West B", result.Ambiguous[1].Satellite.GetName())
+	assert.Equal(t, "2 candidates with close confidence", result.Ambiguous[1].Reason)
+
+	assert.Equal(t, "IS-14", result.Ambiguous[2].Satellite.GetName())
+	assert.Equal(t, "NORAD 36097 is matched by 2 satellites", result.Ambiguous[2].Reason)
+
+	names := make([]string, 0, len(result.Unmatched))
+	for _, sat := range result.Unmatched {
+		names = append(names, sat.GetName())
+	}
+	assert.Equal(t, []string{"Old Bird", "Unknown"}, names, "decayed objects must be skipped")
+}
+
+func TestSatcatMatchLongitude(t *testing.T) {
+	entries, err := ReadSatcat(strings.NewReader("OBJECT_NAME,OBJECT_ID,NORAD_CAT_ID,LONGITUDE\n" +
+		"EUTELSAT 5 WEST B,2019-067A,44624,-5.02\n" +
+		"EUTELSAT 5 WEST B,2019-067B,44625,-30.1\n"))
+	require.NoError(t, err)
+
+	result := testSatcatMatcher().Match([]Satellite{makeSat("Eutelsat 5 West B", -5)}, entries, time.Now())
+	require.Len(t, result.Matched, 1)
+	assert.Equal(t, int64(44624), result.Matched[0].NoradID)
+	assert.Equal(t, 1.0, result.Matched[0].Confidence)
+}
+
+func TestSatcatMatchSharedNorad(t *testing.T) {
+	entries, err := ReadSatcat(strings.NewReader("OBJECT_NAME,OBJECT_ID,NORAD_CAT_ID\nASTRA 1M,2008-057A,33436\n"))
+	require.NoError(t, err)
+
+	matcher := testSatcatMatcher()
+	matcher.Aliases = map[string][]string{"Astra 1M": {"ASTRA 1M"}, "Astra 1M Backup": {"ASTRA 1M"}}
+	list := []Satellite{makeSat("Astra 1M", 19.2), makeSat("Astra 1M Backup", 19.2)}
+	result := matcher.Match(list, entries, time.Now())
+	assert.Empty(t, result.Matched)
+	require.Len(t, result.Ambiguous, 2)
+	assert.Equal(t, "NORAD 33436 is matched by 2 satellites", result.Ambiguous[0].Reason)
+
+	matcher.Aliases = nil
+	matcher.MinConfidence = 0.3
+	result = matcher.Match(list, entries, time.Now())
+	require.Len(t, result.Matched, 1, "the match ahead by margin must be kept")
+	assert.Equal(t, "Astra 1M", result.Matched[0].Name)
+}
+
+func TestLoadSatcatAliases(t *testing.T) {
+	aliases, err := loadSatcatAliases(configuration.ParseString(`satcat.aliases {
+  "Hot Bird 13E": "HOTBIRD 13E"
+  "Eutelsat 5 West B": ["ATLANTIC BIRD 3", "EUTELSAT 5WB"]
+}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"Hot Bird 13E":      {"HOTBIRD 13E"},
+		"Eutelsat 5 West B": {"ATLANTIC BIRD 3", "EUTELSAT 5WB"},
+	}, aliases)
+
+	_, err = loadSatcatAliases(configuration.ParseString(`satcat.aliases { one { name: two } }`))
+	assert.Error(t, err)
+}
+
+func TestWriteSatcatResult(t *testing.T) {
+	result := &SatcatResult{
+		Matched: []SatelliteIdentifier{{Name: "Hot Bird 13E", NoradID: 28946, CosparID: "2006-007A",
+			SatcatName: "HOTBIRD 13E", Confidence: 0.9}},
+		Ambiguous: []SatcatAmbiguity{{Satellite: makeSat("Eutelsat 5 West B", -5), Reason: "2 candidates",
+			Candidates: []SatcatCandidate{
+				{Entry: SatcatEntry{Name: "EUTELSAT 5 WEST B", CosparID: "2019-067A", NoradID: 44624},
+					Confidence: 0.9},
+				{Entry: SatcatEntry{Name: "EUTELSAT 5 WEST B", CosparID: "2019-067B", NoradID: 44625},
+					Confidence: 0.9},
+			}}},
+		Unmatched: []Satellite{makeSat("Unknown", 1)},
+	}
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteSatcatResult(&buffer, result))
+	assert.Equal(t, "NAME          NORAD  COSPAR     SATCAT NAME  CONFIDENCE\n"+
+		"Hot Bird 13E  28946  2006-007A  HOTBIRD 13E  0.90\n"+
+		"\n"+
+		"1 ambiguous satellites need review:\n"+
+		"Eutelsat 5 West B (5.0°W): 2 candidates\n"+
+		"  44624  2019-067A  EUTELSAT 5 WEST B  0.90\n"+
+		"  44625  2019-067B  EUTELSAT 5 WEST B  0.90\n"+
+		"\n"+
+		"1 satellites without candidates: Unknown\n", buffer.String())
+}
+
+func testIdentifiers(t *testing.T, repository SatelliteRepository) {
+	identifiers, err := repository.LoadIdentifiers()
+	require.NoError(t, err)
+	assert.Empty(t, identifiers)
+
+	require.NoError(t, repository.SaveIdentifiers([]SatelliteIdentifier{
+		{Name: "two", NoradID: 2, CosparID: "2000-002A", SatcatName: "TWO", Confidence: 0.8,
+			Matched: "2020-01-01 00:00:00"},
+		{Name: "one", NoradID: 1, CosparID: "2000-001A", SatcatName: "ONE", Confidence: 0.7,
+			Matched: "2020-01-01 00:00:00"},
+	}))
+	require.NoError(t, repository.SaveIdentifiers([]SatelliteIdentifier{
+		{Name: "one", NoradID: 11, CosparID: "2000-011A", SatcatName: "ONE (1)", Confidence: 0.95,
+			Matched: "2020-01-02 00:00:00"},
+	}))
+
+	identifiers, err = repository.LoadIdentifiers()
+	require.NoError(t, err)
+	assert.Equal(t, []SatelliteIdentifier{
+		{Name: "one", NoradID: 11, CosparID: "2000-011A", SatcatName: "ONE (1)", Confidence: 0.95,
+			Matched: "2020-01-02 00:00:00"},
+		{Name: "two", NoradID: 2, CosparID: "2000-002A", SatcatName: "TWO", Confidence: 0.8,
+			Matched: "2020-01-01 00:00:00"},
+	}, identifiers)
+}
+
+func TestSQLRepositoryIdentifiers(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	testIdentifiers(t, repository)
+}
+
+func TestFileRepositoryIdentifiers(t *testing.T) {
+	for _, name := range []string{"satellites.json", "satellites.csv"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			repository, path, cleanup := openTestFileRepository(t, name)
+			defer cleanup()
+
+			testIdentifiers(t, repository)
+
+			reopened, err := openFileRepository(&FileProperties{Path: path})
+			require.NoError(t, err)
+			assert.Equal(t, repository.state.Identifiers, reopened.state.Identifiers)
+		})
+	}
+}
@@ -3,22 +3,33 @@ package main
 import (
 	"fmt"
 	log "github.com/sirupsen/logrus"
+	"path"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
-// Satellite is a struct to hold all information about satellites.
+// Satellite is a struct to hold all information about satellites. Tags are generated by tagging rules on every
+// sync, manual tags and annotations are set by users and never changed by sync.
 type Satellite struct {
-	Name     string  `db:"_name"`
-	URL      string  `db:"_url"`
-	Position float64 `db:"_position"`
-	Band     string  `db:"_band"`
+	ID          int64             `db:"_id"`
+	Name        string            `db:"_name"`
+	URL         string            `db:"_url"`
+	Position    float64           `db:"_position"`
+	Band        string            `db:"_band"`
+	Tags        string            `db:"_tags"`
+	ManualTags  string            `db:"_manual_tags"`
+	Source      string            `db:"_source"`
+	Inclination float64           `db:"-"`
+	Annotations map[string]string `db:"-"`
 }
 
 var (
-	satelliteNameTailRegex = regexp.MustCompile(`\W*\(.*$`)
-	satelliteURLPattern    = getProperties().Parser.SatelliteURLPattern
-	satelliteURLRegex      = regexp.MustCompile(satelliteURLPattern)
+	satelliteNameTailRegex    = regexp.MustCompile(`\W*\(.*$`)
+	satelliteInclinationRegex = regexp.MustCompile(`(?i)\(incl\.?\s*([0-9.]+)`)
+	satelliteURLPattern       = getProperties().Parser.SatelliteURLPattern
+	satelliteURLRegex         = regexp.MustCompile(satelliteURLPattern)
 
 	relativeURLRegex = regexp.MustCompile(`^[^:]*$`)
 )
@@ -95,6 +106,104 @@ func (ptr *Satellite) GetBand() string {
 	return ptr.Band
 }
 
+// GetID returns storage id of the satellite, zero for satellites which are not stored yet.
+func (ptr *Satellite) GetID() int64 {
+	return ptr.ID
+}
+
+// SetSource sets url of the page the satellite was parsed from.
+func (ptr *Satellite) SetSource(source string) {
+	ptr.Source = source
+}
+
+// GetSource returns source field as is. The value is empty for satellites loaded as of a past moment and for
+// satellites stored before sources were kept until the next sync.
+func (ptr *Satellite) GetSource() string {
+	return ptr.Source
+}
+
+// GetRegion returns name of the page the satellite was parsed from without extension, e.g. europe. The value is
+// empty if the source is unknown.
+func (ptr *Satellite) GetRegion() string {
+	if ptr.Source == "" {
+		return ""
+	}
+	base := path.Base(ptr.Source)
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// SetInclination parses orbit inclination from additional information of satellite name, e.g. (incl. 0.6°),
+// and sets it. Zero is set if the name has no inclination.
+func (ptr *Satellite) SetInclination(name string) {
+	ptr.Inclination = 0
+	if matches := satelliteInclinationRegex.FindStringSubmatch(name); matches != nil {
+		if inclination, err := strconv.ParseFloat(strings.TrimSuffix(matches[1], "."), 64); err == nil {
+			ptr.Inclination = inclination
+		}
+	}
+}
+
+// GetInclination returns inclination field as is. The value is zero for satellites loaded from database.
+func (ptr *Satellite) GetInclination() float64 {
+	return ptr.Inclination
+}
+
+// SetTags sets tags generated by tagging rules.
+func (ptr *Satellite) SetTags(tags []string) {
+	ptr.Tags = formatTags(tags)
+}
+
+// GetTags returns tags generated by tagging rules.
+func (ptr *Satellite) GetTags() []string {
+	return parseTags(ptr.Tags)
+}
+
+// SetManualTags sets tags given by users.
+func (ptr *Satellite) SetManualTags(tags []string) {
+	ptr.ManualTags = formatTags(tags)
+}
+
+// GetManualTags returns tags given by users.
+func (ptr *Satellite) GetManualTags() []string {
+	return parseTags(ptr.ManualTags)
+}
+
+// GetAllTags returns both generated and manual tags.
+func (ptr *Satellite) GetAllTags() []string {
+	return parseTags(formatTags(append(ptr.GetTags(), ptr.GetManualTags()...)))
+}
+
+// formatTags sorts tags, removes duplicates and empty ones and joins them with commas.
+func formatTags(tags []string) string {
+	set := make(map[string]bool, len(tags))
+	var unique []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !set[tag] {
+			set[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+	sort.Strings(unique)
+	return strings.Join(unique, ",")
+}
+
+// parseTags splits comma separated tags.
+func parseTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}
+
+// formatOrbitalPosition formats position in degrees with hemisphere letter, e.g. 13.0°E or 30.0°W.
+func formatOrbitalPosition(position float64) string {
+	if position < 0 {
+		return fmt.Sprintf("%.1f°W", -position)
+	}
+	return fmt.Sprintf("%.1f°E", position)
+}
+
 // ByPosName is utility type to sort Satellites array.
 type ByPosName []Satellite
 
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const (
+	scanFormatDVBv5  = "dvbv5"
+	scanFormatLegacy = "legacy"
+)
+
+var (
+	dvbv5Polarizations = map[string]string{"H": "HORIZONTAL", "V": "VERTICAL", "L": "LEFT", "R": "RIGHT"}
+	dvbv5Systems       = map[string]string{systemDVBS: "DVBS", systemDVBS2: "DVBS2"}
+	dvbv5Modulations   = map[string]string{"QPSK": "QPSK", "8PSK": "PSK/8", "16APSK": "APSK/16", "32APSK": "APSK/32"}
+
+	scanFileNameRegex = regexp.MustCompile(`[^A-Za-z0-9.+]+`)
+)
+
+// scanFileName returns name of scan table of the satellite built from its position and name, e.g.
+// 13.0E-Hot-Bird-13E. The position prefix is the same as in Enigma2 names.
+func scanFileName(sat *Satellite) string {
+	return strings.Trim(scanFileNameRegex.ReplaceAllString(enigma2Name(sat), "-"), "-")
+}
+
+// scanFEC returns FEC of the transponder, AUTO if it is unknown.
+func scanFEC(transponder *Transponder) string {
+	if transponder.FEC == "" {
+		return "AUTO"
+	}
+	return transponder.FEC
+}
+
+// WriteDVBv5Scan writes transponders of the satellite as dvbv5 initial scan table used by dvbv5-scan and
+// tvheadend.
+func WriteDVBv5Scan(writer io.Writer, sat *Satellite, transponders []Transponder) error {
+	if _, err := fmt.Fprintf(writer, "# %s\n", enigma2Name(sat)); err != nil {
+		return err
+	}
+
+	for _, transponder := range transponders {
+		polarization, ok := dvbv5Polarizations[transponder.Polarization]
+		if !ok {
+			return fmt.Errorf("wrong transponder %d of %s: unknown polarization %s", transponder.Frequency,
+				sat.GetName(), transponder.Polarization)
+		}
+		system, ok := dvbv5Systems[transponder.System]
+		if !ok {
+			return fmt.Errorf("wrong transponder %d of %s: unknown system %s", transponder.Frequency,
+				sat.GetName(), transponder.System)
+		}
+
+		lines := []string{
+			"[CHANNEL]",
+			"\tDELIVERY_SYSTEM = " + system,
+			fmt.Sprintf("\tFREQUENCY = %d", transponder.Frequency),
+			"\tPOLARIZATION = " + polarization,
+			fmt.Sprintf("\tSYMBOL_RATE = %d", transponder.SymbolRate),
+			"\tINNER_FEC = " + scanFEC(&transponder),
+		}
+		if modulation, ok := dvbv5Modulations[transponder.Modulation]; ok && transponder.System == systemDVBS2 {
+			lines = append(lines, "\tMODULATION = "+modulation)
+		}
+		lines = append(lines, "\tINVERSION = AUTO", "")
+
+		if _, err := io.WriteString(writer, "\n"+strings.Join(lines, "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteLegacyScan writes transponders of the satellite as legacy dvb-apps initial scan table, one transponder per
+// line, e.g. S 11727000 V 27500000 3/4. DVB-S2 transponders are written with S2 prefix, auto roll-off and
+// modulation.
+func WriteLegacyScan(writer io.Writer, sat *Satellite, transponders []Transponder) error {
+	if _, err := fmt.Fprintf(writer, "# %s\n# freq pol sr fec\n", enigma2Name(sat)); err != nil {
+		return err
+	}
+
+	for _, transponder := range transponders {
+		if _, ok := dvbv5Polarizations[transponder.Polarization]; !ok {
+			return fmt.Errorf("wrong transponder %d of %s: unknown polarization %s", transponder.Frequency,
+				sat.GetName(), transponder.Polarization)
+		}
+
+		var line string
+		switch transponder.System {
+		case systemDVBS:
+			line = fmt.Sprintf("S %d %s %d %s\n", transponder.Frequency, transponder.Polarization,
+				transponder.SymbolRate, scanFEC(&transponder))
+		case systemDVBS2:
+			modulation := transponder.Modulation
+			if modulation == "" {
+				modulation = "AUTO"
+			}
+			line = fmt.Sprintf("S2 %d %s %d %s AUTO %s\n", transponder.Frequency, transponder.Polarization,
+				transponder.SymbolRate, scanFEC(&transponder), modulation)
+		default:
+			return fmt.Errorf("wrong transponder %d of %s: unknown system %s", transponder.Frequency,
+				sat.GetName(), transponder.System)
+		}
+
+		if _, err := io.WriteString(writer, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+const (
+	siteFormatHTML     = "html"
+	siteFormatMarkdown = "markdown"
+
+	siteRegionsDir    = "regions"
+	siteSatellitesDir = "satellites"
+)
+
+// SiteCatalogue holds the storage state rendered into the static site. History and transponders are keyed by
+// satellite names, satellites without them have no such sections on their pages.
+type SiteCatalogue struct {
+	Generated    time.Time
+	Satellites   []Satellite
+	History      map[string][]HistoryRecord
+	Transponders map[string][]Transponder
+	Relocations  []Relocation
+	Runs         []SyncRun
+}
+
+// siteTemplates is a set of named page templates of one site format, both html and text templates implement it.
+type siteTemplates interface {
+	ExecuteTemplate(writer io.Writer, name string, data interface{}) error
+}
+
+// siteEntry is a satellite row of site pages with paths of its page and region page relative to the site root.
+type siteEntry struct {
+	Name        string
+	Position    string
+	Band        string
+	URL         string
+	Region      string
+	Tags        string
+	ManualTags  string
+	Annotations map[string]string
+	Path        string
+	RegionPath  string
+}
+
+// siteRegion is a region with the path of its page and count of its satellites.
+type siteRegion struct {
+	Name  string
+	Path  string
+	Count int
+}
+
+// siteChange is a field change of a satellite made by a sync run.
+type siteChange struct {
+	HistoryRecord
+	Path string
+}
+
+// siteRelocation is a relocation of a satellite detected by a sync run with formatted positions.
+type siteRelocation struct {
+	Relocation
+	From string
+	To   string
+	Path string
+}
+
+// siteRun is a sync run with field changes and relocations it made.
+type siteRun struct {
+	SyncRun
+	Changes     []siteChange
+	Relocations []siteRelocation
+}
+
+// sitePageData is passed to every page template. Root is the path from the page to the site root, so pages link
+// each other with relative paths and the site can be served from any directory.
+type sitePageData struct {
+	Title        string
+	Root         string
+	Generated    string
+	Regions      []siteRegion
+	Satellites   []siteEntry
+	Entry        siteEntry
+	History      []HistoryRecord
+	Transponders []Transponder
+	Runs         []siteRun
+}
+
+// sitePage is one file of the site.
+type sitePage struct {
+	path     string
+	template string
+	data     *sitePageData
+}
+
+// siteFileName returns name usable as a file name and a link without escaping.
+func siteFileName(name string) string {
+	return strings.Trim(scanFileNameRegex.ReplaceAllString(name, "-"), "-")
+}
+
+// formatThousandths returns the value divided by 1000, e.g. kHz as MHz.
+func formatThousandths(value int64) string {
+	return strconv.FormatFloat(float64(value)/1000, 'f', -1, 64)
+}
+
+// markdownEscape escapes characters which break Markdown tables and links.
+var markdownEscape = strings.NewReplacer(`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", "&lt;", ">", "&gt;", "`", "\\`").Replace
+
+var siteFunctions = map[string]interface{}{"thousandths": formatThousandths, "md": markdownEscape}
+
+// getSiteTemplates returns page templates and file extension of the site format.
+func getSiteTemplates(format string) (siteTemplates, string, error) {
+	switch format {
+	case siteFormatHTML:
+		return htmltemplate.Must(htmltemplate.New(format).Funcs(siteFunctions).Parse(siteHTMLTemplates)), ".html", nil
+	case siteFormatMarkdown:
+		return texttemplate.Must(texttemplate.New(format).Funcs(siteFunctions).Parse(siteMarkdownTemplates)), ".md",
+			nil
+	default:
+		return nil, "", fmt.Errorf("unknown site format %s, available formats: %s, %s", format, siteFormatHTML,
+			siteFormatMarkdown)
+	}
+}
+
+// makeSitePages lays out pages of the site: the index of all satellites, a page per region, a page per satellite
+// and recent changes of the catalogue runs. Satellites with unknown region are listed on the index only.
+func makeSitePages(catalogue *SiteCatalogue, ext string) []sitePage {
+	satellites := append([]Satellite(nil), catalogue.Satellites...)
+	sort.Sort(ByPosName(satellites))
+	generated := formatMoment(catalogue.Generated)
+
+	entries := make([]siteEntry, 0, len(satellites))
+	var regionNames []string
+	byRegion := make(map[string][]siteEntry)
+	for i := range satellites {
+		sat := &satellites[i]
+		entry := siteEntry{
+			Name:        sat.GetName(),
+			Position:    formatOrbitalPosition(sat.GetPosition()),
+			Band:        sat.GetBand(),
+			URL:         sat.GetURL(),
+			Region:      sat.GetRegion(),
+			Tags:        sat.Tags,
+			ManualTags:  sat.ManualTags,
+			Annotations: sat.Annotations,
+			Path:        siteSatellitesDir + "/" + scanFileName(sat) + ext,
+		}
+		if entry.Region != "" {
+			entry.RegionPath = siteRegionsDir + "/" + siteFileName(entry.Region) + ext
+			if _, found := byRegion[entry.Region]; !found {
+				regionNames = append(regionNames, entry.Region)
+			}
+			byRegion[entry.Region] = append(byRegion[entry.Region], entry)
+		}
+		entries = append(entries, entry)
+	}
+	sort.Strings(regionNames)
+
+	regions := make([]siteRegion, 0, len(regionNames))
+	for _, name := range regionNames {
+		regions = append(regions, siteRegion{Name: name, Path: byRegion[name][0].RegionPath,
+			Count: len(byRegion[name])})
+	}
+
+	pages := []sitePage{{path: "index" + ext, template: "index", data: &sitePageData{Title: "Satellites",
+		Generated: generated, Regions: regions, Satellites: entries}}}
+	for _, region := range regions {
+		pages = append(pages, sitePage{path: region.Path, template: "region", data: &sitePageData{
+			Title: "Satellites of " + region.Name, Root: "../", Generated: generated,
+			Satellites: byRegion[region.Name]}})
+	}
+	for _, entry := range entries {
+		pages = append(pages, sitePage{path: entry.Path, template: "satellite", data: &sitePageData{
+			Title: entry.Position + " " + entry.Name, Root: "../", Generated: generated, Entry: entry,
+			History: catalogue.History[entry.Name], Transponders: catalogue.Transponders[entry.Name]}})
+	}
+
+	runs := make([]siteRun, 0, len(catalogue.Runs))
+	byRunID := make(map[string]int, len(catalogue.Runs))
+	for _, run := range catalogue.Runs {
+		byRunID[run.RunID] = len(runs)
+		runs = append(runs, siteRun{SyncRun: run})
+	}
+	for _, entry := range entries {
+		for _, record := range catalogue.History[entry.Name] {
+			if i, found := byRunID[record.RunID]; found {
+				record.Name = entry.Name
+				runs[i].Changes = append(runs[i].Changes, siteChange{HistoryRecord: record, Path: entry.Path})
+			}
+		}
+	}
+	paths := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		paths[entry.Name] = entry.Path
+	}
+	for _, relocation := range catalogue.Relocations {
+		if i, found := byRunID[relocation.RunID]; found {
+			runs[i].Relocations = append(runs[i].Relocations, siteRelocation{Relocation: relocation,
+				From: formatOrbitalPosition(relocation.FromPosition), To: formatOrbitalPosition(relocation.ToPosition),
+				Path: paths[relocation.Name]})
+		}
+	}
+	for i := range runs {
+		changes := runs[i].Changes
+		sort.SliceStable(changes, func(a, b int) bool { return changes[a].Changed < changes[b].Changed })
+	}
+	pages = append(pages, sitePage{path: "changes" + ext, template: "changes", data: &sitePageData{
+		Title: "Recent changes", Generated: generated, Runs: runs}})
+
+	return pages
+}
+
+// LoadSiteCatalogue loads active satellites with their history, relocations and given count of the latest sync
+// runs from storage. Transponders are not stored, so they are collected from satellite pages if needed.
+func LoadSiteCatalogue(runs int, withTransponders bool) *SiteCatalogue {
+	catalogue := &SiteCatalogue{
+		Generated:   time.Now(),
+		Satellites:  LoadDbSatellites(),
+		History:     make(map[string][]HistoryRecord),
+		Relocations: LoadAllRelocations(),
+		Runs:        LoadSyncRuns(runs),
+	}
+
+	log.Info("loading history of satellites from storage ...")
+	for _, sat := range catalogue.Satellites {
+		records, err := getRepository().LoadHistory(sat.GetName())
+		if err != nil {
+			log.WithError(err).Fatal("critical error, shutting down ...")
+		}
+		catalogue.History[sat.GetName()] = records
+	}
+
+	if withTransponders {
+		catalogue.Transponders = collectTransponders(catalogue.Satellites)
+	}
+	return catalogue
+}
+
+// WriteSite renders the catalogue in given format, html or markdown, to the directory and returns count of written
+// pages. Every page is written atomically, pages of satellites and regions which are gone are left as is.
+func WriteSite(dir, format string, catalogue *SiteCatalogue) (int, error) {
+	templates, ext, err := getSiteTemplates(format)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, subdir := range []string{siteRegionsDir, siteSatellitesDir} {
+		if err := os.MkdirAll(filepath.Join(dir, subdir), 0755); err != nil {
+			return 0, err
+		}
+	}
+
+	pages := makeSitePages(catalogue, ext)
+	for _, page := range pages {
+		page := page
+		if err := writeFileAtomically(filepath.Join(dir, filepath.FromSlash(page.path)), func(writer io.Writer) error {
+			return templates.ExecuteTemplate(writer, page.template, page.data)
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return len(pages), nil
+}
+
+const siteHTMLTemplates = `
+{{- define "top" -}}
+<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: left; }
+th { background: #eee; }
+nav a { margin-right: 1em; }
+</style>
+</head>
+<body>
+<nav><a href="{{.Root}}index.html">Satellites</a><a href="{{.Root}}changes.html">Recent changes</a></nav>
+<h1>{{.Title}}</h1>
+{{end -}}
+
+{{- define "bottom" -}}
+<p><small>Generated {{.Generated}} UTC</small></p>
+</body>
+</html>
+{{end -}}
+
+{{- define "satellites" -}}
+<table>
+<tr><th>Position</th><th>Name</th><th>Band</th><th>Region</th><th>Tags</th></tr>
+{{range .Satellites -}}
+<tr><td>{{.Position}}</td><td><a href="{{$.Root}}{{.Path}}">{{.Name}}</a></td><td>{{.Band}}</td>
+{{- if .RegionPath}}<td><a href="{{$.Root}}{{.RegionPath}}">{{.Region}}</a></td>{{else}}<td></td>{{end -}}
+<td>{{.Tags}}</td></tr>
+{{end -}}
+</table>
+{{end -}}
+
+{{- define "index" -}}
+{{template "top" .}}
+{{- if .Regions}}<p>Regions:{{range .Regions}} <a href="{{.Path}}">{{.Name}}</a> ({{.Count}}){{end}}</p>
+{{end -}}
+{{template "satellites" .}}
+{{- template "bottom" .}}
+{{- end -}}
+
+{{- define "region" -}}
+{{template "top" .}}
+{{- template "satellites" .}}
+{{- template "bottom" .}}
+{{- end -}}
+
+{{- define "satellite" -}}
+{{template "top" .}}
+{{- with .Entry -}}
+<table>
+<tr><th>Position</th><td>{{.Position}}</td></tr>
+<tr><th>Band</th><td>{{.Band}}</td></tr>
+<tr><th>Region</th><td>{{if .RegionPath}}<a href="{{$.Root}}{{.RegionPath}}">{{.Region}}</a>{{end}}</td></tr>
+<tr><th>Page</th><td><a href="{{.URL}}">{{.URL}}</a></td></tr>
+<tr><th>Tags</th><td>{{.Tags}}</td></tr>
+<tr><th>Manual tags</th><td>{{.ManualTags}}</td></tr>
+{{range $key, $value := .Annotations}}<tr><th>{{$key}}</th><td>{{$value}}</td></tr>
+{{end -}}
+</table>
+{{end -}}
+{{if .Transponders -}}
+<h2>Transponders</h2>
+<table>
+<tr><th>Frequency, MHz</th><th>Polarization</th><th>Symbol rate, ksym/s</th><th>FEC</th><th>System</th><th>Modulation</th></tr>
+{{range .Transponders -}}
+<tr><td>{{thousandths .Frequency}}</td><td>{{.Polarization}}</td><td>{{thousandths .SymbolRate}}</td><td>{{.FEC}}</td><td>{{.System}}</td><td>{{.Modulation}}</td></tr>
+{{end -}}
+</table>
+{{end -}}
+{{if .History -}}
+<h2>History</h2>
+<table>
+<tr><th>Changed</th><th>Field</th><th>Old value</th><th>New value</th><th>Run</th></tr>
+{{range .History -}}
+<tr><td>{{.Changed}}</td><td>{{.Field}}</td><td>{{.OldValue}}</td><td>{{.NewValue}}</td><td>{{.RunID}}</td></tr>
+{{end -}}
+</table>
+{{end -}}
+{{template "bottom" .}}
+{{- end -}}
+
+{{- define "changes" -}}
+{{template "top" .}}
+{{- range .Runs -}}
+<h2>{{.Started}} {{.Status}}</h2>
+<p>Run {{.RunID}}: {{.Parsed}} parsed, {{.Inserted}} inserted, {{.Closed}} closed, {{.Updated}} updated, {{.Relocated}} relocated, {{.Failed}} failed</p>
+{{if .Changes -}}
+<table>
+<tr><th>Satellite</th><th>Field</th><th>Old value</th><th>New value</th></tr>
+{{range .Changes -}}
+<tr><td><a href="{{.Path}}">{{.Name}}</a></td><td>{{.Field}}</td><td>{{.OldValue}}</td><td>{{.NewValue}}</td></tr>
+{{end -}}
+</table>
+{{end -}}
+{{if .Relocations -}}
+<table>
+<tr><th>Relocated satellite</th><th>From</th><th>To</th><th>Direction</th></tr>
+{{range .Relocations -}}
+<tr><td>{{if .Path}}<a href="{{.Path}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</td><td>{{.From}}</td><td>{{.To}}</td><td>{{.Direction}}</td></tr>
+{{end -}}
+</table>
+{{end -}}
+{{else -}}
+<p>No sync runs yet.</p>
+{{end -}}
+{{template "bottom" .}}
+{{- end -}}
+`
+
+const siteMarkdownTemplates = `
+{{- define "top" -}}
+[Satellites]({{.Root}}index.md) | [Recent changes]({{.Root}}changes.md)
+
+# {{md .Title}}
+
+{{end -}}
+
+{{- define "bottom" -}}
+_Generated {{.Generated}} UTC_
+{{end -}}
+
+{{- define "satellites" -}}
+| Position | Name | Band | Region | Tags |
+|---|---|---|---|---|
+{{range .Satellites -}}
+| {{.Position}} | [{{md .Name}}]({{$.Root}}{{.Path}}) | {{md .Band}} | {{if .RegionPath}}[{{md .Region}}]({{$.Root}}{{.RegionPath}}){{end}} | {{md .Tags}} |
+{{end}}
+{{end -}}
+
+{{- define "index" -}}
+{{template "top" .}}
+{{- if .Regions}}Regions:{{range .Regions}} [{{md .Name}}]({{.Path}}) ({{.Count}}){{end}}
+
+{{end -}}
+{{template "satellites" .}}
+{{- template "bottom" .}}
+{{- end -}}
+
+{{- define "region" -}}
+{{template "top" .}}
+{{- template "satellites" .}}
+{{- template "bottom" .}}
+{{- end -}}
+
+{{- define "satellite" -}}
+{{template "top" .}}
+{{- with .Entry -}}
+| Field | Value |
+|---|---|
+| Position | {{.Position}} |
+| Band | {{md .Band}} |
+| Region | {{if .RegionPath}}[{{md .Region}}]({{$.Root}}{{.RegionPath}}){{end}} |
+| Page | <{{.URL}}> |
+| Tags | {{md .Tags}} |
+| Manual tags | {{md .ManualTags}} |
+{{range $key, $value := .Annotations}}| {{md $key}} | {{md $value}} |
+{{end}}
+{{end -}}
+{{if .Transponders -}}
+## Transponders
+
+| Frequency, MHz | Polarization | Symbol rate, ksym/s | FEC | System | Modulation |
+|---|---|---|---|---|---|
+{{range .Transponders -}}
+| {{thousandths .Frequency}} | {{.Polarization}} | {{thousandths .SymbolRate}} | {{.FEC}} | {{.System}} | {{.Modulation}} |
+{{end}}
+{{end -}}
+{{if .History -}}
+## History
+
+| Changed | Field | Old value | New value | Run |
+|---|---|---|---|---|
+{{range .History -}}
+| {{.Changed}} | {{.Field}} | {{md .OldValue}} | {{md .NewValue}} | {{.RunID}} |
+{{end}}
+{{end -}}
+{{template "bottom" .}}
+{{- end -}}
+
+{{- define "changes" -}}
+{{template "top" .}}
+{{- range .Runs -}}
+## {{.Started}} {{.Status}}
+
+Run {{.RunID}}: {{.Parsed}} parsed, {{.Inserted}} inserted, {{.Closed}} closed, {{.Updated}} updated, {{.Relocated}} relocated, {{.Failed}} failed
+
+{{if .Changes -}}
+| Satellite | Field | Old value | New value |
+|---|---|---|---|
+{{range .Changes -}}
+| [{{md .Name}}]({{.Path}}) | {{.Field}} | {{md .OldValue}} | {{md .NewValue}} |
+{{end}}
+{{end -}}
+{{if .Relocations -}}
+| Relocated satellite | From | To | Direction |
+|---|---|---|---|
+{{range .Relocations -}}
+| {{if .Path}}[{{md .Name}}]({{.Path}}){{else}}{{md .Name}}{{end}} | {{.From}} | {{.To}} | {{.Direction}} |
+{{end}}
+{{end -}}
+{{else -}}
+No sync runs yet.
+
+{{end -}}
+{{template "bottom" .}}
+{{- end -}}
+`
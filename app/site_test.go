@@ -0,0 +1,105 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testSiteCatalogue() *SiteCatalogue {
+	satellites := testExportList()
+	for i := range satellites {
+		satellites[i].SetSource("https://www.lyngsat.com/europe.html")
+		satellites[i].URL = "https://www.lyngsat.com/" + scanFileName(&satellites[i]) + ".html"
+	}
+	satellites[0].SetSource("https://www.lyngsat.com/america.html")
+	satellites[4].SetSource("")
+	satellites[2].Tags = "ku-europe"
+	satellites[2].Annotations = map[string]string{"dish": "90 cm"}
+
+	return &SiteCatalogue{
+		Generated:  time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Satellites: satellites,
+		History: map[string][]HistoryRecord{
+			"Hot Bird 13E": {
+				{Field: fieldBand, OldValue: "C", NewValue: "Ku", RunID: "old-run", Changed: "2019-12-01 00:00:00"},
+				{Field: fieldPosition, OldValue: "13.1", NewValue: "13", RunID: "run-2",
+					Changed: "2020-01-01 00:00:00"},
+			},
+		},
+		Transponders: map[string][]Transponder{
+			"Hot Bird 13E": {{Frequency: 10719000, Polarization: "V", SymbolRate: 27500000, FEC: "5/6",
+				System: systemDVBS, Modulation: "QPSK"}},
+		},
+		Relocations: []Relocation{
+			{Name: "Hot Bird 13E", FromPosition: 13.1, ToPosition: 13, Direction: driftWest, RunID: "run-2"},
+			{Name: "Gone", FromPosition: 7, ToPosition: 9, Direction: driftEast, RunID: "run-2"},
+			{Name: "Hot Bird 13E", FromPosition: 16, ToPosition: 13.1, Direction: driftWest, RunID: "old-run"},
+		},
+		Runs: []SyncRun{
+			{RunID: "run-2", Started: "2020-01-01 00:00:00", Status: runSucceeded, Parsed: 5, Updated: 1,
+				Relocated: 2},
+			{RunID: "run-1", Started: "2019-12-31 00:00:00", Status: runSucceeded, Parsed: 5, Inserted: 5},
+		},
+	}
+}
+
+func writeTestSite(t *testing.T, format string) (string, func()) {
+	dir, err := ioutil.TempDir("", "sat-parser")
+	require.NoError(t, err)
+
+	count, err := WriteSite(dir, format, testSiteCatalogue())
+	require.NoError(t, err)
+	assert.Equal(t, 9, count, "index, 2 regions, 5 satellites and changes pages expected")
+	return dir, func() { _ = os.RemoveAll(dir) }
+}
+
+func readTestSitePage(t *testing.T, dir, path string) []byte {
+	content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+	require.NoError(t, err)
+	return content
+}
+
+func TestWriteSiteMarkdown(t *testing.T) {
+	dir, cleanup := writeTestSite(t, siteFormatMarkdown)
+	defer cleanup()
+
+	assertGolden(t, "site_index.md", readTestSitePage(t, dir, "index.md"))
+	assertGolden(t, "site_region.md", readTestSitePage(t, dir, "regions/europe.md"))
+	assertGolden(t, "site_satellite.md", readTestSitePage(t, dir, "satellites/13.0E-Hot-Bird-13E.md"))
+	assertGolden(t, "site_changes.md", readTestSitePage(t, dir, "changes.md"))
+}
+
+func TestWriteSiteHTML(t *testing.T) {
+	dir, cleanup := writeTestSite(t, siteFormatHTML)
+	defer cleanup()
+
+	index := string(readTestSitePage(t, dir, "index.html"))
+	assert.Contains(t, index, `<a href="satellites/19.2E-Astra-1KR-1L.html">Astra 1KR &amp; &lt;1L&gt;</a>`)
+	assert.Contains(t, index, `<a href="regions/america.html">america</a> (1)`)
+	assert.NotContains(t, index, "<script")
+	assert.NotContains(t, index, "<link")
+
+	satellite := string(readTestSitePage(t, dir, "satellites/13.0E-Hot-Bird-13E.html"))
+	assert.Contains(t, satellite, `<a href="../regions/europe.html">europe</a>`)
+	assert.Contains(t, satellite, "<tr><th>dish</th><td>90 cm</td></tr>")
+	assert.Contains(t, satellite, "<tr><td>10719</td><td>V</td><td>27500</td><td>5/6</td>")
+
+	changes := string(readTestSitePage(t, dir, "changes.html"))
+	assert.Contains(t, changes, `<a href="satellites/13.0E-Hot-Bird-13E.html">Hot Bird 13E</a>`)
+	assert.Contains(t, changes, `<tr><td><a href="satellites/13.0E-Hot-Bird-13E.html">Hot Bird 13E</a></td>`+
+		`<td>13.1°E</td><td>13.0°E</td><td>west</td></tr>`)
+	assert.Contains(t, changes, "<tr><td>Gone</td><td>7.0°E</td><td>9.0°E</td><td>east</td></tr>",
+		"satellites without pages must be listed without links")
+	assert.NotContains(t, changes, "old-run", "changes of older runs must not be listed")
+	assert.NotContains(t, changes, "16.0°E", "relocations of older runs must not be listed")
+}
+
+func TestWriteSiteUnknownFormat(t *testing.T) {
+	_, err := WriteSite("", "pdf", testSiteCatalogue())
+	assert.Error(t, err)
+}
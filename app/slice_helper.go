@@ -21,8 +21,27 @@ func FindAbsent(a, b *[]Satellite) []Satellite {
 	return FindNewElements(b, a)
 }
 
-// FindChanged returns pairs of elements that are changed between `a` and `b`. Name
-func FindChanged(a, b *[]Satellite) [][]Satellite {
+// FindSourceChanges returns elements of `a` with sources of the same named elements of `b` if they differ.
+// Elements keep their ids, so they can be saved.
+func FindSourceChanges(a, b *[]Satellite) []Satellite {
+	sources := make(map[string]string, len(*b))
+	for _, x := range *b {
+		sources[x.GetName()] = x.GetSource()
+	}
+
+	var changedItems []Satellite
+	for _, x := range *a {
+		if source, found := sources[x.GetName()]; found && source != x.GetSource() {
+			x.SetSource(source)
+			changedItems = append(changedItems, x)
+		}
+	}
+	return changedItems
+}
+
+// FindChanged returns pairs of elements with the same name that are meaningfully changed between `a` and `b`
+// according to the comparator.
+func FindChanged(a, b *[]Satellite, comparator *Comparator) [][]Satellite {
 	exists := make(map[string]Satellite, len(*a))
 	for _, x := range *a {
 		exists[x.GetName()] = x
@@ -31,7 +50,7 @@ func FindChanged(a, b *[]Satellite) [][]Satellite {
 	var changedItems [][]Satellite
 	for _, x := range *b {
 		if foundItem, found := exists[x.GetName()]; found {
-			if foundItem != x {
+			if !comparator.Equal(&foundItem, &x) {
 				changedItems = append(changedItems, []Satellite{foundItem, x})
 			}
 		}
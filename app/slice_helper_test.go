@@ -41,7 +41,7 @@ func TestChangedPosition(t *testing.T) {
 	list1 := []Satellite{makeSat("one", 1), initial, makeSat("two", 2)}
 	list2 := []Satellite{changed, makeSat("one", 1), makeSat("two", 2)}
 
-	absenceItems := FindChanged(&list1, &list2)
+	absenceItems := FindChanged(&list1, &list2, &Comparator{})
 
 	assert.Len(t, absenceItems, 1)
 	assert.Equal(t, absenceItems[0][0], initial)
@@ -56,7 +56,7 @@ func TestChangedURL(t *testing.T) {
 		list1 := []Satellite{makeSat("one", 1), initial, makeSat("two", 2)}
 		list2 := []Satellite{changed, makeSat("one", 1), makeSat("two", 2)}
 
-		absenceItems := FindChanged(&list1, &list2)
+		absenceItems := FindChanged(&list1, &list2, &Comparator{})
 
 		assert.Len(t, absenceItems, 1)
 		assert.Equal(t, absenceItems[0][0], initial)
@@ -71,7 +71,7 @@ func TestChangedBand(t *testing.T) {
 	list1 := []Satellite{makeSat("one", 1), initial, makeSat("two", 2)}
 	list2 := []Satellite{changed, makeSat("one", 1), makeSat("two", 2)}
 
-	absenceItems := FindChanged(&list1, &list2)
+	absenceItems := FindChanged(&list1, &list2, &Comparator{})
 
 	assert.Len(t, absenceItems, 1)
 	assert.Equal(t, absenceItems[0][0], initial)
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	slotsFormatText = "text"
+	slotsFormatJSON = "json"
+)
+
+// OrbitalSlot is a group of co-located satellites. Position is the mean position of members, From and To are
+// positions of the westmost and eastmost members. Occupancy holds member counts since moments they changed.
+type OrbitalSlot struct {
+	Position  float64         `json:"position"`
+	From      float64         `json:"from"`
+	To        float64         `json:"to"`
+	Members   []SlotMember    `json:"members"`
+	Bands     []string        `json:"bands"`
+	Inclined  bool            `json:"inclined"`
+	Occupancy []SlotOccupancy `json:"occupancy,omitempty"`
+}
+
+// SlotMember is a satellite of an orbital slot.
+type SlotMember struct {
+	Name     string  `json:"name"`
+	Position float64 `json:"position"`
+	Band     string  `json:"band"`
+	Inclined bool    `json:"inclined"`
+}
+
+// SlotOccupancy is a count of satellites in the slot since the moment.
+type SlotOccupancy struct {
+	Since string `json:"since"`
+	Count int    `json:"count"`
+}
+
+// CatalogueSnapshot is a list of satellites active at the moment.
+type CatalogueSnapshot struct {
+	Moment     time.Time
+	Satellites []Satellite
+}
+
+// isInclined returns true if the satellite has inclined orbit. Inclination is known for parsed satellites only,
+// stored ones are recognized by the tag given by tagging rules.
+func isInclined(sat *Satellite, inclinedTag string) bool {
+	if sat.GetInclination() > 0 {
+		return true
+	}
+	if inclinedTag == "" {
+		return false
+	}
+	for _, tag := range sat.GetAllTags() {
+		if tag == inclinedTag {
+			return true
+		}
+	}
+	return false
+}
+
+// ClusterSlots groups satellites into orbital slots. Satellites are taken by position and a satellite joins the
+// slot if it is not farther than tolerance degrees from the westmost member, so a slot is never wider than
+// the tolerance.
+func ClusterSlots(list []Satellite, tolerance float64, inclinedTag string) []OrbitalSlot {
+	satellites := append([]Satellite(nil), list...)
+	sort.Sort(ByPosName(satellites))
+
+	var slots []OrbitalSlot
+	for i := range satellites {
+		sat := &satellites[i]
+		if len(slots) == 0 || sat.GetPosition()-slots[len(slots)-1].From > tolerance {
+			slots = append(slots, OrbitalSlot{From: sat.GetPosition()})
+		}
+		slot := &slots[len(slots)-1]
+		member := SlotMember{Name: sat.GetName(), Position: sat.GetPosition(), Band: sat.GetBand(),
+			Inclined: isInclined(sat, inclinedTag)}
+		slot.Members = append(slot.Members, member)
+		slot.To = member.Position
+		slot.Inclined = slot.Inclined || member.Inclined
+	}
+
+	for i := range slots {
+		slot := &slots[i]
+		var sum float64
+		bands := make(map[string]bool)
+		for _, member := range slot.Members {
+			sum += member.Position
+			if member.Band != "" && !bands[member.Band] {
+				bands[member.Band] = true
+				slot.Bands = append(slot.Bands, member.Band)
+			}
+		}
+		slot.Position = math.Round(sum/float64(len(slot.Members))*1000) / 1000
+		sort.Strings(slot.Bands)
+	}
+	return slots
+}
+
+// findSlot returns index of the slot nearest to the position within tolerance, -1 if there is no such slot.
+func findSlot(slots []OrbitalSlot, position, tolerance float64) int {
+	found, distance := -1, tolerance
+	for i := range slots {
+		if d := math.Abs(slots[i].Position - position); d <= distance {
+			found, distance = i, d
+		}
+	}
+	return found
+}
+
+// AddSlotOccupancy counts satellites of every snapshot in the slots, a satellite is counted in the nearest slot
+// within tolerance. Snapshots must be ordered by time, only changed counts are kept.
+func AddSlotOccupancy(slots []OrbitalSlot, snapshots []CatalogueSnapshot, tolerance float64) {
+	for _, snapshot := range snapshots {
+		counts := make([]int, len(slots))
+		for i := range snapshot.Satellites {
+			if slot := findSlot(slots, snapshot.Satellites[i].GetPosition(), tolerance); slot >= 0 {
+				counts[slot]++
+			}
+		}
+
+		for i := range slots {
+			occupancy := &slots[i].Occupancy
+			if n := len(*occupancy); n > 0 && (*occupancy)[n-1].Count == counts[i] {
+				continue
+			}
+			*occupancy = append(*occupancy, SlotOccupancy{Since: formatMoment(snapshot.Moment), Count: counts[i]})
+		}
+	}
+}
+
+// WriteSlots writes slots as text report or JSON.
+func WriteSlots(writer io.Writer, slots []OrbitalSlot, format string) error {
+	switch format {
+	case slotsFormatJSON:
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if slots == nil {
+			slots = []OrbitalSlot{}
+		}
+		return encoder.Encode(slots)
+
+	case slotsFormatText:
+		for i, slot := range slots {
+			if i > 0 {
+				_, _ = fmt.Fprintln(writer)
+			}
+			inclined := "no"
+			if slot.Inclined {
+				inclined = "yes"
+			}
+			_, _ = fmt.Fprintf(writer, "%s (%s .. %s), %d satellites, bands: %s, inclined: %s\n",
+				formatOrbitalPosition(slot.Position), formatPosition(slot.From), formatPosition(slot.To),
+				len(slot.Members), strings.Join(slot.Bands, ", "), inclined)
+
+			tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+			for _, member := range slot.Members {
+				name := member.Name
+				if member.Inclined {
+					name += " (inclined)"
+				}
+				_, _ = fmt.Fprintf(tabWriter, "  %s\t%s\t%s\n", formatPosition(member.Position), name, member.Band)
+			}
+			if err := tabWriter.Flush(); err != nil {
+				return err
+			}
+
+			if len(slot.Occupancy) > 0 {
+				changes := make([]string, 0, len(slot.Occupancy))
+				for _, occupancy := range slot.Occupancy {
+					changes = append(changes, fmt.Sprintf("%d since %s", occupancy.Count, occupancy.Since))
+				}
+				_, _ = fmt.Fprintf(writer, "  occupancy: %s\n", strings.Join(changes, ", "))
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown slots format %s, available formats: %s, %s", format, slotsFormatText,
+			slotsFormatJSON)
+	}
+}
+
+// LoadSlotSnapshots loads catalogues as of finishes of the latest sync runs which applied changes, the oldest
+// first. Occupancy changes by sync runs only, so these moments show all its changes.
+func LoadSlotSnapshots(runs int) []CatalogueSnapshot {
+	var snapshots []CatalogueSnapshot
+	for _, run := range LoadSyncRuns(runs) {
+		if run.Status != runSucceeded && run.Status != runFailed || run.Finished == "" {
+			continue
+		}
+		finished, err := ParseMoment(run.Finished)
+		if err != nil {
+			log.WithError(err).Fatalf("wrong finish time of run %s", run.RunID)
+		}
+
+		versions := LoadCatalogueAsOf(finished)
+		satellites := make([]Satellite, 0, len(versions))
+		for _, version := range versions {
+			satellites = append(satellites, version.Satellite)
+		}
+		snapshots = append([]CatalogueSnapshot{{Moment: finished, Satellites: satellites}}, snapshots...)
+	}
+	return snapshots
+}
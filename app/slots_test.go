@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func testSlotList() []Satellite {
+	list := []Satellite{
+		makeBandSat("Hot Bird 13F", 13, "Ku"),
+		makeBandSat("Astra 1KR", 19.2, "Ku"),
+		makeBandSat("Hot Bird 13E", 13, "Ku"),
+		makeBandSat("Eutelsat 13B", 13.2, "Ka"),
+		makeBandSat("Astra 1M", 19.2, "Ku"),
+		makeBandSat("Astra 1H", 19.2, "C"),
+		makeBandSat("Eutelsat 16A", 16, "Ku"),
+	}
+	list[5].SetTags([]string{"inclined"})
+	return list
+}
+
+func TestClusterSlots(t *testing.T) {
+	slots := ClusterSlots(testSlotList(), 0.3, "inclined")
+	require.Len(t, slots, 3)
+
+	assert.Equal(t, 13.067, slots[0].Position)
+	assert.Equal(t, 13.0, slots[0].From)
+	assert.Equal(t, 13.2, slots[0].To)
+	assert.Equal(t, []string{"Ka", "Ku"}, slots[0].Bands)
+	assert.False(t, slots[0].Inclined)
+	assert.Equal(t, []SlotMember{
+		{Name: "Hot Bird 13E", Position: 13, Band: "Ku"},
+		{Name: "Hot Bird 13F", Position: 13, Band: "Ku"},
+		{Name: "Eutelsat 13B", Position: 13.2, Band: "Ka"},
+	}, slots[0].Members)
+
+	assert.Len(t, slots[1].Members, 1)
+	assert.Equal(t, 19.2, slots[2].Position)
+	assert.Equal(t, []string{"C", "Ku"}, slots[2].Bands)
+	assert.True(t, slots[2].Inclined, "tagged satellite must be recognized as inclined")
+	assert.True(t, slots[2].Members[0].Inclined)
+}
+
+func TestClusterSlotsTolerance(t *testing.T) {
+	list := []Satellite{makeSat("a", 1), makeSat("b", 1.2), makeSat("c", 1.4), makeSat("d", 1.6)}
+	slots := ClusterSlots(list, 0.3, "")
+	require.Len(t, slots, 2, "slot must not grow wider than tolerance")
+	assert.Equal(t, 1.1, slots[0].Position)
+	assert.Equal(t, 1.5, slots[1].Position)
+
+	assert.Len(t, ClusterSlots(list, 0, ""), 4)
+	assert.Empty(t, ClusterSlots(nil, 0.3, ""))
+}
+
+func TestClusterSlotsParsedInclination(t *testing.T) {
+	sat := makeSat("Intelsat 10-02", 1)
+	sat.SetInclination("(incl. 0.6°)")
+	slots := ClusterSlots([]Satellite{sat}, 0.3, "")
+	require.Len(t, slots, 1)
+	assert.True(t, slots[0].Inclined)
+}
+
+func TestAddSlotOccupancy(t *testing.T) {
+	slots := ClusterSlots(testSlotList(), 0.3, "inclined")
+	first := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []CatalogueSnapshot{
+		{Moment: first, Satellites: []Satellite{makeSat("Hot Bird 13E", 13), makeSat("Astra 1KR", 19.2)}},
+		{Moment: first.Add(time.Hour), Satellites: []Satellite{makeSat("Hot Bird 13E", 13),
+			makeSat("Astra 1KR", 19.2), makeSat("Astra 1M", 19.2), makeSat("Far Away", 30)}},
+		{Moment: first.Add(2 * time.Hour), Satellites: []Satellite{makeSat("Hot Bird 13E", 13),
+			makeSat("Astra 1KR", 19.2), makeSat("Astra 1M", 19.2)}},
+	}
+
+	AddSlotOccupancy(slots, snapshots, 0.3)
+	assert.Equal(t, []SlotOccupancy{{Since: "2020-01-01 00:00:00", Count: 1}}, slots[0].Occupancy)
+	assert.Equal(t, []SlotOccupancy{{Since: "2020-01-01 00:00:00", Count: 0}}, slots[1].Occupancy)
+	assert.Equal(t, []SlotOccupancy{
+		{Since: "2020-01-01 00:00:00", Count: 1},
+		{Since: "2020-01-01 01:00:00", Count: 2},
+	}, slots[2].Occupancy, "only changed counts must be kept")
+}
+
+func TestWriteSlotsText(t *testing.T) {
+	slots := ClusterSlots(testSlotList()[:3], 0.3, "inclined")
+	slots[0].Occupancy = []SlotOccupancy{{Since: "2020-01-01 00:00:00", Count: 2}}
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteSlots(&buffer, slots, slotsFormatText))
+	assert.Equal(t, "13.0°E (13 .. 13), 2 satellites, bands: Ku, inclined: no\n"+
+		"  13  Hot Bird 13E  Ku\n"+
+		"  13  Hot Bird 13F  Ku\n"+
+		"  occupancy: 2 since 2020-01-01 00:00:00\n"+
+		"\n"+
+		"19.2°E (19.2 .. 19.2), 1 satellites, bands: Ku, inclined: no\n"+
+		"  19.2  Astra 1KR  Ku\n", buffer.String())
+}
+
+func TestWriteSlotsJSON(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, WriteSlots(&buffer, nil, slotsFormatJSON))
+	assert.Equal(t, "[]\n", buffer.String())
+
+	buffer.Reset()
+	slots := ClusterSlots(testSlotList(), 0.3, "inclined")
+	require.NoError(t, WriteSlots(&buffer, slots, slotsFormatJSON))
+	var decoded []OrbitalSlot
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &decoded))
+	assert.Equal(t, slots, decoded)
+	assert.Contains(t, buffer.String(), `"inclined": true`)
+
+	assert.Error(t, WriteSlots(&buffer, slots, "xml"))
+}
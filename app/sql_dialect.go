@@ -0,0 +1,536 @@
+package main
+
+import (
+	_ "github.com/go-sql-driver/mysql" // mysql driver is used explicitly in sqlx
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"           // postgres driver is used explicitly in sqlx
+	_ "github.com/mattn/go-sqlite3" // sqlite driver is used explicitly in sqlx
+	"strings"
+)
+
+const defaultTable = "satellites"
+
+// sqlTables maps placeholders used in statements to suffixes of table names, every table name is built from
+// the configured satellites table name.
+var sqlTables = map[string]string{
+	"{satellites}":  "",
+	"{history}":     "_history",
+	"{relocations}": "_relocations",
+	"{versions}":    "_versions",
+	"{runs}":        "_sync_runs",
+	"{annotations}": "_annotations",
+	"{identifiers}": "_identifiers",
+	"{migrations}":  "_migrations",
+}
+
+// sqlDialect holds differences between supported SQL databases.
+type sqlDialect struct {
+	name      string // storage.driver setting value
+	title     string // human readable name for logs
+	driver    string // database/sql driver name
+	quoteChar string
+	maxParams int // maximum count of parameters in one statement
+	// timestampParam is a placeholder of timestamp parameter in a select list, where its type is not inferred
+	timestampParam string
+	// upsertSatellites is a tail of insert statement which updates existing satellite with the same id
+	upsertSatellites string
+	// upsertAnnotation is a tail of insert statement which updates existing annotation with the same name and key
+	upsertAnnotation string
+	// upsertIdentifier is a tail of insert statement which updates existing identifier with the same name
+	upsertIdentifier string
+	migrations       []migration
+}
+
+// quote quotes identifier according to the dialect.
+func (ptr *sqlDialect) quote(identifier string) string {
+	return ptr.quoteChar + strings.Replace(identifier, ptr.quoteChar, ptr.quoteChar+ptr.quoteChar, -1) + ptr.quoteChar
+}
+
+// rebind replaces ? placeholders with the driver specific ones.
+func (ptr *sqlDialect) rebind(query string) string {
+	return sqlx.Rebind(sqlx.BindType(ptr.driver), query)
+}
+
+// expander returns replacer of table placeholders with quoted table names, {table} is replaced with raw
+// satellites table name to build names of indexes.
+func (ptr *sqlDialect) expander(table string) *strings.Replacer {
+	replacements := []string{"{table}", table}
+	for placeholder, suffix := range sqlTables {
+		replacements = append(replacements, placeholder, ptr.quote(table+suffix))
+	}
+	return strings.NewReplacer(replacements...)
+}
+
+// statements of SQLite satellites table, they create the table and rebuild the one created before migrations
+const (
+	sqliteCreateSatellites = "CREATE TABLE IF NOT EXISTS {satellites} (" +
+		"_id INTEGER PRIMARY KEY AUTOINCREMENT, " +
+		"_name TEXT NOT NULL, " +
+		"_position REAL NOT NULL, " +
+		"_url TEXT NOT NULL, " +
+		"_band TEXT NOT NULL, " +
+		"_tags TEXT NOT NULL DEFAULT '', " +
+		"_status INTEGER NOT NULL DEFAULT 1, " +
+		"_closed TIMESTAMP NULL)"
+	sqliteCreateSatellitesIndex = `CREATE INDEX IF NOT EXISTS "{table}_status_name_idx" ` +
+		"ON {satellites} (_status, _name)"
+)
+
+var (
+	mysqlDialect = &sqlDialect{
+		name:           "mysql",
+		title:          "MySQL",
+		driver:         "mysql",
+		quoteChar:      "`",
+		maxParams:      65535,
+		timestampParam: "?",
+		upsertSatellites: "ON DUPLICATE KEY UPDATE " +
+			"_position = VALUES(_position), _url = VALUES(_url), _band = VALUES(_band), _tags = VALUES(_tags), " +
+			"_source = VALUES(_source)",
+		upsertAnnotation: "ON DUPLICATE KEY UPDATE _value = VALUES(_value), _updated = VALUES(_updated)",
+		upsertIdentifier: "ON DUPLICATE KEY UPDATE _norad_id = VALUES(_norad_id), _cospar_id = VALUES(_cospar_id), " +
+			"_satcat_name = VALUES(_satcat_name), _confidence = VALUES(_confidence), _matched = VALUES(_matched)",
+		migrations: []migration{
+			{
+				version:     1,
+				description: "create satellites table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {satellites} (" +
+					"_id INT NOT NULL AUTO_INCREMENT PRIMARY KEY, " +
+					"_name VARCHAR(255) NOT NULL, " +
+					"_position DOUBLE NOT NULL, " +
+					"_url VARCHAR(255) NOT NULL, " +
+					"_band VARCHAR(64) NOT NULL, " +
+					"_tags VARCHAR(255) NOT NULL DEFAULT '', " +
+					"_status TINYINT NOT NULL DEFAULT 1, " +
+					"_closed TIMESTAMP NULL DEFAULT NULL, " +
+					"INDEX (_status, _name))"},
+				down: []string{"DROP TABLE {satellites}"},
+				// ids are numbered in the order of existing rows
+				legacy: []string{"ALTER TABLE {satellites} " +
+					"ADD COLUMN _id INT NOT NULL AUTO_INCREMENT PRIMARY KEY FIRST, " +
+					"ADD INDEX (_status, _name)"},
+			},
+			{
+				version:     2,
+				description: "create history table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {history} (" +
+					"_id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY, " +
+					"_satellite_id INT NOT NULL, " +
+					"_field VARCHAR(32) NOT NULL, " +
+					"_old_value VARCHAR(255) NOT NULL, " +
+					"_new_value VARCHAR(255) NOT NULL, " +
+					"_run_id VARCHAR(64) NOT NULL, " +
+					"_changed TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+					"INDEX (_satellite_id))"},
+				down: []string{"DROP TABLE {history}"},
+			},
+			{
+				version:     3,
+				description: "create relocations table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {relocations} (" +
+					"_id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY, " +
+					"_satellite_id INT NOT NULL, " +
+					"_from_position DOUBLE NOT NULL, " +
+					"_to_position DOUBLE NOT NULL, " +
+					"_direction VARCHAR(8) NOT NULL, " +
+					"_run_id VARCHAR(64) NOT NULL, " +
+					"_detected TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+					"INDEX (_satellite_id))"},
+				down: []string{"DROP TABLE {relocations}"},
+			},
+			{
+				version:     4,
+				description: "add manual tags column",
+				up: []string{"ALTER TABLE {satellites} " +
+					"ADD COLUMN _manual_tags VARCHAR(255) NOT NULL DEFAULT '' AFTER _tags"},
+				down: []string{"ALTER TABLE {satellites} DROP COLUMN _manual_tags"},
+			},
+			{
+				version:     5,
+				description: "create versions table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {versions} (" +
+					"_id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY, " +
+					"_satellite_id INT NOT NULL, " +
+					"_name VARCHAR(255) NOT NULL, " +
+					"_position DOUBLE NOT NULL, " +
+					"_url VARCHAR(255) NOT NULL, " +
+					"_band VARCHAR(64) NOT NULL, " +
+					"_tags VARCHAR(255) NOT NULL DEFAULT '', " +
+					"_valid_from TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+					"_valid_to TIMESTAMP NULL DEFAULT NULL, " +
+					"INDEX (_satellite_id), " +
+					"INDEX (_valid_from))",
+					// satellites closed before versioning have no known opening time and are not restored. Versions
+					// are opened in UTC like all timestamps bound by sync, not in session time zone
+					"INSERT INTO {versions} (_satellite_id, _name, _position, _url, _band, _tags, _valid_from) " +
+						"SELECT _id, _name, _position, _url, _band, _tags, UTC_TIMESTAMP() FROM {satellites} " +
+						"WHERE _status = 1"},
+				down: []string{"DROP TABLE {versions}"},
+			},
+			{
+				version:     6,
+				description: "create sync runs table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {runs} (" +
+					"_run_id VARCHAR(64) NOT NULL PRIMARY KEY, " +
+					"_started DATETIME NOT NULL, " +
+					"_finished DATETIME NOT NULL, " +
+					"_revision VARCHAR(64) NOT NULL, " +
+					"_config_hash VARCHAR(64) NOT NULL, " +
+					"_status VARCHAR(32) NOT NULL, " +
+					"_pages TEXT NOT NULL, " +
+					"_parsed INT NOT NULL, " +
+					"_parse_errors INT NOT NULL, " +
+					"_inserted INT NOT NULL, " +
+					"_closed INT NOT NULL, " +
+					"_updated INT NOT NULL, " +
+					"_relocated INT NOT NULL, " +
+					"_failed INT NOT NULL, " +
+					"INDEX (_started))"},
+				down: []string{"DROP TABLE {runs}"},
+			},
+			{
+				version:     7,
+				description: "create annotations table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {annotations} (" +
+					"_name VARCHAR(255) NOT NULL, " +
+					"_key VARCHAR(64) NOT NULL, " +
+					"_value TEXT NOT NULL, " +
+					"_updated DATETIME NOT NULL, " +
+					"PRIMARY KEY (_name, _key))"},
+				down: []string{"DROP TABLE {annotations}"},
+			},
+			{
+				version:     8,
+				description: "add source column",
+				up: []string{"ALTER TABLE {satellites} " +
+					"ADD COLUMN _source VARCHAR(255) NOT NULL DEFAULT '' AFTER _manual_tags"},
+				down: []string{"ALTER TABLE {satellites} DROP COLUMN _source"},
+			},
+			{
+				version:     9,
+				description: "create identifiers table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {identifiers} (" +
+					"_name VARCHAR(255) NOT NULL PRIMARY KEY, " +
+					"_norad_id INT NOT NULL, " +
+					"_cospar_id VARCHAR(16) NOT NULL, " +
+					"_satcat_name VARCHAR(255) NOT NULL, " +
+					"_confidence DOUBLE NOT NULL, " +
+					"_matched DATETIME NOT NULL, " +
+					"INDEX (_norad_id))"},
+				down: []string{"DROP TABLE {identifiers}"},
+			},
+		},
+	}
+
+	postgresDialect = &sqlDialect{
+		name:           "postgres",
+		title:          "PostgreSQL",
+		driver:         "postgres",
+		quoteChar:      `"`,
+		maxParams:      65535,
+		timestampParam: "CAST(? AS TIMESTAMP)",
+		upsertSatellites: "ON CONFLICT (_id) DO UPDATE SET " +
+			"_position = excluded._position, _url = excluded._url, _band = excluded._band, " +
+			"_tags = excluded._tags, _source = excluded._source",
+		upsertAnnotation: "ON CONFLICT (_name, _key) DO UPDATE SET _value = excluded._value, " +
+			"_updated = excluded._updated",
+		upsertIdentifier: "ON CONFLICT (_name) DO UPDATE SET _norad_id = excluded._norad_id, " +
+			"_cospar_id = excluded._cospar_id, _satcat_name = excluded._satcat_name, " +
+			"_confidence = excluded._confidence, _matched = excluded._matched",
+		migrations: []migration{
+			{
+				version:     1,
+				description: "create satellites table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {satellites} (" +
+					"_id SERIAL PRIMARY KEY, " +
+					"_name VARCHAR(255) NOT NULL, " +
+					"_position DOUBLE PRECISION NOT NULL, " +
+					"_url VARCHAR(255) NOT NULL, " +
+					"_band VARCHAR(64) NOT NULL, " +
+					"_tags VARCHAR(255) NOT NULL DEFAULT '', " +
+					"_status SMALLINT NOT NULL DEFAULT 1, " +
+					"_closed TIMESTAMP NULL)",
+					`CREATE INDEX IF NOT EXISTS "{table}_status_name_idx" ON {satellites} (_status, _name)`},
+				down: []string{"DROP TABLE {satellites}"},
+				legacy: []string{"ALTER TABLE {satellites} ADD COLUMN _id SERIAL PRIMARY KEY",
+					`CREATE INDEX IF NOT EXISTS "{table}_status_name_idx" ON {satellites} (_status, _name)`},
+			},
+			{
+				version:     2,
+				description: "create history table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {history} (" +
+					"_id BIGSERIAL PRIMARY KEY, " +
+					"_satellite_id INT NOT NULL, " +
+					"_field VARCHAR(32) NOT NULL, " +
+					"_old_value VARCHAR(255) NOT NULL, " +
+					"_new_value VARCHAR(255) NOT NULL, " +
+					"_run_id VARCHAR(64) NOT NULL, " +
+					"_changed TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+					`CREATE INDEX IF NOT EXISTS "{table}_history_satellite_idx" ON {history} (_satellite_id)`},
+				down: []string{"DROP TABLE {history}"},
+			},
+			{
+				version:     3,
+				description: "create relocations table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {relocations} (" +
+					"_id BIGSERIAL PRIMARY KEY, " +
+					"_satellite_id INT NOT NULL, " +
+					"_from_position DOUBLE PRECISION NOT NULL, " +
+					"_to_position DOUBLE PRECISION NOT NULL, " +
+					"_direction VARCHAR(8) NOT NULL, " +
+					"_run_id VARCHAR(64) NOT NULL, " +
+					"_detected TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+					`CREATE INDEX IF NOT EXISTS "{table}_relocations_satellite_idx" ON {relocations} (_satellite_id)`},
+				down: []string{"DROP TABLE {relocations}"},
+			},
+			{
+				version:     4,
+				description: "add manual tags column",
+				up:          []string{"ALTER TABLE {satellites} ADD COLUMN _manual_tags VARCHAR(255) NOT NULL DEFAULT ''"},
+				down:        []string{"ALTER TABLE {satellites} DROP COLUMN _manual_tags"},
+			},
+			{
+				version:     5,
+				description: "create versions table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {versions} (" +
+					"_id BIGSERIAL PRIMARY KEY, " +
+					"_satellite_id INT NOT NULL, " +
+					"_name VARCHAR(255) NOT NULL, " +
+					"_position DOUBLE PRECISION NOT NULL, " +
+					"_url VARCHAR(255) NOT NULL, " +
+					"_band VARCHAR(64) NOT NULL, " +
+					"_tags VARCHAR(255) NOT NULL DEFAULT '', " +
+					"_valid_from TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+					"_valid_to TIMESTAMP NULL)",
+					`CREATE INDEX IF NOT EXISTS "{table}_versions_satellite_idx" ON {versions} (_satellite_id)`,
+					`CREATE INDEX IF NOT EXISTS "{table}_versions_valid_from_idx" ON {versions} (_valid_from)`,
+					// versions are opened in UTC like all timestamps bound by sync, not in session time zone
+					"INSERT INTO {versions} (_satellite_id, _name, _position, _url, _band, _tags, _valid_from) " +
+						"SELECT _id, _name, _position, _url, _band, _tags, (now() AT TIME ZONE 'UTC') " +
+						"FROM {satellites} WHERE _status = 1"},
+				down: []string{"DROP TABLE {versions}"},
+			},
+			{
+				version:     6,
+				description: "create sync runs table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {runs} (" +
+					"_run_id VARCHAR(64) NOT NULL PRIMARY KEY, " +
+					"_started TIMESTAMP NOT NULL, " +
+					"_finished TIMESTAMP NOT NULL, " +
+					"_revision VARCHAR(64) NOT NULL, " +
+					"_config_hash VARCHAR(64) NOT NULL, " +
+					"_status VARCHAR(32) NOT NULL, " +
+					"_pages TEXT NOT NULL, " +
+					"_parsed INT NOT NULL, " +
+					"_parse_errors INT NOT NULL, " +
+					"_inserted INT NOT NULL, " +
+					"_closed INT NOT NULL, " +
+					"_updated INT NOT NULL, " +
+					"_relocated INT NOT NULL, " +
+					"_failed INT NOT NULL)",
+					`CREATE INDEX IF NOT EXISTS "{table}_sync_runs_started_idx" ON {runs} (_started)`},
+				down: []string{"DROP TABLE {runs}"},
+			},
+			{
+				version:     7,
+				description: "create annotations table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {annotations} (" +
+					"_name VARCHAR(255) NOT NULL, " +
+					"_key VARCHAR(64) NOT NULL, " +
+					"_value TEXT NOT NULL, " +
+					"_updated TIMESTAMP NOT NULL, " +
+					"PRIMARY KEY (_name, _key))"},
+				down: []string{"DROP TABLE {annotations}"},
+			},
+			{
+				version:     8,
+				description: "add source column",
+				up:          []string{"ALTER TABLE {satellites} ADD COLUMN _source VARCHAR(255) NOT NULL DEFAULT ''"},
+				down:        []string{"ALTER TABLE {satellites} DROP COLUMN _source"},
+			},
+			{
+				version:     9,
+				description: "create identifiers table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {identifiers} (" +
+					"_name VARCHAR(255) NOT NULL PRIMARY KEY, " +
+					"_norad_id INT NOT NULL, " +
+					"_cospar_id VARCHAR(16) NOT NULL, " +
+					"_satcat_name VARCHAR(255) NOT NULL, " +
+					"_confidence DOUBLE PRECISION NOT NULL, " +
+					"_matched TIMESTAMP NOT NULL)",
+					`CREATE INDEX IF NOT EXISTS "{table}_identifiers_norad_idx" ON {identifiers} (_norad_id)`},
+				down: []string{"DROP TABLE {identifiers}"},
+			},
+		},
+	}
+
+	sqliteDialect = &sqlDialect{
+		name:           "sqlite",
+		title:          "SQLite",
+		driver:         "sqlite3",
+		quoteChar:      `"`,
+		maxParams:      999,
+		timestampParam: "?",
+		upsertSatellites: "ON CONFLICT (_id) DO UPDATE SET " +
+			"_position = excluded._position, _url = excluded._url, _band = excluded._band, " +
+			"_tags = excluded._tags, _source = excluded._source",
+		upsertAnnotation: "ON CONFLICT (_name, _key) DO UPDATE SET _value = excluded._value, " +
+			"_updated = excluded._updated",
+		upsertIdentifier: "ON CONFLICT (_name) DO UPDATE SET _norad_id = excluded._norad_id, " +
+			"_cospar_id = excluded._cospar_id, _satcat_name = excluded._satcat_name, " +
+			"_confidence = excluded._confidence, _matched = excluded._matched",
+		migrations: []migration{
+			{
+				version:     1,
+				description: "create satellites table",
+				up:          []string{sqliteCreateSatellites, sqliteCreateSatellitesIndex},
+				down:        []string{"DROP TABLE {satellites}"},
+				// SQLite cannot add a primary key to existing table, so the table is rebuilt
+				legacy: []string{`ALTER TABLE {satellites} RENAME TO "{table}_legacy"`,
+					sqliteCreateSatellites,
+					"INSERT INTO {satellites} (_name, _position, _url, _band, _tags, _status, _closed) " +
+						`SELECT _name, _position, _url, _band, _tags, _status, _closed FROM "{table}_legacy" ` +
+						"ORDER BY rowid",
+					`DROP TABLE "{table}_legacy"`,
+					sqliteCreateSatellitesIndex},
+			},
+			{
+				version:     2,
+				description: "create history table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {history} (" +
+					"_id INTEGER PRIMARY KEY AUTOINCREMENT, " +
+					"_satellite_id INTEGER NOT NULL, " +
+					"_field TEXT NOT NULL, " +
+					"_old_value TEXT NOT NULL, " +
+					"_new_value TEXT NOT NULL, " +
+					"_run_id TEXT NOT NULL, " +
+					"_changed TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+					`CREATE INDEX IF NOT EXISTS "{table}_history_satellite_idx" ON {history} (_satellite_id)`},
+				down: []string{"DROP TABLE {history}"},
+			},
+			{
+				version:     3,
+				description: "create relocations table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {relocations} (" +
+					"_id INTEGER PRIMARY KEY AUTOINCREMENT, " +
+					"_satellite_id INTEGER NOT NULL, " +
+					"_from_position REAL NOT NULL, " +
+					"_to_position REAL NOT NULL, " +
+					"_direction TEXT NOT NULL, " +
+					"_run_id TEXT NOT NULL, " +
+					"_detected TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+					`CREATE INDEX IF NOT EXISTS "{table}_relocations_satellite_idx" ON {relocations} (_satellite_id)`},
+				down: []string{"DROP TABLE {relocations}"},
+			},
+			{
+				version:     4,
+				description: "add manual tags column",
+				up:          []string{"ALTER TABLE {satellites} ADD COLUMN _manual_tags TEXT NOT NULL DEFAULT ''"},
+				// SQLite cannot drop columns, so the table is rebuilt without the column
+				down: []string{`CREATE TABLE "{table}_rebuild" (` +
+					"_id INTEGER PRIMARY KEY AUTOINCREMENT, " +
+					"_name TEXT NOT NULL, " +
+					"_position REAL NOT NULL, " +
+					"_url TEXT NOT NULL, " +
+					"_band TEXT NOT NULL, " +
+					"_tags TEXT NOT NULL DEFAULT '', " +
+					"_status INTEGER NOT NULL DEFAULT 1, " +
+					"_closed TIMESTAMP NULL)",
+					`INSERT INTO "{table}_rebuild" (_id, _name, _position, _url, _band, _tags, _status, _closed) ` +
+						"SELECT _id, _name, _position, _url, _band, _tags, _status, _closed FROM {satellites}",
+					"DROP TABLE {satellites}",
+					`ALTER TABLE "{table}_rebuild" RENAME TO {satellites}`,
+					`CREATE INDEX IF NOT EXISTS "{table}_status_name_idx" ON {satellites} (_status, _name)`},
+			},
+			{
+				version:     5,
+				description: "create versions table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {versions} (" +
+					"_id INTEGER PRIMARY KEY AUTOINCREMENT, " +
+					"_satellite_id INTEGER NOT NULL, " +
+					"_name TEXT NOT NULL, " +
+					"_position REAL NOT NULL, " +
+					"_url TEXT NOT NULL, " +
+					"_band TEXT NOT NULL, " +
+					"_tags TEXT NOT NULL DEFAULT '', " +
+					"_valid_from TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+					"_valid_to TIMESTAMP NULL)",
+					`CREATE INDEX IF NOT EXISTS "{table}_versions_satellite_idx" ON {versions} (_satellite_id)`,
+					`CREATE INDEX IF NOT EXISTS "{table}_versions_valid_from_idx" ON {versions} (_valid_from)`,
+					// CURRENT_TIMESTAMP of SQLite is UTC like all timestamps bound by sync
+					"INSERT INTO {versions} (_satellite_id, _name, _position, _url, _band, _tags, _valid_from) " +
+						"SELECT _id, _name, _position, _url, _band, _tags, CURRENT_TIMESTAMP FROM {satellites} " +
+						"WHERE _status = 1"},
+				down: []string{"DROP TABLE {versions}"},
+			},
+			{
+				version:     6,
+				description: "create sync runs table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {runs} (" +
+					"_run_id VARCHAR(64) NOT NULL PRIMARY KEY, " +
+					"_started TIMESTAMP NOT NULL, " +
+					"_finished TIMESTAMP NOT NULL, " +
+					"_revision VARCHAR(64) NOT NULL, " +
+					"_config_hash VARCHAR(64) NOT NULL, " +
+					"_status VARCHAR(32) NOT NULL, " +
+					"_pages TEXT NOT NULL, " +
+					"_parsed INTEGER NOT NULL, " +
+					"_parse_errors INTEGER NOT NULL, " +
+					"_inserted INTEGER NOT NULL, " +
+					"_closed INTEGER NOT NULL, " +
+					"_updated INTEGER NOT NULL, " +
+					"_relocated INTEGER NOT NULL, " +
+					"_failed INTEGER NOT NULL)",
+					`CREATE INDEX IF NOT EXISTS "{table}_sync_runs_started_idx" ON {runs} (_started)`},
+				down: []string{"DROP TABLE {runs}"},
+			},
+			{
+				version:     7,
+				description: "create annotations table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {annotations} (" +
+					"_name TEXT NOT NULL, " +
+					"_key TEXT NOT NULL, " +
+					"_value TEXT NOT NULL, " +
+					// TEXT keeps the time as it is saved, the driver reformats TIMESTAMP values as RFC 3339
+					"_updated TEXT NOT NULL, " +
+					"PRIMARY KEY (_name, _key))"},
+				down: []string{"DROP TABLE {annotations}"},
+			},
+			{
+				version:     8,
+				description: "add source column",
+				up:          []string{"ALTER TABLE {satellites} ADD COLUMN _source TEXT NOT NULL DEFAULT ''"},
+				down: []string{`CREATE TABLE "{table}_rebuild" (` +
+					"_id INTEGER PRIMARY KEY AUTOINCREMENT, " +
+					"_name TEXT NOT NULL, " +
+					"_position REAL NOT NULL, " +
+					"_url TEXT NOT NULL, " +
+					"_band TEXT NOT NULL, " +
+					"_tags TEXT NOT NULL DEFAULT '', " +
+					"_status INTEGER NOT NULL DEFAULT 1, " +
+					"_closed TIMESTAMP NULL, " +
+					"_manual_tags TEXT NOT NULL DEFAULT '')",
+					`INSERT INTO "{table}_rebuild" ` +
+						"(_id, _name, _position, _url, _band, _tags, _status, _closed, _manual_tags) " +
+						"SELECT _id, _name, _position, _url, _band, _tags, _status, _closed, _manual_tags " +
+						"FROM {satellites}",
+					"DROP TABLE {satellites}",
+					`ALTER TABLE "{table}_rebuild" RENAME TO {satellites}`,
+					`CREATE INDEX IF NOT EXISTS "{table}_status_name_idx" ON {satellites} (_status, _name)`},
+			},
+			{
+				version:     9,
+				description: "create identifiers table",
+				up: []string{"CREATE TABLE IF NOT EXISTS {identifiers} (" +
+					"_name TEXT NOT NULL PRIMARY KEY, " +
+					"_norad_id INTEGER NOT NULL, " +
+					"_cospar_id TEXT NOT NULL, " +
+					"_satcat_name TEXT NOT NULL, " +
+					"_confidence REAL NOT NULL, " +
+					"_matched TEXT NOT NULL)",
+					`CREATE INDEX IF NOT EXISTS "{table}_identifiers_norad_idx" ON {identifiers} (_norad_id)`},
+				down: []string{"DROP TABLE {identifiers}"},
+			},
+		},
+	}
+)
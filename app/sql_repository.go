@@ -0,0 +1,715 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// sqlStatements holds queries of one SQL storage prepared for its dialect and table names.
+// Statements with %s are templates of multi-row statements, %s is replaced with row placeholders. updateSources
+// takes WHEN ? THEN ? groups and id placeholders.
+type sqlStatements struct {
+	selectActive      string
+	updateManualTags  string
+	updateSources     string
+	insertSatellites  string
+	closeSatellites   string
+	countActive       string
+	countActiveByName string
+	upsertSatellites  string
+	insertHistory     string
+	selectHistory     string
+	selectAll         string
+	selectAllHistory  string
+	insertRelocations string
+	selectRelocations string
+	insertNewVersions string
+	closeVersions     string
+	insertVersions    string
+	selectAsOf        string
+	selectVersions    string
+	insertRun         string
+	selectRuns        string
+	selectRun         string
+	selectAnnotations string
+	upsertAnnotation  string
+	deleteAnnotation  string
+	selectIdentifiers string
+	upsertIdentifier  string
+	savepoint         string
+	rollbackSavepoint string
+	releaseSavepoint  string
+
+	createMigrations string
+	selectMigrations string
+	insertMigration  string
+	deleteMigration  string
+	probeTable       string
+	probeIdentity    string
+}
+
+func newSQLStatements(dialect *sqlDialect, table string) *sqlStatements {
+	expander := dialect.expander(table)
+	expand := func(query string) string {
+		return dialect.rebind(expander.Replace(query))
+	}
+
+	return &sqlStatements{
+		selectActive: expand("SELECT _id, _position, _name, _url, _band, _tags, _manual_tags, _source " +
+			"FROM {satellites} " +
+			"WHERE _status = 1 ORDER BY _position, _name"),
+		updateManualTags: expand("UPDATE {satellites} SET _manual_tags = ? WHERE _status = 1 AND _name = ?"),
+		updateSources: expand("UPDATE {satellites} SET _source = CASE _id%s ELSE _source END " +
+			"WHERE _status = 1 AND _id IN (%s)"),
+		insertSatellites: expand("INSERT INTO {satellites} (_name, _position, _url, _band, _tags, _source) VALUES %s"),
+		closeSatellites: expand("UPDATE {satellites} SET _status = 0, _closed = ? " +
+			"WHERE _status = 1 AND _id IN (%s)"),
+		countActive:       expand("SELECT COUNT(*) FROM {satellites} WHERE _status = 1 AND _id IN (%s)"),
+		countActiveByName: expand("SELECT COUNT(*) FROM {satellites} WHERE _status = 1 AND _name = ?"),
+		upsertSatellites: expand("INSERT INTO {satellites} (_id, _name, _position, _url, _band, _tags, _source) " +
+			"VALUES %s " +
+			dialect.upsertSatellites),
+		insertHistory: expand("INSERT INTO {history} (_satellite_id, _field, _old_value, _new_value, _run_id, " +
+			"_changed) VALUES %s"),
+		selectHistory: expand("SELECT h._satellite_id, s._name, h._field, h._old_value, h._new_value, h._run_id, " +
+			"h._changed FROM {history} h JOIN {satellites} s ON s._id = h._satellite_id WHERE s._name = ? " +
+			"ORDER BY h._changed, h._id"),
+		selectAll: expand("SELECT _id, _position, _name, _url, _band, _tags, _manual_tags, _source, _status, _closed " +
+			"FROM {satellites} ORDER BY _position, _name, _id"),
+		selectAllHistory: expand("SELECT h._satellite_id, s._name, h._field, h._old_value, h._new_value, " +
+			"h._run_id, h._changed FROM {history} h JOIN {satellites} s ON s._id = h._satellite_id " +
+			"ORDER BY h._changed, h._id"),
+		insertRelocations: expand("INSERT INTO {relocations} (_satellite_id, _from_position, _to_position, " +
+			"_direction, _run_id, _detected) VALUES %s"),
+		selectRelocations: expand("SELECT r._satellite_id, s._name, r._from_position, r._to_position, r._direction, " +
+			"r._run_id, r._detected FROM {relocations} r JOIN {satellites} s ON s._id = r._satellite_id " +
+			"ORDER BY r._detected, r._id"),
+		insertNewVersions: expand("INSERT INTO {versions} (_satellite_id, _name, _position, _url, _band, _tags, " +
+			"_valid_from) SELECT _id, _name, _position, _url, _band, _tags, " + dialect.timestampParam + " " +
+			"FROM {satellites} s WHERE _status = 1 AND _name IN (%s) " +
+			"AND NOT EXISTS (SELECT 1 FROM {versions} v WHERE v._satellite_id = s._id AND v._valid_to IS NULL)"),
+		closeVersions: expand("UPDATE {versions} SET _valid_to = ? " +
+			"WHERE _valid_to IS NULL AND _satellite_id IN (%s)"),
+		insertVersions: expand("INSERT INTO {versions} (_satellite_id, _name, _position, _url, _band, _tags, " +
+			"_valid_from) VALUES %s"),
+		selectAsOf: expand("SELECT _satellite_id AS _id, _position, _name, _url, _band, _tags, _valid_from, " +
+			"_valid_to FROM {versions} WHERE _valid_from <= ? AND (_valid_to IS NULL OR _valid_to > ?) " +
+			"ORDER BY _position, _name"),
+		selectVersions: expand("SELECT _satellite_id AS _id, _position, _name, _url, _band, _tags, _valid_from, " +
+			"_valid_to FROM {versions} ORDER BY _satellite_id, _valid_from, _id"),
+		insertRun: expand("INSERT INTO {runs} (_run_id, _started, _finished, _revision, _config_hash, _status, " +
+			"_pages, _parsed, _parse_errors, _inserted, _closed, _updated, _relocated, _failed) VALUES (" +
+			":_run_id, :_started, :_finished, :_revision, :_config_hash, :_status, :_pages, :_parsed, " +
+			":_parse_errors, :_inserted, :_closed, :_updated, :_relocated, :_failed)"),
+		selectRuns:        expand("SELECT * FROM {runs} ORDER BY _started DESC, _run_id DESC LIMIT ?"),
+		selectRun:         expand("SELECT * FROM {runs} WHERE _run_id = ?"),
+		selectAnnotations: expand("SELECT _name, _key, _value, _updated FROM {annotations} ORDER BY _name, _key"),
+		upsertAnnotation: expand("INSERT INTO {annotations} (_name, _key, _value, _updated) VALUES (?, ?, ?, ?) " +
+			dialect.upsertAnnotation),
+		deleteAnnotation: expand("DELETE FROM {annotations} WHERE _name = ? AND _key = ?"),
+		selectIdentifiers: expand("SELECT _name, _norad_id, _cospar_id, _satcat_name, _confidence, _matched " +
+			"FROM {identifiers} ORDER BY _name"),
+		upsertIdentifier: expand("INSERT INTO {identifiers} (_name, _norad_id, _cospar_id, _satcat_name, " +
+			"_confidence, _matched) VALUES (?, ?, ?, ?, ?, ?) " + dialect.upsertIdentifier),
+		savepoint:         "SAVEPOINT sync_batch",
+		rollbackSavepoint: "ROLLBACK TO SAVEPOINT sync_batch",
+		releaseSavepoint:  "RELEASE SAVEPOINT sync_batch",
+
+		createMigrations: expand("CREATE TABLE IF NOT EXISTS {migrations} (" +
+			"_version INT NOT NULL PRIMARY KEY, " +
+			"_description VARCHAR(255) NOT NULL, " +
+			"_applied TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)"),
+		selectMigrations: expand("SELECT _version, _description, _applied FROM {migrations} ORDER BY _version"),
+		insertMigration:  expand("INSERT INTO {migrations} (_version, _description) VALUES (?, ?)"),
+		deleteMigration:  expand("DELETE FROM {migrations} WHERE _version = ?"),
+		probeTable:       expand("SELECT _name FROM {satellites} WHERE 1 = 0"),
+		probeIdentity:    expand("SELECT _id FROM {satellites} WHERE 1 = 0"),
+	}
+}
+
+// sqlRepository is a SatelliteRepository implementation for SQL databases.
+type sqlRepository struct {
+	db      *sqlx.DB
+	dialect *sqlDialect
+	table   string
+	stmts   *sqlStatements
+	timeout time.Duration
+}
+
+// openSQLRepository opens connection pool and pings database until it is available or connect attempts run out.
+func openSQLRepository(dialect *sqlDialect, properties *DatabaseProperties) (*sqlRepository, error) {
+	log.Infof("opening connection to %s ...", dialect.title)
+
+	table := properties.Table
+	if table == "" {
+		table = defaultTable
+	}
+
+	db, err := sqlx.Open(dialect.driver, properties.URL)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(int(properties.MaxOpenConns))
+	db.SetMaxIdleConns(int(properties.MaxIdleConns))
+	db.SetConnMaxLifetime(time.Duration(properties.ConnMaxLifetimeSeconds) * time.Second)
+
+	repository := &sqlRepository{
+		db:      db,
+		dialect: dialect,
+		table:   table,
+		stmts:   newSQLStatements(dialect, table),
+		timeout: time.Duration(properties.QueryTimeoutSeconds) * time.Second,
+	}
+
+	backoff := time.Duration(properties.ConnectBackoffSeconds) * time.Second
+	if err := retry(int(properties.ConnectAttempts), backoff, repository.ping); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("cannot connect to %s: %w", dialect.title, err)
+	}
+
+	if _, err := db.Exec(repository.stmts.createMigrations); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("cannot create migrations table: %w", err)
+	}
+
+	log.Infof("connection to %s created", dialect.title)
+	return repository, nil
+}
+
+// retry calls fn until it succeeds or attempts run out, the delay between attempts starts with backoff and doubles
+// after every failed attempt. The last error is returned if all attempts fail.
+func retry(attempts int, backoff time.Duration, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= attempts {
+			return err
+		}
+
+		log.WithError(err).Warnf("attempt %d out of %d failed, retrying in %s ...", attempt, attempts, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// queryContext returns context of one query limited by timeout, zero timeout means no limit.
+func queryContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+func (ptr *sqlRepository) ping() error {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+	return ptr.db.PingContext(ctx)
+}
+
+// LoadActive returns all active satellites ordered by position and name.
+func (ptr *sqlRepository) LoadActive() ([]Satellite, error) {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	var satellites []Satellite
+	if err := ptr.db.SelectContext(ctx, &satellites, ptr.stmts.selectActive); err != nil {
+		return nil, err
+	}
+
+	annotations, err := ptr.LoadAnnotations()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load annotations: %w", err)
+	}
+	attachAnnotations(satellites, annotations)
+	return satellites, nil
+}
+
+// LoadHistory returns field changes of satellites with given name ordered by time.
+func (ptr *sqlRepository) LoadHistory(name string) ([]HistoryRecord, error) {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	var records []HistoryRecord
+	if err := ptr.db.SelectContext(ctx, &records, ptr.stmts.selectHistory, name); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// LoadAll returns active and closed satellites ordered by position and name.
+func (ptr *sqlRepository) LoadAll() ([]StoredSatellite, error) {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	var satellites []StoredSatellite
+	if err := ptr.db.SelectContext(ctx, &satellites, ptr.stmts.selectAll); err != nil {
+		return nil, err
+	}
+	return satellites, nil
+}
+
+// LoadAllHistory returns field changes of all satellites ordered by time.
+func (ptr *sqlRepository) LoadAllHistory() ([]HistoryRecord, error) {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	var records []HistoryRecord
+	if err := ptr.db.SelectContext(ctx, &records, ptr.stmts.selectAllHistory); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// LoadRelocations returns relocations of all satellites ordered by detection time.
+func (ptr *sqlRepository) LoadRelocations() ([]Relocation, error) {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	var relocations []Relocation
+	if err := ptr.db.SelectContext(ctx, &relocations, ptr.stmts.selectRelocations); err != nil {
+		return nil, err
+	}
+	return relocations, nil
+}
+
+// LoadAsOf returns versions of satellites which were active at the moment ordered by position and name.
+func (ptr *sqlRepository) LoadAsOf(moment time.Time) ([]SatelliteVersion, error) {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	var versions []SatelliteVersion
+	at := formatMoment(moment)
+	if err := ptr.db.SelectContext(ctx, &versions, ptr.stmts.selectAsOf, at, at); err != nil {
+		return nil, err
+	}
+
+	annotations, err := ptr.LoadAnnotations()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load annotations: %w", err)
+	}
+	attachVersionAnnotations(versions, annotations)
+	return versions, nil
+}
+
+// LoadVersions returns all versions of all satellites ordered by satellite id and time.
+func (ptr *sqlRepository) LoadVersions() ([]SatelliteVersion, error) {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	var versions []SatelliteVersion
+	if err := ptr.db.SelectContext(ctx, &versions, ptr.stmts.selectVersions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// SaveRun saves log record of a sync run.
+func (ptr *sqlRepository) SaveRun(run *SyncRun) error {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	_, err := ptr.db.NamedExecContext(ctx, ptr.stmts.insertRun, run)
+	return err
+}
+
+// LoadRuns returns given count of the latest sync runs, the latest first.
+func (ptr *sqlRepository) LoadRuns(limit int) ([]SyncRun, error) {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	var runs []SyncRun
+	if err := ptr.db.SelectContext(ctx, &runs, ptr.stmts.selectRuns, limit); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// LoadRun returns sync run with given id, nil if there is no such run.
+func (ptr *sqlRepository) LoadRun(runID string) (*SyncRun, error) {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	var runs []SyncRun
+	if err := ptr.db.SelectContext(ctx, &runs, ptr.stmts.selectRun, runID); err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, nil
+	}
+	return &runs[0], nil
+}
+
+// SaveManualTags replaces manual tags of active satellites with given name. MySQL does not count rows whose tags
+// are already set as affected, so the satellite is looked for by a separate count if no rows are affected.
+func (ptr *sqlRepository) SaveManualTags(name string, tags []string) error {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	result, err := ptr.db.ExecContext(ctx, ptr.stmts.updateManualTags, formatTags(tags), name)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+
+	var active int64
+	if err := ptr.db.QueryRowxContext(ctx, ptr.stmts.countActiveByName, name).Scan(&active); err != nil {
+		return err
+	}
+	if active == 0 {
+		return fmt.Errorf("active satellite %s not found", name)
+	}
+	return nil
+}
+
+// LoadAnnotations returns annotations of all satellites ordered by name and key.
+func (ptr *sqlRepository) LoadAnnotations() ([]Annotation, error) {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	var annotations []Annotation
+	if err := ptr.db.SelectContext(ctx, &annotations, ptr.stmts.selectAnnotations); err != nil {
+		return nil, err
+	}
+	return annotations, nil
+}
+
+// SaveAnnotation inserts annotation or replaces value of existing one.
+func (ptr *sqlRepository) SaveAnnotation(annotation Annotation) error {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	_, err := ptr.db.ExecContext(ctx, ptr.stmts.upsertAnnotation, annotation.Name, annotation.Key, annotation.Value,
+		annotation.Updated)
+	return err
+}
+
+// DeleteAnnotation removes annotation with given key of satellites with given name.
+func (ptr *sqlRepository) DeleteAnnotation(name, key string) (bool, error) {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	result, err := ptr.db.ExecContext(ctx, ptr.stmts.deleteAnnotation, name, key)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// LoadIdentifiers returns catalogue identifiers of all satellites ordered by name.
+func (ptr *sqlRepository) LoadIdentifiers() ([]SatelliteIdentifier, error) {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+
+	var identifiers []SatelliteIdentifier
+	if err := ptr.db.SelectContext(ctx, &identifiers, ptr.stmts.selectIdentifiers); err != nil {
+		return nil, err
+	}
+	return identifiers, nil
+}
+
+// SaveIdentifiers inserts identifiers or replaces existing ones with the same names within one transaction.
+func (ptr *sqlRepository) SaveIdentifiers(identifiers []SatelliteIdentifier) error {
+	tx, err := ptr.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, identifier := range identifiers {
+		ctx, cancel := queryContext(ptr.timeout)
+		_, err := tx.ExecContext(ctx, ptr.stmts.upsertIdentifier, identifier.Name, identifier.NoradID,
+			identifier.CosparID, identifier.SatcatName, identifier.Confidence, identifier.Matched)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("cannot save identifier of %s: %w", identifier.Name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Begin starts SQL transaction. The transaction itself has no timeout, every its statement is limited alone.
+func (ptr *sqlRepository) Begin() (SatelliteTx, error) {
+	tx, err := ptr.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{tx: tx, dialect: ptr.dialect, stmts: ptr.stmts, timeout: ptr.timeout,
+		moment: formatMoment(time.Now())}, nil
+}
+
+// sqlTx is a SatelliteTx implementation, every batch is changed inside a savepoint to be rolled back alone.
+type sqlTx struct {
+	tx      *sqlx.Tx
+	dialect *sqlDialect
+	stmts   *sqlStatements
+	timeout time.Duration
+	// moment is the UTC time of all changes of the transaction, it is bound from here instead of CURRENT_TIMESTAMP
+	// which depends on the session time zone, so a closed version ends exactly when the next one starts
+	moment string
+}
+
+// exec executes one statement limited by query timeout.
+func (ptr *sqlTx) exec(query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := queryContext(ptr.timeout)
+	defer cancel()
+	return ptr.tx.ExecContext(ctx, query, args...)
+}
+
+// execBatch runs fn inside a savepoint and rolls back to it if fn fails.
+func (ptr *sqlTx) execBatch(fn func() error) error {
+	if _, err := ptr.exec(ptr.stmts.savepoint); err != nil {
+		return fmt.Errorf("cannot create savepoint: %w", err)
+	}
+
+	if err := fn(); err != nil {
+		if _, rollbackErr := ptr.exec(ptr.stmts.rollbackSavepoint); rollbackErr != nil {
+			return fmt.Errorf("%v, cannot roll back to savepoint: %w", err, rollbackErr)
+		}
+		return err
+	}
+
+	if _, err := ptr.exec(ptr.stmts.releaseSavepoint); err != nil {
+		return fmt.Errorf("cannot release savepoint: %w", err)
+	}
+	return nil
+}
+
+// placeholders returns placeholders group for one row, e.g. (?, ?, ?).
+func placeholders(count int) string {
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", count), ", ") + ")"
+}
+
+// forEachRange splits count rows of rowParams parameters into ranges whose parameters together with args
+// parameters of the statement fit the dialect limit and calls fn for every range.
+func (ptr *sqlTx) forEachRange(count, rowParams, args int, fn func(from, to int) error) error {
+	chunkSize := (ptr.dialect.maxParams - args) / rowParams
+	for from := 0; from < count; from += chunkSize {
+		to := from + chunkSize
+		if to > count {
+			to = count
+		}
+		if err := fn(from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forEachChunk splits rows into chunks whose parameters fit the dialect limit and calls fn with the multi-row
+// statement template filled for every chunk and parameters of the chunk preceded by args of the template.
+func (ptr *sqlTx) forEachChunk(template, rowPlaceholder string, rows [][]interface{}, args []interface{},
+	fn func(query string, args []interface{}) error) error {
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return ptr.forEachRange(len(rows), len(rows[0]), len(args), func(from, to int) error {
+		groups := make([]string, 0, to-from)
+		chunkArgs := make([]interface{}, 0, len(args)+(to-from)*len(rows[0]))
+		chunkArgs = append(chunkArgs, args...)
+		for _, row := range rows[from:to] {
+			groups = append(groups, rowPlaceholder)
+			chunkArgs = append(chunkArgs, row...)
+		}
+
+		return fn(ptr.dialect.rebind(fmt.Sprintf(template, strings.Join(groups, ", "))), chunkArgs)
+	})
+}
+
+// execValues executes multi-row statement template for all rows and returns count of affected rows. Rows are
+// split into several statements if their parameters do not fit the dialect limit, args are parameters of the
+// template which precede the rows in every statement.
+func (ptr *sqlTx) execValues(template, rowPlaceholder string, rows [][]interface{},
+	args ...interface{}) (int64, error) {
+
+	var affected int64
+	err := ptr.forEachChunk(template, rowPlaceholder, rows, args, func(query string, args []interface{}) error {
+		result, err := ptr.exec(query, args...)
+		if err != nil {
+			return err
+		}
+
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		affected += count
+		return nil
+	})
+	return affected, err
+}
+
+// countValues executes count query template for all rows and returns the sum of counts. Rows are split into
+// several queries if their parameters do not fit the dialect limit.
+func (ptr *sqlTx) countValues(template, rowPlaceholder string, rows [][]interface{}) (int64, error) {
+	var total int64
+	err := ptr.forEachChunk(template, rowPlaceholder, rows, nil, func(query string, args []interface{}) error {
+		ctx, cancel := queryContext(ptr.timeout)
+		defer cancel()
+
+		var count int64
+		if err := ptr.tx.QueryRowxContext(ctx, query, args...).Scan(&count); err != nil {
+			return err
+		}
+		total += count
+		return nil
+	})
+	return total, err
+}
+
+// Insert adds new active satellites with one multi-row statement and opens their first versions.
+func (ptr *sqlTx) Insert(list []Satellite) error {
+	rows := make([][]interface{}, 0, len(list))
+	names := make([][]interface{}, 0, len(list))
+	for _, sat := range list {
+		rows = append(rows, []interface{}{sat.Name, sat.Position, sat.URL, sat.Band, sat.Tags, sat.Source})
+		names = append(names, []interface{}{sat.Name})
+	}
+
+	return ptr.execBatch(func() error {
+		if _, err := ptr.execValues(ptr.stmts.insertSatellites, placeholders(6), rows); err != nil {
+			return err
+		}
+		// ids of inserted rows are unknown, so versions are copied from the new satellites found by names
+		if _, err := ptr.execValues(ptr.stmts.insertNewVersions, "?", names, ptr.moment); err != nil {
+			return fmt.Errorf("cannot open versions: %w", err)
+		}
+		return nil
+	})
+}
+
+// Close marks active satellites closed by id list and closes their current versions.
+func (ptr *sqlTx) Close(list []Satellite) error {
+	rows := make([][]interface{}, 0, len(list))
+	for _, sat := range list {
+		rows = append(rows, []interface{}{sat.ID})
+	}
+
+	return ptr.execBatch(func() error {
+		affected, err := ptr.execValues(ptr.stmts.closeSatellites, "?", rows, ptr.moment)
+		if err != nil {
+			return err
+		}
+		if affected != int64(len(list)) {
+			return fmt.Errorf("only %d out of %d active satellites found", affected, len(list))
+		}
+		if _, err := ptr.execValues(ptr.stmts.closeVersions, "?", rows, ptr.moment); err != nil {
+			return fmt.Errorf("cannot close versions: %w", err)
+		}
+		return nil
+	})
+}
+
+// Update upserts new values of active satellites by their ids, replaces their current versions with new ones and
+// saves history records of changed fields. The upsert would insert missing satellites and rewrite closed ones, so
+// the batch fails unless all its satellites are active.
+func (ptr *sqlTx) Update(pairs [][]Satellite) error {
+	rows := make([][]interface{}, 0, len(pairs))
+	versionRows := make([][]interface{}, 0, len(pairs))
+	ids := make([][]interface{}, 0, len(pairs))
+	var historyRows [][]interface{}
+	for _, pair := range pairs {
+		oldSat, newSat := pair[0], pair[1]
+		rows = append(rows, []interface{}{oldSat.ID, newSat.Name, newSat.Position, newSat.URL, newSat.Band,
+			newSat.Tags, newSat.Source})
+		versionRows = append(versionRows, []interface{}{oldSat.ID, newSat.Name, newSat.Position, newSat.URL,
+			newSat.Band, newSat.Tags, ptr.moment})
+		ids = append(ids, []interface{}{oldSat.ID})
+		for _, record := range DiffSatellites(&oldSat, &newSat, &getProperties().Comparison) {
+			historyRows = append(historyRows, []interface{}{
+				record.SatelliteID, record.Field, record.OldValue, record.NewValue, record.RunID, ptr.moment})
+		}
+	}
+
+	return ptr.execBatch(func() error {
+		active, err := ptr.countValues(ptr.stmts.countActive, "?", ids)
+		if err != nil {
+			return err
+		}
+		if active != int64(len(pairs)) {
+			return fmt.Errorf("only %d out of %d active satellites found", active, len(pairs))
+		}
+		if _, err := ptr.execValues(ptr.stmts.upsertSatellites, placeholders(7), rows); err != nil {
+			return err
+		}
+		if _, err := ptr.execValues(ptr.stmts.closeVersions, "?", ids, ptr.moment); err != nil {
+			return fmt.Errorf("cannot close versions: %w", err)
+		}
+		if _, err := ptr.execValues(ptr.stmts.insertVersions, placeholders(7), versionRows); err != nil {
+			return fmt.Errorf("cannot open versions: %w", err)
+		}
+		if _, err := ptr.execValues(ptr.stmts.insertHistory, placeholders(6), historyRows); err != nil {
+			return fmt.Errorf("cannot save history records: %w", err)
+		}
+		return nil
+	})
+}
+
+// SaveSources sets sources of active satellites by their ids with one statement, which is split into several ones
+// if its parameters do not fit the dialect limit. MySQL does not count rows whose source is already set as
+// affected, so the batch fails unless all its satellites are found active by a separate count.
+func (ptr *sqlTx) SaveSources(list []Satellite) error {
+	ids := make([][]interface{}, 0, len(list))
+	for _, sat := range list {
+		ids = append(ids, []interface{}{sat.ID})
+	}
+
+	return ptr.execBatch(func() error {
+		active, err := ptr.countValues(ptr.stmts.countActive, "?", ids)
+		if err != nil {
+			return err
+		}
+		if active != int64(len(list)) {
+			return fmt.Errorf("only %d out of %d active satellites found", active, len(list))
+		}
+
+		// every satellite takes WHEN id THEN source parameters and id parameter of IN list
+		return ptr.forEachRange(len(list), 3, 0, func(from, to int) error {
+			args := make([]interface{}, 0, (to-from)*3)
+			for _, sat := range list[from:to] {
+				args = append(args, sat.ID, sat.Source)
+			}
+			for _, sat := range list[from:to] {
+				args = append(args, sat.ID)
+			}
+
+			query := fmt.Sprintf(ptr.stmts.updateSources, strings.Repeat(" WHEN ? THEN ?", to-from),
+				strings.TrimSuffix(strings.Repeat("?, ", to-from), ", "))
+			_, err := ptr.exec(ptr.dialect.rebind(query), args...)
+			return err
+		})
+	})
+}
+
+// SaveRelocations saves relocations of active satellites.
+func (ptr *sqlTx) SaveRelocations(list []Relocation) error {
+	rows := make([][]interface{}, 0, len(list))
+	for _, relocation := range list {
+		rows = append(rows, []interface{}{relocation.SatelliteID, relocation.FromPosition, relocation.ToPosition,
+			relocation.Direction, relocation.RunID, ptr.moment})
+	}
+
+	return ptr.execBatch(func() error {
+		_, err := ptr.execValues(ptr.stmts.insertRelocations, placeholders(6), rows)
+		return err
+	})
+}
+
+// Commit commits SQL transaction.
+func (ptr *sqlTx) Commit() error {
+	return ptr.tx.Commit()
+}
+
+// Rollback rolls back SQL transaction.
+func (ptr *sqlTx) Rollback() error {
+	return ptr.tx.Rollback()
+}
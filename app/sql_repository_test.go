@@ -0,0 +1,359 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestRepository(t *testing.T) (*sqlRepository, func()) {
+	dir, err := ioutil.TempDir("", "sat-parser")
+	require.NoError(t, err)
+
+	repository, err := openSQLRepository(sqliteDialect,
+		&DatabaseProperties{URL: filepath.Join(dir, "test.db"), Table: "satellites"})
+	require.NoError(t, err)
+	require.NoError(t, checkSchema(repository, true))
+
+	return repository, func() {
+		_ = repository.db.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func syncTestRepository(t testing.TB, repository SatelliteRepository, onlineList []Satellite, atomic bool,
+	batchSize int) *SyncStatus {
+
+	dbList, err := repository.LoadActive()
+	require.NoError(t, err)
+	return ApplySyncPlan(repository, MakeSyncPlan(&dbList, &onlineList), atomic, batchSize)
+}
+
+// loadTestActive loads active satellites without storage ids to compare them with parsed ones.
+func loadTestActive(t *testing.T, repository SatelliteRepository) []Satellite {
+	active, err := repository.LoadActive()
+	require.NoError(t, err)
+	for i := range active {
+		assert.NotZero(t, active[i].ID)
+		active[i].ID = 0
+	}
+	return active
+}
+
+func TestSQLRepositorySync(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	status := syncTestRepository(t, repository, []Satellite{makeSat("one", 1), makeSat("two", 2)}, true, 100)
+	assert.True(t, status.Succeeded())
+	assert.Equal(t, 2, status.Inserted)
+
+	moved := makeSat("two", 20)
+	moved.SetBand("Ku")
+	status = syncTestRepository(t, repository, []Satellite{moved, makeSat("three", 3)}, true, 100)
+	assert.True(t, status.Succeeded())
+	assert.Equal(t, SyncStatus{Inserted: 1, Closed: 1, Updated: 1, Relocated: 1}, *status)
+
+	assert.Equal(t, []Satellite{makeSat("three", 3), moved}, loadTestActive(t, repository))
+
+	history, err := repository.LoadHistory("two")
+	require.NoError(t, err)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, fieldPosition, history[0].Field)
+		assert.Equal(t, "2", history[0].OldValue)
+		assert.Equal(t, "20", history[0].NewValue)
+		assert.Equal(t, runID, history[0].RunID)
+		assert.NotEmpty(t, history[0].Changed)
+		assert.Equal(t, fieldBand, history[1].Field)
+	}
+
+	relocations, err := repository.LoadRelocations()
+	require.NoError(t, err)
+	if assert.Len(t, relocations, 1) {
+		assert.Equal(t, "two", relocations[0].Name)
+		assert.Equal(t, 2.0, relocations[0].FromPosition)
+		assert.Equal(t, 20.0, relocations[0].ToPosition)
+		assert.Equal(t, driftEast, relocations[0].Direction)
+		assert.NotEmpty(t, relocations[0].Detected)
+	}
+}
+
+func TestSQLRepositoryAtomicRollback(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	plan := &SyncPlan{
+		Inserts: []Satellite{makeSat("one", 1)},
+		Closes:  []Satellite{makeSat("missing", 2)},
+	}
+
+	status := ApplySyncPlan(repository, plan, true, 100)
+	assert.False(t, status.Succeeded())
+	assert.True(t, status.RolledBack)
+	assert.Equal(t, 1, status.Failed)
+
+	assert.Empty(t, loadTestActive(t, repository), "all changes must be rolled back")
+}
+
+func TestSQLRepositoryPartialCommit(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	plan := &SyncPlan{
+		Inserts: []Satellite{makeSat("one", 1)},
+		Closes:  []Satellite{makeSat("missing", 2)},
+	}
+
+	status := ApplySyncPlan(repository, plan, false, 100)
+	assert.False(t, status.Succeeded())
+	assert.False(t, status.RolledBack)
+	assert.Equal(t, 1, status.Inserted)
+	assert.Equal(t, 1, status.Failed)
+
+	assert.Equal(t, []Satellite{makeSat("one", 1)}, loadTestActive(t, repository),
+		"succeeded rows must be committed")
+}
+
+func TestSQLRepositoryFailedBatchRetriedRowByRow(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	status := syncTestRepository(t, repository,
+		[]Satellite{makeSat("one", 1), makeSat("two", 2), makeSat("three", 3)}, true, 100)
+	require.True(t, status.Succeeded())
+
+	closes, err := repository.LoadActive()
+	require.NoError(t, err)
+	missing := makeSat("missing", 4)
+	missing.ID = 1000
+	closes = append(closes[:1], missing, closes[1])
+
+	status = ApplySyncPlan(repository, &SyncPlan{Closes: closes}, false, 10)
+	assert.Equal(t, 2, status.Closed)
+	assert.Equal(t, 1, status.Failed)
+
+	assert.Equal(t, []Satellite{makeSat("three", 3)}, loadTestActive(t, repository))
+}
+
+func TestSQLRepositoryUpdateOfInactiveSatellites(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	require.True(t, syncTestRepository(t, repository, []Satellite{makeSat("one", 1), makeSat("two", 2)}, true,
+		100).Succeeded())
+	require.True(t, syncTestRepository(t, repository, []Satellite{makeSat("one", 1)}, true, 100).Succeeded())
+	all, err := repository.LoadAll()
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	active, closed := all[0].Satellite, all[1].Satellite
+	require.False(t, all[1].IsActive())
+	missing := makeSat("missing", 3)
+	missing.ID = 1000
+
+	update := func(oldSat Satellite) [][]Satellite {
+		newSat := oldSat
+		newSat.SetBand("Ku")
+		return [][]Satellite{{oldSat, newSat}}
+	}
+	plan := &SyncPlan{Updates: append(append(update(active), update(closed)...), update(missing)...)}
+	status := ApplySyncPlan(repository, plan, false, 10)
+	assert.Equal(t, 1, status.Updated)
+	assert.Equal(t, 2, status.Failed)
+
+	all, err = repository.LoadAll()
+	require.NoError(t, err)
+	require.Len(t, all, 2, "missing satellite must not be inserted")
+	assert.Equal(t, "Ku", all[0].GetBand())
+	assert.Empty(t, all[1].GetBand(), "closed satellite must not be rewritten")
+	assert.False(t, all[1].IsActive())
+}
+
+func TestSQLTxFailedBatchRolledBackToSavepoint(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	require.True(t, syncTestRepository(t, repository, []Satellite{makeSat("one", 1), makeSat("two", 2)}, true,
+		100).Succeeded())
+	active, err := repository.LoadActive()
+	require.NoError(t, err)
+	missing := makeSat("missing", 3)
+	missing.ID = 1000
+
+	tx, err := repository.Begin()
+	require.NoError(t, err)
+	assert.Error(t, tx.Close([]Satellite{active[0], missing}))
+	assert.NoError(t, tx.Insert([]Satellite{makeSat("three", 3)}), "failed batch must not break the transaction")
+	require.NoError(t, tx.Commit())
+
+	assert.Equal(t, []Satellite{makeSat("one", 1), makeSat("two", 2), makeSat("three", 3)},
+		loadTestActive(t, repository), "rows of the failed batch must be rolled back")
+}
+
+func TestSQLRepositoryParamsLimit(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	var onlineList []Satellite
+	for i := 0; i < 500; i++ {
+		onlineList = append(onlineList, makeSat(fmt.Sprintf("sat %03d", i), float64(i%360)))
+	}
+
+	status := syncTestRepository(t, repository, onlineList, true, 500)
+	require.True(t, status.Succeeded(), "statements must be split to fit SQLite parameters limit")
+	assert.Equal(t, 500, status.Inserted)
+
+	for i := range onlineList {
+		onlineList[i].SetSource(fmt.Sprintf("page %d", i%3))
+	}
+	status = syncTestRepository(t, repository, onlineList, true, 500)
+	require.True(t, status.Succeeded(), "sources must be split to fit SQLite parameters limit")
+	assert.ElementsMatch(t, onlineList, loadTestActive(t, repository), "every satellite must get its own source")
+}
+
+func benchmarkSQLRepositorySync(b *testing.B, batchSize int) {
+	log.SetLevel(log.WarnLevel)
+	dir, err := ioutil.TempDir("", "sat-parser")
+	require.NoError(b, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	repository, err := openSQLRepository(sqliteDialect, &DatabaseProperties{URL: filepath.Join(dir, "bench.db")})
+	require.NoError(b, err)
+	defer func() { _ = repository.db.Close() }()
+	require.NoError(b, checkSchema(repository, true))
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		// every iteration inserts 1000 new satellites, updates and closes the ones of the previous iteration
+		var onlineList []Satellite
+		for i := 0; i < 1000; i++ {
+			onlineList = append(onlineList, makeSat(fmt.Sprintf("sat %d-%d", n, i), float64(i%360)))
+			if n > 0 {
+				onlineList = append(onlineList, makeSat(fmt.Sprintf("sat %d-%d", n-1, i), float64(i%360)+0.1))
+			}
+		}
+
+		status := syncTestRepository(b, repository, onlineList, true, batchSize)
+		require.True(b, status.Succeeded())
+	}
+}
+
+func BenchmarkSQLRepositorySyncRowByRow(b *testing.B) {
+	benchmarkSQLRepositorySync(b, 1)
+}
+
+func BenchmarkSQLRepositorySyncBatched(b *testing.B) {
+	benchmarkSQLRepositorySync(b, 100)
+}
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	err := retry(3, time.Millisecond, func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("not ready")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	calls = 0
+	err = retry(3, time.Millisecond, func() error {
+		calls++
+		return errors.New("not ready")
+	})
+	assert.EqualError(t, err, "not ready")
+	assert.Equal(t, 3, calls, "attempts must be bounded")
+}
+
+func TestOpenSQLRepositoryUnavailable(t *testing.T) {
+	_, err := openSQLRepository(sqliteDialect, &DatabaseProperties{
+		URL: filepath.Join(os.TempDir(), "sat-parser-missing", "dir", "test.db"), ConnectAttempts: 2})
+	assert.Error(t, err, "unavailable storage must be detected at startup")
+}
+
+func TestSQLRepositoryTags(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	tagged := makeSat("one", 1)
+	tagged.SetTags([]string{"inclined"})
+	status := syncTestRepository(t, repository, []Satellite{tagged}, true, 100)
+	require.True(t, status.Succeeded())
+	require.NoError(t, repository.SaveManualTags("one", []string{"mine"}))
+	assert.NoError(t, repository.SaveManualTags("one", []string{"mine"}), "unchanged tags must be saved")
+	assert.EqualError(t, repository.SaveManualTags("missing", []string{"mine"}), "active satellite missing not found")
+
+	status = syncTestRepository(t, repository, []Satellite{makeSat("one", 1)}, true, 100)
+	require.True(t, status.Succeeded())
+	assert.Equal(t, 1, status.Updated, "tags change must be saved as field change")
+
+	active := loadTestActive(t, repository)
+	if assert.Len(t, active, 1) {
+		assert.Empty(t, active[0].GetTags())
+		assert.Equal(t, []string{"mine"}, active[0].GetManualTags(), "sync must keep manual tags")
+	}
+
+	records, err := repository.LoadHistory("one")
+	require.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, fieldTags, records[0].Field)
+		assert.Equal(t, "inclined", records[0].OldValue)
+	}
+}
+
+func TestSQLRepositorySources(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	status := syncTestRepository(t, repository, []Satellite{makeSourcedSat("one", 1, "asia")}, true, 100)
+	require.True(t, status.Succeeded())
+
+	status = syncTestRepository(t, repository, []Satellite{makeSourcedSat("one", 1, "europe")}, true, 100)
+	assert.Equal(t, SyncStatus{}, *status, "moving to another page is not a change")
+	assert.Equal(t, []Satellite{makeSourcedSat("one", 1, "europe")}, loadTestActive(t, repository))
+
+	records, err := repository.LoadHistory("one")
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestSQLRepositoryUpdateWithSourceChange(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	onlineList := []Satellite{makeSourcedSat("one", 1, "asia"), makeSourcedSat("two", 2, "asia")}
+	require.True(t, syncTestRepository(t, repository, onlineList, true, 100).Succeeded())
+
+	moved := makeSourcedSat("one", 1, "europe")
+	moved.SetBand("Ku")
+	onlineList = []Satellite{moved, makeSourcedSat("two", 2, "europe")}
+	status := syncTestRepository(t, repository, onlineList, true, 100)
+	assert.Equal(t, SyncStatus{Updated: 1}, *status, "changed satellite moved to another page must be updated once")
+	assert.Equal(t, onlineList, loadTestActive(t, repository))
+}
+
+func TestSQLTxSaveUnchangedSources(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	require.True(t, syncTestRepository(t, repository, []Satellite{makeSourcedSat("one", 1, "asia")}, true,
+		100).Succeeded())
+	active, err := repository.LoadActive()
+	require.NoError(t, err)
+
+	tx, err := repository.Begin()
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+	assert.NoError(t, tx.SaveSources(active), "unchanged sources of active satellites must be saved")
+
+	active[0].ID += 100
+	assert.EqualError(t, tx.SaveSources(active), "only 0 out of 1 active satellites found")
+}
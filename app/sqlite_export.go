@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// sqliteExportVersion is a version of the exported file schema, it is changed when tables or views change.
+const sqliteExportVersion = "1"
+
+// SQLiteSnapshot holds storage content exported to a standalone SQLite file. LastRun is nil if there were no
+// sync runs.
+type SQLiteSnapshot struct {
+	Exported    time.Time
+	Storage     string
+	LastRun     *SyncRun
+	Satellites  []StoredSatellite
+	History     []HistoryRecord
+	Relocations []Relocation
+	Versions    []SatelliteVersion
+	Runs        []SyncRun
+	Annotations []Annotation
+	Identifiers []SatelliteIdentifier
+}
+
+// sqliteExportSchema creates tables, indexes and views of the exported file. The schema does not depend on the
+// storage schema, so the file stays readable with plain SQL after storage migrations.
+var sqliteExportSchema = []string{
+	"CREATE TABLE metadata (" +
+		"key TEXT PRIMARY KEY, " +
+		"value TEXT NOT NULL)",
+	"CREATE TABLE satellites (" +
+		"id INTEGER PRIMARY KEY, " +
+		"name TEXT NOT NULL, " +
+		"position REAL NOT NULL, " +
+		"url TEXT NOT NULL, " +
+		"band TEXT NOT NULL, " +
+		"region TEXT NOT NULL, " +
+		"source TEXT NOT NULL, " +
+		"tags TEXT NOT NULL, " +
+		"manual_tags TEXT NOT NULL, " +
+		"active INTEGER NOT NULL, " +
+		"closed TEXT NULL)",
+	"CREATE TABLE history (" +
+		"id INTEGER PRIMARY KEY AUTOINCREMENT, " +
+		"satellite_id INTEGER NOT NULL REFERENCES satellites (id), " +
+		"field TEXT NOT NULL, " +
+		"old_value TEXT NOT NULL, " +
+		"new_value TEXT NOT NULL, " +
+		"run_id TEXT NOT NULL, " +
+		"changed TEXT NOT NULL)",
+	"CREATE TABLE relocations (" +
+		"id INTEGER PRIMARY KEY AUTOINCREMENT, " +
+		"satellite_id INTEGER NOT NULL REFERENCES satellites (id), " +
+		"from_position REAL NOT NULL, " +
+		"to_position REAL NOT NULL, " +
+		"direction TEXT NOT NULL, " +
+		"run_id TEXT NOT NULL, " +
+		"detected TEXT NOT NULL)",
+	"CREATE TABLE versions (" +
+		"id INTEGER PRIMARY KEY AUTOINCREMENT, " +
+		"satellite_id INTEGER NOT NULL REFERENCES satellites (id), " +
+		"name TEXT NOT NULL, " +
+		"position REAL NOT NULL, " +
+		"url TEXT NOT NULL, " +
+		"band TEXT NOT NULL, " +
+		"tags TEXT NOT NULL, " +
+		"valid_from TEXT NOT NULL, " +
+		"valid_to TEXT NULL)",
+	"CREATE TABLE runs (" +
+		"run_id TEXT PRIMARY KEY, " +
+		"started TEXT NOT NULL, " +
+		"finished TEXT NOT NULL, " +
+		"revision TEXT NOT NULL, " +
+		"config_hash TEXT NOT NULL, " +
+		"status TEXT NOT NULL, " +
+		"pages TEXT NOT NULL, " +
+		"parsed INTEGER NOT NULL, " +
+		"parse_errors INTEGER NOT NULL, " +
+		"inserted INTEGER NOT NULL, " +
+		"closed INTEGER NOT NULL, " +
+		"updated INTEGER NOT NULL, " +
+		"relocated INTEGER NOT NULL, " +
+		"failed INTEGER NOT NULL)",
+	// annotations and identifiers belong to satellite names, like in storage, so they survive close and
+	// reinsert of a satellite
+	"CREATE TABLE annotations (" +
+		"name TEXT NOT NULL, " +
+		"key TEXT NOT NULL, " +
+		"value TEXT NOT NULL, " +
+		"updated TEXT NOT NULL, " +
+		"PRIMARY KEY (name, key))",
+	"CREATE TABLE identifiers (" +
+		"name TEXT PRIMARY KEY, " +
+		"norad_id INTEGER NOT NULL, " +
+		"cospar_id TEXT NOT NULL, " +
+		"satcat_name TEXT NOT NULL, " +
+		"confidence REAL NOT NULL, " +
+		"matched TEXT NOT NULL)",
+	"CREATE TABLE tags (" +
+		"satellite_id INTEGER NOT NULL REFERENCES satellites (id), " +
+		"tag TEXT NOT NULL, " +
+		"manual INTEGER NOT NULL, " +
+		"PRIMARY KEY (satellite_id, tag, manual))",
+
+	"CREATE INDEX satellites_name_idx ON satellites (name)",
+	"CREATE INDEX satellites_position_idx ON satellites (position)",
+	"CREATE INDEX satellites_region_idx ON satellites (region)",
+	"CREATE INDEX history_satellite_idx ON history (satellite_id)",
+	"CREATE INDEX history_changed_idx ON history (changed)",
+	"CREATE INDEX relocations_satellite_idx ON relocations (satellite_id)",
+	"CREATE INDEX versions_satellite_idx ON versions (satellite_id)",
+	"CREATE INDEX versions_valid_idx ON versions (valid_from, valid_to)",
+	"CREATE INDEX tags_tag_idx ON tags (tag)",
+
+	"CREATE VIEW satellite_details AS " +
+		"SELECT s.id, s.name, s.position, s.url, s.band, s.region, s.tags, s.manual_tags, " +
+		"(SELECT group_concat(key || '=' || value, '; ') FROM " +
+		"(SELECT key, value FROM annotations a WHERE a.name = s.name ORDER BY key)) AS annotations, " +
+		"i.norad_id, i.cospar_id, s.active, s.closed FROM satellites s LEFT JOIN identifiers i ON i.name = s.name",
+	"CREATE VIEW active_satellites AS " +
+		"SELECT id, name, position, url, band, region, tags, manual_tags, annotations, norad_id, cospar_id " +
+		"FROM satellite_details WHERE active = 1 ORDER BY position, name",
+	"CREATE VIEW closed_satellites AS " +
+		"SELECT id, name, position, url, band, region, tags, manual_tags, annotations, norad_id, cospar_id, closed " +
+		"FROM satellite_details WHERE active = 0 ORDER BY closed DESC, name",
+	"CREATE VIEW satellite_annotations AS " +
+		"SELECT a.key, a.value, a.updated, s.id AS satellite_id, s.name, s.position, s.active FROM annotations a " +
+		"JOIN satellites s ON s.name = a.name ORDER BY s.name, a.key, s.id",
+	"CREATE VIEW satellite_tags AS " +
+		"SELECT t.tag, t.manual, s.id AS satellite_id, s.name, s.position, s.active FROM tags t " +
+		"JOIN satellites s ON s.id = t.satellite_id ORDER BY t.tag, s.position, s.name",
+	"CREATE VIEW recent_changes AS " +
+		"SELECT h.changed, s.name, s.position, h.field, h.old_value, h.new_value, h.run_id FROM history h " +
+		"JOIN satellites s ON s.id = h.satellite_id ORDER BY h.changed DESC, h.id DESC",
+	"CREATE VIEW recent_relocations AS " +
+		"SELECT r.detected, s.name, r.from_position, r.to_position, r.direction, r.run_id FROM relocations r " +
+		"JOIN satellites s ON s.id = r.satellite_id ORDER BY r.detected DESC, r.id DESC",
+}
+
+// normalizeMoment returns storage timestamp in the common layout, storages return them differently, e.g. SQLite
+// driver returns RFC 3339 ones. Unknown values are returned as is.
+func normalizeMoment(value string) string {
+	moment, err := ParseMoment(value)
+	if err != nil {
+		return value
+	}
+	return formatMoment(moment)
+}
+
+// metadata returns key and value pairs of the metadata table.
+func (ptr *SQLiteSnapshot) metadata() [][2]string {
+	rows := [][2]string{
+		{"format_version", sqliteExportVersion},
+		{"exported", formatMoment(ptr.Exported)},
+		{"revision", revision},
+		{"storage", ptr.Storage},
+		{"satellites", strconv.Itoa(len(ptr.Satellites))},
+		{"history_records", strconv.Itoa(len(ptr.History))},
+		{"relocations", strconv.Itoa(len(ptr.Relocations))},
+		{"versions", strconv.Itoa(len(ptr.Versions))},
+		{"runs", strconv.Itoa(len(ptr.Runs))},
+		{"annotations", strconv.Itoa(len(ptr.Annotations))},
+		{"identifiers", strconv.Itoa(len(ptr.Identifiers))},
+	}
+	if ptr.LastRun != nil {
+		rows = append(rows, [2]string{"last_run_id", ptr.LastRun.RunID},
+			[2]string{"last_run_started", normalizeMoment(ptr.LastRun.Started)},
+			[2]string{"last_run_status", ptr.LastRun.Status})
+	}
+	return rows
+}
+
+// WriteSQLiteExport writes the snapshot into a new SQLite file, an existing file is replaced when the new one
+// is complete. History records, relocations and versions of satellites which are not in the snapshot are skipped.
+func WriteSQLiteExport(path string, snapshot *SQLiteSnapshot) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tempPath := file.Name()
+	defer func() { _ = os.Remove(tempPath) }()
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	db, err := sqlx.Open(sqliteDialect.driver, tempPath)
+	if err != nil {
+		return err
+	}
+	if err := writeSQLiteSnapshot(db, snapshot); err != nil {
+		_ = db.Close()
+		return fmt.Errorf("cannot write %s: %w", path, err)
+	}
+	if err := db.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
+}
+
+// writeSQLiteSnapshot creates the schema and fills it within one transaction.
+func writeSQLiteSnapshot(db *sqlx.DB, snapshot *SQLiteSnapshot) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, statement := range sqliteExportSchema {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("cannot create schema: %w", err)
+		}
+	}
+
+	for _, row := range snapshot.metadata() {
+		if _, err := tx.Exec("INSERT INTO metadata (key, value) VALUES (?, ?)", row[0], row[1]); err != nil {
+			return fmt.Errorf("cannot save metadata: %w", err)
+		}
+	}
+
+	exported := make(map[int64]bool, len(snapshot.Satellites))
+	for i := range snapshot.Satellites {
+		sat := &snapshot.Satellites[i]
+		var closed *string
+		if sat.Closed != nil {
+			value := normalizeMoment(*sat.Closed)
+			closed = &value
+		}
+		if _, err := tx.Exec("INSERT INTO satellites (id, name, position, url, band, region, source, tags, "+
+			"manual_tags, active, closed) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", sat.ID, sat.Name, sat.Position,
+			sat.URL, sat.Band, sat.GetRegion(), sat.Source, sat.Tags, sat.ManualTags, sat.IsActive(),
+			closed); err != nil {
+			return fmt.Errorf("cannot save satellite %s: %w", sat.Name, err)
+		}
+		exported[sat.ID] = true
+
+		for _, tags := range []struct {
+			list   []string
+			manual bool
+		}{{sat.GetTags(), false}, {sat.GetManualTags(), true}} {
+			for _, tag := range tags.list {
+				if _, err := tx.Exec("INSERT INTO tags (satellite_id, tag, manual) VALUES (?, ?, ?)", sat.ID, tag,
+					tags.manual); err != nil {
+					return fmt.Errorf("cannot save tags of %s: %w", sat.Name, err)
+				}
+			}
+		}
+	}
+
+	for _, record := range snapshot.History {
+		if !exported[record.SatelliteID] {
+			continue
+		}
+		if _, err := tx.Exec("INSERT INTO history (satellite_id, field, old_value, new_value, run_id, changed) "+
+			"VALUES (?, ?, ?, ?, ?, ?)", record.SatelliteID, record.Field, record.OldValue, record.NewValue,
+			record.RunID, normalizeMoment(record.Changed)); err != nil {
+			return fmt.Errorf("cannot save history of %s: %w", record.Name, err)
+		}
+	}
+
+	for _, relocation := range snapshot.Relocations {
+		if !exported[relocation.SatelliteID] {
+			continue
+		}
+		if _, err := tx.Exec("INSERT INTO relocations (satellite_id, from_position, to_position, direction, run_id, "+
+			"detected) VALUES (?, ?, ?, ?, ?, ?)", relocation.SatelliteID, relocation.FromPosition,
+			relocation.ToPosition, relocation.Direction, relocation.RunID,
+			normalizeMoment(relocation.Detected)); err != nil {
+			return fmt.Errorf("cannot save relocation of %s: %w", relocation.Name, err)
+		}
+	}
+
+	for _, version := range snapshot.Versions {
+		if !exported[version.ID] {
+			continue
+		}
+		var validTo *string
+		if version.ValidTo != nil {
+			value := normalizeMoment(*version.ValidTo)
+			validTo = &value
+		}
+		if _, err := tx.Exec("INSERT INTO versions (satellite_id, name, position, url, band, tags, valid_from, "+
+			"valid_to) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", version.ID, version.Name, version.Position, version.URL,
+			version.Band, version.Tags, normalizeMoment(version.ValidFrom), validTo); err != nil {
+			return fmt.Errorf("cannot save version of %s: %w", version.Name, err)
+		}
+	}
+
+	for _, run := range snapshot.Runs {
+		if _, err := tx.Exec("INSERT INTO runs (run_id, started, finished, revision, config_hash, status, pages, "+
+			"parsed, parse_errors, inserted, closed, updated, relocated, failed) "+
+			"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", run.RunID, normalizeMoment(run.Started),
+			normalizeMoment(run.Finished), run.Revision, run.ConfigHash, run.Status, run.Pages, run.Parsed,
+			run.ParseErrors, run.Inserted, run.Closed, run.Updated, run.Relocated, run.Failed); err != nil {
+			return fmt.Errorf("cannot save run %s: %w", run.RunID, err)
+		}
+	}
+
+	for _, annotation := range snapshot.Annotations {
+		if _, err := tx.Exec("INSERT INTO annotations (name, key, value, updated) VALUES (?, ?, ?, ?)",
+			annotation.Name, annotation.Key, annotation.Value, normalizeMoment(annotation.Updated)); err != nil {
+			return fmt.Errorf("cannot save annotation %s of %s: %w", annotation.Key, annotation.Name, err)
+		}
+	}
+
+	for _, identifier := range snapshot.Identifiers {
+		if _, err := tx.Exec("INSERT INTO identifiers (name, norad_id, cospar_id, satcat_name, confidence, matched) "+
+			"VALUES (?, ?, ?, ?, ?, ?)", identifier.Name, identifier.NoradID, identifier.CosparID,
+			identifier.SatcatName, identifier.Confidence, normalizeMoment(identifier.Matched)); err != nil {
+			return fmt.Errorf("cannot save identifier of %s: %w", identifier.Name, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LoadSQLiteSnapshot loads all satellites with their history, relocations, versions, annotations and identifiers
+// and all sync runs from storage.
+func LoadSQLiteSnapshot() *SQLiteSnapshot {
+	log.Info("loading all satellites, history and sync runs from storage ...")
+
+	repository := getRepository()
+	snapshot := &SQLiteSnapshot{Exported: time.Now(), Storage: getProperties().Storage.Driver,
+		Relocations: LoadAllRelocations(), Runs: LoadSyncRuns(math.MaxInt32)}
+	var err error
+	if snapshot.Satellites, err = repository.LoadAll(); err != nil {
+		log.WithError(err).Fatal("critical error, shutting down ...")
+	}
+	if snapshot.History, err = repository.LoadAllHistory(); err != nil {
+		log.WithError(err).Fatal("critical error, shutting down ...")
+	}
+	if snapshot.Versions, err = repository.LoadVersions(); err != nil {
+		log.WithError(err).Fatal("critical error, shutting down ...")
+	}
+	if snapshot.Annotations, err = repository.LoadAnnotations(); err != nil {
+		log.WithError(err).Fatal("critical error, shutting down ...")
+	}
+	if snapshot.Identifiers, err = repository.LoadIdentifiers(); err != nil {
+		log.WithError(err).Fatal("critical error, shutting down ...")
+	}
+
+	if snapshot.Storage == "" {
+		snapshot.Storage = mysqlDialect.name
+	}
+	if len(snapshot.Runs) > 0 {
+		snapshot.LastRun = &snapshot.Runs[0]
+	}
+
+	log.Infof("snapshot loading finished. %d satellites, %d history records, %d versions and %d runs loaded",
+		len(snapshot.Satellites), len(snapshot.History), len(snapshot.Versions), len(snapshot.Runs))
+	return snapshot
+}
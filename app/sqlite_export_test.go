@@ -0,0 +1,122 @@
+package main
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteSQLiteExport(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	tagged := makeSourcedSat("one", 1, "https://www.lyngsat.com/europe.html")
+	tagged.SetTags([]string{"ku-europe"})
+	require.True(t, syncTestRepository(t, repository, []Satellite{tagged, makeSat("two", 2)}, true, 100).Succeeded())
+	require.NoError(t, repository.SaveManualTags("one", []string{"mine"}))
+	tagged.SetBand("Ku")
+	require.True(t, syncTestRepository(t, repository, []Satellite{tagged}, true, 100).Succeeded())
+
+	satellites, err := repository.LoadAll()
+	require.NoError(t, err)
+	history, err := repository.LoadAllHistory()
+	require.NoError(t, err)
+	require.Len(t, satellites, 2)
+	require.Len(t, history, 1)
+	versions, err := repository.LoadVersions()
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+
+	dir, err := ioutil.TempDir("", "sat-parser")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "export.sqlite")
+	runs := []SyncRun{{RunID: "run-1", Started: "2020-01-02T00:00:00Z", Status: runSucceeded, Updated: 1}}
+	snapshot := &SQLiteSnapshot{Exported: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), Storage: "sqlite",
+		LastRun: &runs[0], Runs: runs, Versions: versions,
+		Annotations: []Annotation{
+			{Name: "one", Key: "owner", Value: "Eutelsat", Updated: "2020-01-02T00:00:00Z"},
+			{Name: "one", Key: "dish", Value: "90cm", Updated: "2020-01-02T00:00:00Z"},
+		},
+		Identifiers: []SatelliteIdentifier{{Name: "one", NoradID: 12345, CosparID: "2020-001A", SatcatName: "ONE",
+			Confidence: 0.9, Matched: "2020-01-02T00:00:00Z"}},
+		Satellites: satellites, History: history, Relocations: []Relocation{
+			{SatelliteID: satellites[0].ID, Name: "one", FromPosition: 5, ToPosition: 1, Direction: driftWest,
+				RunID: "run-1", Detected: "2020-01-02T00:00:01Z"},
+			{SatelliteID: 1000, Name: "gone", FromPosition: 1, ToPosition: 5, Direction: driftEast, RunID: "run-1"},
+		}}
+	require.NoError(t, WriteSQLiteExport(path, snapshot))
+	require.NoError(t, WriteSQLiteExport(path, snapshot), "existing file must be replaced")
+
+	db, err := sqlx.Open(sqliteDialect.driver, path)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	var metadata []struct {
+		Key   string `db:"key"`
+		Value string `db:"value"`
+	}
+	require.NoError(t, db.Select(&metadata, "SELECT key, value FROM metadata ORDER BY key"))
+	values := make(map[string]string)
+	for _, row := range metadata {
+		values[row.Key] = row.Value
+	}
+	assert.Equal(t, "2020-01-02 03:04:05", values["exported"])
+	assert.Equal(t, revision, values["revision"])
+	assert.Equal(t, "2020-01-02 00:00:00", values["last_run_started"])
+	assert.Equal(t, "3", values["versions"])
+	assert.Equal(t, "2", values["annotations"])
+
+	var active []string
+	require.NoError(t, db.Select(&active, "SELECT name || ' ' || band || ' ' || region || ' ' || annotations || "+
+		"' ' || norad_id || ' ' || cospar_id FROM active_satellites"))
+	assert.Equal(t, []string{"one Ku europe dish=90cm; owner=Eutelsat 12345 2020-001A"}, active)
+
+	var annotations []string
+	require.NoError(t, db.Select(&annotations, "SELECT name || ' ' || key || '=' || value || ' ' || updated "+
+		"FROM satellite_annotations"))
+	assert.Equal(t, []string{"one dish=90cm 2020-01-02 00:00:00", "one owner=Eutelsat 2020-01-02 00:00:00"},
+		annotations)
+
+	var exportedVersions []string
+	require.NoError(t, db.Select(&exportedVersions, "SELECT name || ' ' || band || ' ' || (valid_to IS NULL) "+
+		"FROM versions ORDER BY satellite_id, id"))
+	assert.Equal(t, []string{"one  0", "one Ku 1", "two  0"}, exportedVersions)
+
+	var exportedRuns []string
+	require.NoError(t, db.Select(&exportedRuns, "SELECT run_id || ' ' || started || ' ' || updated FROM runs"))
+	assert.Equal(t, []string{"run-1 2020-01-02 00:00:00 1"}, exportedRuns)
+
+	var closed []string
+	require.NoError(t, db.Select(&closed, "SELECT name FROM closed_satellites WHERE closed IS NOT NULL"))
+	assert.Equal(t, []string{"two"}, closed)
+
+	var tags []string
+	require.NoError(t, db.Select(&tags, "SELECT tag FROM satellite_tags WHERE name = 'one'"))
+	assert.Equal(t, []string{"ku-europe", "mine"}, tags)
+
+	var changes []string
+	require.NoError(t, db.Select(&changes, "SELECT field || ':' || old_value || '>' || new_value FROM recent_changes"))
+	assert.Equal(t, []string{"band:>Ku"}, changes)
+
+	var relocations []string
+	require.NoError(t, db.Select(&relocations, "SELECT detected || ' ' || name || ' ' || from_position || '>' || "+
+		"to_position || ' ' || direction FROM recent_relocations"))
+	assert.Equal(t, []string{"2020-01-02 00:00:01 one 5.0>1.0 west"}, relocations,
+		"relocations of satellites which are not exported must be skipped")
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	require.NoError(t, err)
+	assert.Empty(t, files, "temporary files must be removed")
+}
+
+func TestNormalizeMoment(t *testing.T) {
+	assert.Equal(t, "2020-01-02 03:04:05", normalizeMoment("2020-01-02T03:04:05Z"))
+	assert.Equal(t, "2020-01-02 03:04:05", normalizeMoment("2020-01-02 03:04:05"))
+	assert.Equal(t, "yesterday", normalizeMoment("yesterday"))
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// SatelliteRepository is a storage of satellites and their change history.
+type SatelliteRepository interface {
+	// LoadActive returns all active satellites ordered by position and name.
+	LoadActive() ([]Satellite, error)
+	// LoadHistory returns field changes of satellites with given name ordered by time.
+	LoadHistory(name string) ([]HistoryRecord, error)
+	// LoadAll returns active and closed satellites ordered by position and name, without annotations.
+	LoadAll() ([]StoredSatellite, error)
+	// LoadAllHistory returns field changes of all satellites ordered by time.
+	LoadAllHistory() ([]HistoryRecord, error)
+	// LoadRelocations returns relocations of all satellites ordered by detection time.
+	LoadRelocations() ([]Relocation, error)
+	// LoadAsOf returns versions of satellites which were active at the moment ordered by position and name.
+	LoadAsOf(moment time.Time) ([]SatelliteVersion, error)
+	// LoadVersions returns all versions of all satellites ordered by satellite id and time, without annotations.
+	LoadVersions() ([]SatelliteVersion, error)
+	// SaveRun saves log record of a sync run.
+	SaveRun(run *SyncRun) error
+	// LoadRuns returns given count of the latest sync runs, the latest first.
+	LoadRuns(limit int) ([]SyncRun, error)
+	// LoadRun returns sync run with given id, nil if there is no such run.
+	LoadRun(runID string) (*SyncRun, error)
+	// SaveManualTags replaces manual tags of active satellites with given name, sync never changes them.
+	SaveManualTags(name string, tags []string) error
+	// LoadAnnotations returns annotations of all satellites ordered by name and key. Active satellites and
+	// versions are loaded with their annotations already.
+	LoadAnnotations() ([]Annotation, error)
+	// SaveAnnotation sets annotation value of satellites with given name, sync never changes annotations.
+	SaveAnnotation(annotation Annotation) error
+	// DeleteAnnotation removes annotation with given key of satellites with given name, returns false if there
+	// is no such annotation.
+	DeleteAnnotation(name, key string) (bool, error)
+	// LoadIdentifiers returns catalogue identifiers of all satellites ordered by name.
+	LoadIdentifiers() ([]SatelliteIdentifier, error)
+	// SaveIdentifiers replaces catalogue identifiers of satellites with given names, sync never changes them.
+	SaveIdentifiers(identifiers []SatelliteIdentifier) error
+	// Begin starts a transaction, all changes of one sync are applied within it.
+	Begin() (SatelliteTx, error)
+}
+
+// StoredSatellite is a stored satellite with its status, closed satellites keep their closing time.
+type StoredSatellite struct {
+	Satellite
+	Status int     `db:"_status"`
+	Closed *string `db:"_closed"`
+}
+
+// IsActive returns true if the satellite is not closed.
+func (ptr *StoredSatellite) IsActive() bool {
+	return ptr.Status == 1
+}
+
+// SatelliteTx is a storage transaction. Every method changes a batch of rows, a failed method leaves no changes
+// behind and does not break the transaction, so the rest of the batches can still be committed.
+type SatelliteTx interface {
+	// Insert adds new active satellites and opens their first versions.
+	Insert(list []Satellite) error
+	// Close marks active satellites closed and closes their current versions.
+	Close(list []Satellite) error
+	// Update sets new values to active satellites, replaces their current versions with new ones and saves history
+	// records of changed fields.
+	Update(pairs [][]Satellite) error
+	// SaveSources sets sources of active satellites without new versions and history records, the page
+	// a satellite is listed on is not its field.
+	SaveSources(list []Satellite) error
+	// SaveRelocations saves relocations of active satellites.
+	SaveRelocations(list []Relocation) error
+	Commit() error
+	Rollback() error
+}
+
+var (
+	repositoryPtr SatelliteRepository
+)
+
+// getRepository opens storage chosen by storage.driver setting if needed, checks its schema and returns it.
+func getRepository() SatelliteRepository {
+	if repositoryPtr == nil {
+		repository := getUncheckedRepository()
+		if err := checkSchema(repository, getProperties().Storage.AutoMigrate); err != nil {
+			log.WithError(err).Fatal("critical error, shutting down ...")
+		}
+	}
+	return repositoryPtr
+}
+
+// getUncheckedRepository opens storage chosen by storage.driver setting if needed and returns it without schema
+// check, it is used to migrate schema.
+func getUncheckedRepository() SatelliteRepository {
+	if repositoryPtr == nil {
+		repository, err := openRepository(getProperties().Storage.Driver)
+		if err != nil {
+			log.WithError(err).Fatal("critical error, shutting down ...")
+		}
+		repositoryPtr = repository
+	}
+	return repositoryPtr
+}
+
+func openRepository(driver string) (SatelliteRepository, error) {
+	switch driver {
+	case "", mysqlDialect.name:
+		return openSQLRepository(mysqlDialect, &getProperties().Mysql)
+	case postgresDialect.name:
+		return openSQLRepository(postgresDialect, &getProperties().Postgres)
+	case sqliteDialect.name:
+		return openSQLRepository(sqliteDialect, &getProperties().Sqlite)
+	case "file":
+		return openFileRepository(&getProperties().File)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %s, available drivers: mysql, postgres, sqlite, file", driver)
+	}
+}
+
+// LoadDbSatellites loads all active satellite items from storage.
+func LoadDbSatellites() []Satellite {
+	log.Info("loading satellites from storage ...")
+
+	satellites, err := getRepository().LoadActive()
+	if err != nil {
+		log.WithError(err).Fatal("critical error, shutting down ...")
+	}
+
+	log.Infof("DB loading finished. %d satellites loaded", len(satellites))
+
+	return satellites
+}
+
+// LoadSatelliteHistory loads all field changes of satellites with given name from storage ordered by time.
+func LoadSatelliteHistory(name string) []HistoryRecord {
+	log.Infof("loading history of %s from storage ...", name)
+
+	records, err := getRepository().LoadHistory(name)
+	if err != nil {
+		log.WithError(err).Fatal("critical error, shutting down ...")
+	}
+
+	log.Infof("history loading finished. %d records loaded", len(records))
+
+	return records
+}
+
+// LoadAllRelocations loads relocations of all satellites from storage ordered by detection time.
+func LoadAllRelocations() []Relocation {
+	relocations, err := getRepository().LoadRelocations()
+	if err != nil {
+		log.WithError(err).Fatal("critical error, shutting down ...")
+	}
+	return relocations
+}
+
+// LoadCatalogueAsOf loads versions of satellites which were active at the moment from storage.
+func LoadCatalogueAsOf(moment time.Time) []SatelliteVersion {
+	log.Infof("loading satellites active at %s from storage ...", formatMoment(moment))
+
+	versions, err := getRepository().LoadAsOf(moment)
+	if err != nil {
+		log.WithError(err).Fatal("critical error, shutting down ...")
+	}
+
+	log.Infof("versions loading finished. %d versions loaded", len(versions))
+
+	return versions
+}
+
+// SaveSyncRun finishes the run with given status unless its status is set and saves it to storage. Failed saving
+// does not stop the tool, the run log is informational.
+func SaveSyncRun(run *SyncRun, status string) {
+	run.Finish(status)
+	if err := getRepository().SaveRun(run); err != nil {
+		log.WithError(err).Error("cannot save sync run")
+	}
+}
+
+// LoadSyncRuns loads given count of the latest sync runs from storage.
+func LoadSyncRuns(limit int) []SyncRun {
+	runs, err := getRepository().LoadRuns(limit)
+	if err != nil {
+		log.WithError(err).Fatal("critical error, shutting down ...")
+	}
+	return runs
+}
+
+// LoadSyncRun loads sync run with given id from storage, nil if there is no such run.
+func LoadSyncRun(runID string) *SyncRun {
+	run, err := getRepository().LoadRun(runID)
+	if err != nil {
+		log.WithError(err).Fatal("critical error, shutting down ...")
+	}
+	return run
+}
+
+// LoadSatelliteAnnotations loads annotations of satellites with given name from storage ordered by key.
+func LoadSatelliteAnnotations(name string) []Annotation {
+	annotations, err := getRepository().LoadAnnotations()
+	if err != nil {
+		log.WithError(err).Fatal("critical error, shutting down ...")
+	}
+
+	var found []Annotation
+	for _, annotation := range annotations {
+		if annotation.Name == name {
+			found = append(found, annotation)
+		}
+	}
+	return found
+}
+
+// SyncStatus holds counts of succeeded and failed rows of one sync run.
+type SyncStatus struct {
+	Inserted   int
+	Closed     int
+	Updated    int
+	Relocated  int
+	Failed     int
+	Errors     []error
+	RolledBack bool
+}
+
+// Succeeded returns true if all rows were applied and committed.
+func (ptr *SyncStatus) Succeeded() bool {
+	return ptr.Failed == 0 && !ptr.RolledBack
+}
+
+func (ptr *SyncStatus) fail(err error) {
+	ptr.Failed++
+	ptr.Errors = append(ptr.Errors, err)
+}
+
+// ApplySyncPlan applies all changes of the plan in a single transaction by batches of batchSize rows. A failed
+// batch is retried row by row, so a failed row is rolled back alone without breaking the transaction. When atomic
+// is true any failed row rolls back the whole transaction, otherwise succeeded rows are committed.
+func ApplySyncPlan(repository SatelliteRepository, plan *SyncPlan, atomic bool, batchSize int) *SyncStatus {
+	log.Info("applying changes to storage ...")
+
+	status := &SyncStatus{}
+	tx, err := repository.Begin()
+	if err != nil {
+		status.fail(fmt.Errorf("cannot begin transaction: %w", err))
+		status.RolledBack = true
+		return status
+	}
+
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	insertSatellites(tx, &plan.Inserts, batchSize, status)
+	markSatellitesClosed(tx, &plan.Closes, batchSize, status)
+	updateSatellites(tx, &plan.Updates, batchSize, status)
+	insertRelocations(tx, &plan.Relocations, batchSize, status)
+	saveSources(tx, &plan.Sources, batchSize, status)
+
+	if atomic && status.Failed > 0 {
+		log.Errorf("%d rows failed, rolling back all changes ...", status.Failed)
+		if err := tx.Rollback(); err != nil {
+			log.WithError(err).Error("cannot roll back transaction")
+		}
+		status.RolledBack = true
+		return status
+	}
+
+	if err := tx.Commit(); err != nil {
+		status.fail(fmt.Errorf("cannot commit transaction: %w", err))
+		status.RolledBack = true
+	}
+	return status
+}
+
+// applyBatches calls apply for every batch of rows in range [0, size) and returns count of applied rows. A failed
+// batch is retried row by row to find failed rows, every failed row is reported to fail.
+func applyBatches(size, batchSize int, apply func(from, to int) error, fail func(i int, err error)) int {
+	count := 0
+	for from := 0; from < size; from += batchSize {
+		to := from + batchSize
+		if to > size {
+			to = size
+		}
+
+		err := apply(from, to)
+		if err == nil {
+			count += to - from
+			continue
+		}
+
+		if to-from == 1 {
+			fail(from, err)
+			continue
+		}
+
+		log.WithError(err).Warnf("batch of %d rows failed, retrying row by row ...", to-from)
+		for i := from; i < to; i++ {
+			if err := apply(i, i+1); err != nil {
+				fail(i, err)
+				continue
+			}
+			count++
+		}
+	}
+	return count
+}
+
+func insertSatellites(tx SatelliteTx, list *[]Satellite, batchSize int, status *SyncStatus) {
+	log.Info("inserting satellites ...")
+
+	count := applyBatches(len(*list), batchSize, func(from, to int) error {
+		log.Debugf("inserting %v", (*list)[from:to])
+		return tx.Insert((*list)[from:to])
+	}, func(i int, err error) {
+		sat := (*list)[i]
+		log.WithError(err).Errorf("cannot insert satellite %v", sat)
+		status.fail(fmt.Errorf("cannot insert satellite %s: %w", sat.GetName(), err))
+	})
+	status.Inserted += count
+	log.Infof("inserting satellites finished. %d out of %d inserted", count, len(*list))
+}
+
+func markSatellitesClosed(tx SatelliteTx, list *[]Satellite, batchSize int, status *SyncStatus) {
+	log.Info("marking satellites closed ...")
+
+	count := applyBatches(len(*list), batchSize, func(from, to int) error {
+		log.Debugf("marking %v", (*list)[from:to])
+		return tx.Close((*list)[from:to])
+	}, func(i int, err error) {
+		sat := (*list)[i]
+		log.WithError(err).Errorf("cannot mark satellite %v", sat)
+		status.fail(fmt.Errorf("cannot mark satellite %s closed: %w", sat.GetName(), err))
+	})
+	status.Closed += count
+	log.Infof("marking satellites closed finished. %d out of %d marked", count, len(*list))
+}
+
+func updateSatellites(tx SatelliteTx, list *[][]Satellite, batchSize int, status *SyncStatus) {
+	log.Info("updating satellites ...")
+
+	count := applyBatches(len(*list), batchSize, func(from, to int) error {
+		log.Debugf("updating %v", (*list)[from:to])
+		return tx.Update((*list)[from:to])
+	}, func(i int, err error) {
+		oldSat, newSat := (*list)[i][0], (*list)[i][1]
+		log.WithError(err).Errorf("cannot update satellite %v with new values %v", oldSat, newSat)
+		status.fail(fmt.Errorf("cannot update satellite %s: %w", newSat.GetName(), err))
+	})
+	status.Updated += count
+	log.Infof("updating satellites finished. %d out of %d updated", count, len(*list))
+}
+
+func insertRelocations(tx SatelliteTx, list *[]Relocation, batchSize int, status *SyncStatus) {
+	log.Info("saving relocations ...")
+
+	count := applyBatches(len(*list), batchSize, func(from, to int) error {
+		log.Debugf("saving relocations %v", (*list)[from:to])
+		return tx.SaveRelocations((*list)[from:to])
+	}, func(i int, err error) {
+		relocation := (*list)[i]
+		log.WithError(err).Errorf("cannot save relocation %v", relocation)
+		status.fail(fmt.Errorf("cannot save relocation of %s: %w", relocation.Name, err))
+	})
+	status.Relocated += count
+	log.Infof("saving relocations finished. %d out of %d saved", count, len(*list))
+}
+
+func saveSources(tx SatelliteTx, list *[]Satellite, batchSize int, status *SyncStatus) {
+	log.Info("saving sources ...")
+
+	count := applyBatches(len(*list), batchSize, func(from, to int) error {
+		log.Debugf("saving sources of %v", (*list)[from:to])
+		return tx.SaveSources((*list)[from:to])
+	}, func(i int, err error) {
+		sat := (*list)[i]
+		log.WithError(err).Errorf("cannot save source of satellite %v", sat)
+		status.fail(fmt.Errorf("cannot save source of %s: %w", sat.GetName(), err))
+	})
+	log.Infof("saving sources finished. %d out of %d saved", count, len(*list))
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// testTx is a SatelliteTx which records changed batches by satellite names and fails batches containing any of
+// failing names.
+type testTx struct {
+	failing    map[string]bool
+	batches    []string
+	committed  bool
+	rolledBack bool
+}
+
+func (ptr *testTx) apply(change string, names []string) error {
+	ptr.batches = append(ptr.batches, change+" "+strings.Join(names, ","))
+	for _, name := range names {
+		if ptr.failing[name] {
+			return errors.New(name + " failed")
+		}
+	}
+	return nil
+}
+
+func testTxNames(list []Satellite) []string {
+	names := make([]string, 0, len(list))
+	for i := range list {
+		names = append(names, list[i].GetName())
+	}
+	return names
+}
+
+func (ptr *testTx) Insert(list []Satellite) error {
+	return ptr.apply("insert", testTxNames(list))
+}
+
+func (ptr *testTx) Close(list []Satellite) error {
+	return ptr.apply("close", testTxNames(list))
+}
+
+func (ptr *testTx) Update(pairs [][]Satellite) error {
+	var names []string
+	for _, pair := range pairs {
+		names = append(names, pair[1].GetName())
+	}
+	return ptr.apply("update", names)
+}
+
+func (ptr *testTx) SaveSources(list []Satellite) error {
+	return ptr.apply("source", testTxNames(list))
+}
+
+func (ptr *testTx) SaveRelocations(list []Relocation) error {
+	var names []string
+	for _, relocation := range list {
+		names = append(names, relocation.Name)
+	}
+	return ptr.apply("relocation", names)
+}
+
+func (ptr *testTx) Commit() error {
+	ptr.committed = true
+	return nil
+}
+
+func (ptr *testTx) Rollback() error {
+	ptr.rolledBack = true
+	return nil
+}
+
+// testTxRepository is a SatelliteRepository which begins the given transaction, other methods are not used by
+// sync.
+type testTxRepository struct {
+	SatelliteRepository
+	tx       *testTx
+	beginErr error
+}
+
+func (ptr *testTxRepository) Begin() (SatelliteTx, error) {
+	if ptr.beginErr != nil {
+		return nil, ptr.beginErr
+	}
+	return ptr.tx, nil
+}
+
+func TestApplyBatchesRetriesFailedBatchRowByRow(t *testing.T) {
+	var applied [][2]int
+	var failed []int
+	count := applyBatches(5, 3, func(from, to int) error {
+		applied = append(applied, [2]int{from, to})
+		if from <= 1 && 1 < to {
+			return errors.New("row 1 failed")
+		}
+		return nil
+	}, func(i int, err error) {
+		failed = append(failed, i)
+	})
+
+	assert.Equal(t, 4, count)
+	assert.Equal(t, []int{1}, failed)
+	assert.Equal(t, [][2]int{{0, 3}, {0, 1}, {1, 2}, {2, 3}, {3, 5}}, applied,
+		"only the failed batch must be retried row by row")
+}
+
+func TestApplySyncPlanAtomicRollback(t *testing.T) {
+	tx := &testTx{failing: map[string]bool{"two": true}}
+	plan := &SyncPlan{Inserts: []Satellite{makeSat("one", 1), makeSat("two", 2), makeSat("three", 3)},
+		Closes: []Satellite{makeSat("four", 4)}}
+
+	status := ApplySyncPlan(&testTxRepository{tx: tx}, plan, true, 10)
+	assert.True(t, status.RolledBack)
+	assert.False(t, status.Succeeded())
+	assert.Equal(t, 2, status.Inserted)
+	assert.Equal(t, 1, status.Closed)
+	assert.Equal(t, 1, status.Failed)
+	if assert.Len(t, status.Errors, 1) {
+		assert.EqualError(t, status.Errors[0], "cannot insert satellite two: two failed")
+	}
+	assert.True(t, tx.rolledBack)
+	assert.False(t, tx.committed, "atomic sync must not commit after a failed row")
+	assert.Equal(t, []string{"insert one,two,three", "insert one", "insert two", "insert three", "close four"},
+		tx.batches, "rows after the failed batch must still be applied to report all failures")
+}
+
+func TestApplySyncPlanPartialCommit(t *testing.T) {
+	tx := &testTx{failing: map[string]bool{"two": true}}
+	plan := &SyncPlan{Updates: [][]Satellite{{makeSat("one", 1), makeSat("one", 2)},
+		{makeSat("two", 1), makeSat("two", 2)}, {makeSat("three", 1), makeSat("three", 2)}}}
+
+	status := ApplySyncPlan(&testTxRepository{tx: tx}, plan, false, 2)
+	assert.False(t, status.RolledBack)
+	assert.Equal(t, 2, status.Updated)
+	assert.Equal(t, 1, status.Failed)
+	assert.True(t, tx.committed, "succeeded rows must be committed")
+	assert.False(t, tx.rolledBack)
+	assert.Equal(t, []string{"update one,two", "update one", "update two", "update three"}, tx.batches)
+}
+
+func TestApplySyncPlanBeginFailure(t *testing.T) {
+	status := ApplySyncPlan(&testTxRepository{beginErr: errors.New("no connection")},
+		&SyncPlan{Inserts: []Satellite{makeSat("one", 1)}}, false, 10)
+	assert.True(t, status.RolledBack)
+	require.Len(t, status.Errors, 1)
+	assert.EqualError(t, status.Errors[0], "cannot begin transaction: no connection")
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"github.com/artemkaxboy/configuration"
+	log "github.com/sirupsen/logrus"
+	"regexp"
+	"strings"
+)
+
+const tagRulesPath = "tagging.rules"
+
+// TagRule gives its tag to satellites which match all conditions set in the rule. Name and band are matched
+// by regular expressions, region is compared ignoring case, positions and inclinations are inclusive ranges.
+type TagRule struct {
+	Tag            string
+	Name           *regexp.Regexp
+	Band           *regexp.Regexp
+	Region         string
+	MinPosition    *float64
+	MaxPosition    *float64
+	MinInclination *float64
+	MaxInclination *float64
+}
+
+var (
+	tagRulesPtr *[]TagRule
+)
+
+// getTagRules loads tagging rules from the properties file if needed and returns them.
+func getTagRules() []TagRule {
+	if tagRulesPtr == nil {
+		rules, err := loadTagRules(getConfig())
+		if err != nil {
+			log.WithError(err).Fatal("cannot load tagging rules")
+		}
+		tagRulesPtr = &rules
+	}
+	return *tagRulesPtr
+}
+
+// loadTagRules reads rules from tagging.rules object, every key of the object is a tag and its value holds
+// conditions. go-hocon cannot load objects with arbitrary keys, so the rules are read from config directly.
+func loadTagRules(config *configuration.Config) ([]TagRule, error) {
+	tags, configs := getObjectConfigs(config, tagRulesPath)
+	var rules []TagRule
+	for i, tag := range tags {
+		rule, err := loadTagRule(tag, configs[i])
+		if err != nil {
+			return nil, fmt.Errorf("wrong rule of tag %s: %w", tag, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func loadTagRule(tag string, config *configuration.Config) (TagRule, error) {
+	rule := TagRule{Tag: tag}
+	if config == nil || !config.Root().IsObject() {
+		return rule, fmt.Errorf("rule must be an object of conditions")
+	}
+
+	for _, key := range config.Root().GetObject().GetKeys() {
+		var err error
+		switch key {
+		case "name":
+			rule.Name, err = regexp.Compile(config.GetString(key))
+		case "band":
+			rule.Band, err = regexp.Compile(config.GetString(key))
+		case "region":
+			rule.Region = config.GetString(key)
+		case "minPosition":
+			rule.MinPosition, err = getFloat(config, key)
+		case "maxPosition":
+			rule.MaxPosition, err = getFloat(config, key)
+		case "minInclination":
+			rule.MinInclination, err = getFloat(config, key)
+		case "maxInclination":
+			rule.MaxInclination, err = getFloat(config, key)
+		default:
+			err = fmt.Errorf("unknown condition, available conditions: name, band, region, " +
+				"minPosition, maxPosition, minInclination, maxInclination")
+		}
+		if err != nil {
+			return rule, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return rule, nil
+}
+
+// getFloat returns pointer to float value of the key to keep optional values.
+func getFloat(config *configuration.Config, key string) (*float64, error) {
+	value, err := config.GetFloat64Safely(key)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// Matches returns true if the satellite matches all conditions of the rule.
+func (ptr *TagRule) Matches(sat *Satellite) bool {
+	return (ptr.Name == nil || ptr.Name.MatchString(sat.GetName())) &&
+		(ptr.Band == nil || ptr.Band.MatchString(sat.GetBand())) &&
+		(ptr.Region == "" || strings.EqualFold(ptr.Region, sat.GetRegion())) &&
+		(ptr.MinPosition == nil || sat.GetPosition() >= *ptr.MinPosition) &&
+		(ptr.MaxPosition == nil || sat.GetPosition() <= *ptr.MaxPosition) &&
+		(ptr.MinInclination == nil || sat.GetInclination() >= *ptr.MinInclination) &&
+		(ptr.MaxInclination == nil || sat.GetInclination() <= *ptr.MaxInclination)
+}
+
+// ApplyTagRules replaces generated tags of every satellite in the list with tags of matching rules.
+func ApplyTagRules(list *[]Satellite, rules []TagRule) {
+	for i := range *list {
+		sat := &(*list)[i]
+		var tags []string
+		for _, rule := range rules {
+			if rule.Matches(sat) {
+				tags = append(tags, rule.Tag)
+			}
+		}
+		sat.SetTags(tags)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"github.com/artemkaxboy/configuration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const testTagRules = `tagging {
+  rules {
+    ku-europe {
+      band: "(?i)ku"
+      region: europe
+    }
+    inclined {
+      minInclination: 0.1
+    }
+    hotbird-cluster {
+      name: "(?i)^hot ?bird"
+      minPosition: 12.5
+      maxPosition: 13.5
+    }
+  }
+}`
+
+func TestLoadTagRules(t *testing.T) {
+	rules, err := loadTagRules(configuration.ParseString(testTagRules))
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+
+	assert.Equal(t, "hotbird-cluster", rules[2].Tag)
+	assert.Equal(t, 12.5, *rules[2].MinPosition)
+	assert.Equal(t, 13.5, *rules[2].MaxPosition)
+	assert.Nil(t, rules[2].Band)
+}
+
+func TestLoadTagRulesErrors(t *testing.T) {
+	_, err := loadTagRules(configuration.ParseString(`tagging.rules.wrong { nmae: "x" }`))
+	assert.Error(t, err, "unknown conditions must be refused")
+
+	_, err = loadTagRules(configuration.ParseString(`tagging.rules.wrong { name: "(" }`))
+	assert.Error(t, err, "wrong regular expressions must be refused")
+
+	rules, err := loadTagRules(configuration.ParseString(`logLevel: debug`))
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+}
+
+func TestApplyTagRules(t *testing.T) {
+	rules, err := loadTagRules(configuration.ParseString(testTagRules))
+	require.NoError(t, err)
+
+	hotbird := makeSourcedSat("Hot Bird 13E", 13, "https://www.base.com/europe.html")
+	hotbird.SetBand("Ku")
+	hotbird.SetInclination("Hot Bird 13E (incl. 0.2°)")
+	asian := makeSourcedSat("ABS 7", 116, "https://www.base.com/asia.html")
+	asian.SetBand("Ku")
+	asian.SetTags([]string{"stale"})
+	list := []Satellite{hotbird, asian}
+
+	ApplyTagRules(&list, rules)
+
+	assert.Equal(t, []string{"hotbird-cluster", "inclined", "ku-europe"}, list[0].GetTags())
+	assert.Empty(t, list[1].GetTags(), "tags must be recomputed")
+}
+
+func TestTagsChangeIsFieldChange(t *testing.T) {
+	initial := makeSat("one", 1)
+	initial.SetManualTags([]string{"mine"})
+	changed := makeSat("one", 1)
+	changed.SetTags([]string{"inclined"})
+
+	assert.Equal(t, []string{fieldTags}, (&Comparator{}).Differences(&initial, &changed),
+		"manual tags must not be compared")
+
+	records := DiffSatellites(&initial, &changed, &Comparator{})
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, fieldTags, records[0].Field)
+		assert.Equal(t, "inclined", records[0].NewValue)
+	}
+}
+
+func TestGetAllTags(t *testing.T) {
+	sat := makeSat("one", 1)
+	sat.SetTags([]string{"inclined", "ku-europe"})
+	sat.SetManualTags([]string{"mine", "inclined", ""})
+
+	assert.Equal(t, "inclined,mine", sat.ManualTags)
+	assert.Equal(t, []string{"inclined", "ku-europe", "mine"}, sat.GetAllTags())
+}
+
+func TestExampleTagRules(t *testing.T) {
+	assert.Len(t, getTagRules(), 3, "example rules must be loaded with properties")
+}
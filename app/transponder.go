@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Transponder holds tuning parameters of one satellite transponder. Frequency is in kHz, symbol rate is in
+// symbols per second, polarization is one of H, V, L, R, FEC is e.g. 3/4 or empty if unknown, system is DVB-S or
+// DVB-S2 and modulation is e.g. QPSK or 8PSK or empty if unknown.
+type Transponder struct {
+	Frequency    int64  `json:"frequency"`
+	Polarization string `json:"polarization"`
+	SymbolRate   int64  `json:"symbolRate"`
+	FEC          string `json:"fec"`
+	System       string `json:"system"`
+	Modulation   string `json:"modulation"`
+}
+
+const (
+	systemDVBS  = "DVB-S"
+	systemDVBS2 = "DVB-S2"
+)
+
+var (
+	// frequency in MHz followed by polarization, e.g. 10719 V or 11747.5 H
+	transponderFrequencyRegex = regexp.MustCompile(`\b(\d{4,5}(?:\.\d{1,3})?)\s*([HVLR])\b`)
+	// symbol rate in ksymbols per second followed by FEC, e.g. 27500-5/6
+	transponderSymbolRateRegex = regexp.MustCompile(`\b(\d{3,5})\s*-\s*(\d{1,2}/\d{1,2})\b`)
+	transponderSystemRegex     = regexp.MustCompile(`\bDVB-S2X?\b`)
+	transponderModulationRegex = regexp.MustCompile(`\b(QPSK|8PSK|16APSK|32APSK)\b`)
+)
+
+// ParseTransponders extracts transponders from satellite page. Every table row without nested tables holding
+// frequency with polarization and symbol rate with FEC is a transponder, repeated frequencies are skipped.
+// Rows without symbol rate are headers and notes, they are ignored.
+func ParseTransponders(reader io.Reader) ([]Transponder, error) {
+	document, err := goquery.NewDocumentFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error reading satellite page: %w", err)
+	}
+
+	var transponders []Transponder
+	found := make(map[string]bool)
+	document.Find("tr").FilterFunction(lastLevelTable).Each(func(_ int, selection *goquery.Selection) {
+		text := rowText(selection)
+		frequency := transponderFrequencyRegex.FindStringSubmatch(text)
+		symbolRate := transponderSymbolRateRegex.FindStringSubmatch(text)
+		if frequency == nil || symbolRate == nil {
+			return
+		}
+
+		megahertz, _ := strconv.ParseFloat(frequency[1], 64)
+		ksymbols, _ := strconv.ParseInt(symbolRate[1], 10, 64)
+		transponder := Transponder{
+			Frequency:    int64(math.Round(megahertz * 1000)),
+			Polarization: frequency[2],
+			SymbolRate:   ksymbols * 1000,
+			FEC:          symbolRate[2],
+			System:       systemDVBS,
+			Modulation:   transponderModulationRegex.FindString(text),
+		}
+		if transponderSystemRegex.MatchString(text) {
+			transponder.System = systemDVBS2
+		}
+
+		key := frequency[1] + frequency[2]
+		if !found[key] {
+			found[key] = true
+			transponders = append(transponders, transponder)
+		}
+	})
+	return transponders, nil
+}
+
+// rowText returns text of the table row with its text nodes separated by spaces, cells and lines of cells are
+// not glued together then.
+func rowText(selection *goquery.Selection) string {
+	var parts []string
+	selection.Find("*").Contents().Each(func(_ int, node *goquery.Selection) {
+		if goquery.NodeName(node) == "#text" {
+			parts = append(parts, node.Text())
+		}
+	})
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
+
+// loadTransponders loads satellite page and parses its transponders.
+func loadTransponders(url string) ([]Transponder, error) {
+	response, _, err := getResponse(url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = closeReader(response) }()
+
+	reader, err := getUtf8Reader(response)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTransponders(reader)
+}
+
+// CollectTransponders loads pages of all satellites of the list by their URLs with given count of workers and
+// returns transponders by satellite names. Satellites which pages cannot be loaded are skipped and their errors
+// are returned.
+func CollectTransponders(list []Satellite, workers int) (map[string][]Transponder, []error) {
+	if workers < 1 {
+		workers = 1
+	}
+	log.Infof("collecting transponders of %d satellites ...", len(list))
+
+	type result struct {
+		name         string
+		transponders []Transponder
+		err          error
+	}
+	chSat, chResult := make(chan Satellite), make(chan result)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for sat := range chSat {
+				transponders, err := loadTransponders(sat.GetURL())
+				if err != nil {
+					err = fmt.Errorf("cannot collect transponders of %s: %w", sat.GetName(), err)
+				}
+				chResult <- result{name: sat.GetName(), transponders: transponders, err: err}
+			}
+		}()
+	}
+	go func() {
+		for _, sat := range list {
+			chSat <- sat
+		}
+		close(chSat)
+	}()
+
+	transponders := make(map[string][]Transponder, len(list))
+	var errorz []error
+	for range list {
+		received := <-chResult
+		if received.err != nil {
+			errorz = append(errorz, received.err)
+			continue
+		}
+		transponders[received.name] = received.transponders
+	}
+
+	log.Infof("transponders collecting finished. %d out of %d satellites collected", len(transponders), len(list))
+	return transponders, errorz
+}
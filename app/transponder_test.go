@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testSatellitePage = `<table><tr><td>
+<table border>
+<tr><td>Frequency</td><td>Provider</td><td>System</td><td>SR-FEC</td></tr>
+<tr><td><b>10719 V</b> tp 1</td><td>Eutelsat</td><td>DVB-S2<br>8PSK</td><td>27500-5/6</td></tr>
+<tr><td><b>10719 V</b> tp 1</td><td>Second channel of the same transponder</td><td></td><td>27500-5/6</td></tr>
+<tr><td><b>11747.5 H</b></td><td>Rai</td><td>DVB-S<br>QPSK</td><td>27500 - 3/4</td></tr>
+<tr><td><b>11766 L</b></td><td>Feeds only, symbol rate unknown</td><td>DVB-S</td><td></td></tr>
+</table>
+</td></tr></table>`
+
+func TestParseTransponders(t *testing.T) {
+	transponders, err := ParseTransponders(strings.NewReader(testSatellitePage))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Transponder{
+		{Frequency: 10719000, Polarization: "V", SymbolRate: 27500000, FEC: "5/6", System: systemDVBS2,
+			Modulation: "8PSK"},
+		{Frequency: 11747500, Polarization: "H", SymbolRate: 27500000, FEC: "3/4", System: systemDVBS,
+			Modulation: "QPSK"},
+	}, transponders)
+}
+
+func TestCollectTransponders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/Hot-Bird-13E.html" {
+			http.NotFound(writer, request)
+			return
+		}
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = writer.Write([]byte(testSatellitePage))
+	}))
+	defer server.Close()
+
+	list := []Satellite{
+		{Name: "Hot Bird 13E", Position: 13, URL: server.URL + "/Hot-Bird-13E.html"},
+		{Name: "Missing", Position: 14, URL: server.URL + "/Missing.html"},
+	}
+	transponders, errorz := CollectTransponders(list, 2)
+
+	assert.Len(t, errorz, 1)
+	assert.Len(t, transponders, 1)
+	assert.Len(t, transponders["Hot Bird 13E"], 2)
+}
+
+func testScanTransponders() []Transponder {
+	return []Transponder{
+		{Frequency: 10719000, Polarization: "V", SymbolRate: 27500000, FEC: "5/6", System: systemDVBS2,
+			Modulation: "8PSK"},
+		{Frequency: 11727000, Polarization: "V", SymbolRate: 27500000, FEC: "3/4", System: systemDVBS},
+		{Frequency: 12012000, Polarization: "H", SymbolRate: 29900000, System: systemDVBS2},
+	}
+}
+
+func TestWriteDVBv5Scan(t *testing.T) {
+	sat := makeBandSat("Hot Bird 13E", 13, "Ku")
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteDVBv5Scan(&buffer, &sat, testScanTransponders()))
+	assertGolden(t, "dvbv5_13.0E-Hot-Bird-13E", buffer.Bytes())
+}
+
+func TestWriteLegacyScan(t *testing.T) {
+	sat := makeBandSat("Hot Bird 13E", 13, "Ku")
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteLegacyScan(&buffer, &sat, testScanTransponders()))
+	assertGolden(t, "legacy_13.0E-Hot-Bird-13E", buffer.Bytes())
+
+	assert.Error(t, WriteLegacyScan(&buffer, &sat, []Transponder{{Polarization: "V", System: "DVB-T"}}))
+}
+
+func TestScanFileName(t *testing.T) {
+	sat := makeSat("Astra 1KR & 1L", 19.2)
+	assert.Equal(t, "19.2E-Astra-1KR-1L", scanFileName(&sat))
+	sat = makeSat("Intelsat 14", -45)
+	assert.Equal(t, "45.0W-Intelsat-14", scanFileName(&sat))
+}
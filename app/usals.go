@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	// maxDiSEqCSlot is the last stored position of DiSEqC 1.2 motors, position 0 is the reference one
+	maxDiSEqCSlot = 255
+
+	diseqcFormatCSV = "csv"
+	diseqcFormatVDR = "vdr"
+)
+
+// MotorAngle returns USALS rotation angle of a polar mount motor at the observer location to the geostationary
+// satellite at given orbital position. Angles are in degrees from the observer meridian, east is positive.
+func (ptr *Observer) MotorAngle(position float64) float64 {
+	delta := radians(position - ptr.Longitude)
+	// the motor axis is parallel to the Earth axis, so the angle is the hour angle of the satellite seen from
+	// the observer, the Earth radius ratio moves the observer off the Earth center
+	ratio := earthRadius / geostationaryRadius * math.Cos(radians(ptr.Latitude))
+	return degrees(math.Atan2(math.Sin(delta), math.Cos(delta)-ratio))
+}
+
+// formatMotorAngle returns motor angle with its direction, e.g. 21.1°E.
+func formatMotorAngle(angle float64) string {
+	if angle < 0 {
+		return fmt.Sprintf("%.1f°W", -angle)
+	}
+	return fmt.Sprintf("%.1f°E", angle)
+}
+
+// DiSEqCSlot is a stored position of DiSEqC 1.2 motor assigned to a satellite.
+type DiSEqCSlot struct {
+	Slot       int
+	Satellite  Satellite
+	MotorAngle float64
+}
+
+// MakeDiSEqCSlots assigns stored positions to visible satellites of the list in their order starting from 1.
+// Returns error if the satellites do not fit positions of DiSEqC 1.2 motor.
+func MakeDiSEqCSlots(list []Satellite, observer *Observer) ([]DiSEqCSlot, error) {
+	var slots []DiSEqCSlot
+	for _, sat := range list {
+		if !observer.LookAngles(sat.GetPosition()).Visible {
+			continue
+		}
+		slots = append(slots, DiSEqCSlot{Slot: len(slots) + 1, Satellite: sat,
+			MotorAngle: observer.MotorAngle(sat.GetPosition())})
+	}
+
+	if len(slots) > maxDiSEqCSlot {
+		return nil, fmt.Errorf("%d visible satellites do not fit %d motor positions, use filters", len(slots),
+			maxDiSEqCSlot)
+	}
+	return slots, nil
+}
+
+// WriteDiSEqCSlots writes motor positions in given format: csv is a table of slots, satellites and motor angles,
+// vdr is diseqc.conf of VDR with universal LNB which drives the motor to the stored position before tuning.
+func WriteDiSEqCSlots(writer io.Writer, slots []DiSEqCSlot, format string) error {
+	switch format {
+	case diseqcFormatCSV:
+		rows := make([][]string, 0, len(slots))
+		for _, slot := range slots {
+			rows = append(rows, []string{fmt.Sprint(slot.Slot), formatPosition(slot.Satellite.GetPosition()),
+				slot.Satellite.GetName(), fmt.Sprintf("%.1f", slot.MotorAngle)})
+		}
+		return writeCSV(writer, []string{"slot", "position", "name", "motor_angle"}, rows)
+
+	case diseqcFormatVDR:
+		if _, err := io.WriteString(writer, "# DiSEqC 1.2 motor positions, universal LNB\n"); err != nil {
+			return err
+		}
+		for _, slot := range slots {
+			source := vdrSource(slot.Satellite.GetPosition())
+			goTo := fmt.Sprintf("[E0 31 6B %02X]", slot.Slot)
+			if _, err := fmt.Fprintf(writer, "# %d %s, motor angle %s\n"+
+				"%s 11700 V  9750 t v W15 %s W15 t\n"+
+				"%s 99999 V 10600 t v W15 %s W15 T\n"+
+				"%s 11700 H  9750 t V W15 %s W15 t\n"+
+				"%s 99999 H 10600 t V W15 %s W15 T\n",
+				slot.Slot, slot.Satellite.GetName(), formatMotorAngle(slot.MotorAngle),
+				source, goTo, source, goTo, source, goTo, source, goTo); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown DiSEqC format %s, available formats: %s, %s", format, diseqcFormatCSV,
+			diseqcFormatVDR)
+	}
+}
+
+// vdrSource returns VDR source of the satellite position, e.g. S19.2E.
+func vdrSource(position float64) string {
+	return "S" + formatShortPosition(position)
+}
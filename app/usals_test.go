@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func TestMotorAngle(t *testing.T) {
+	// Reference angles follow from the geometry of the polar mount, whose axis is parallel to the Earth axis, so
+	// the motor angle is the angle between the observer meridian and the satellite direction projected on the
+	// equatorial plane. The observer is projected R·cos(latitude) away from the axis, R = 6378.137 km, and
+	// satellites are r = 42164.17 km away from it.
+
+	// on the observer meridian the satellite needs no rotation, east and west offsets are symmetric
+	assert.InDelta(t, 0, (&Observer{Latitude: 55.75, Longitude: 37.6}).MotorAngle(37.6), 1e-9)
+	assert.InDelta(t, -(&Observer{Latitude: 50}).MotorAngle(30), (&Observer{Latitude: 50}).MotorAngle(-30), 1e-9,
+		"west angles are negative")
+
+	// at the pole the observer is on the axis, so the motor angle equals the longitude offset
+	assert.InDelta(t, 30, (&Observer{Latitude: 90}).MotorAngle(30), 1e-9)
+
+	// at the equator a satellite 90° away is seen along the vector (-R, r), i.e. at 90° + atan(R / r)
+	assert.InDelta(t, 98.602, (&Observer{}).MotorAngle(90), 0.001)
+
+	// the satellite direction is perpendicular to the meridian when r·cos(offset) = R·cos(latitude), so the motor
+	// turns exactly 90° at offsets acos(R·cos(latitude) / r): 81.300° at the equator, 84.420° at 50°N and
+	// 87.034° at 70°S
+	assert.InDelta(t, 90, (&Observer{}).MotorAngle(81.300), 0.001)
+	assert.InDelta(t, -90, (&Observer{Latitude: 50, Longitude: 10}).MotorAngle(10-84.420), 0.001)
+	assert.InDelta(t, 90, (&Observer{Latitude: -70, Longitude: -60}).MotorAngle(-60+87.034), 0.001)
+
+	// at the equator the motor angle grows faster with longitude offset than at higher latitudes
+	assert.Greater(t, (&Observer{}).MotorAngle(30), (&Observer{Latitude: 60}).MotorAngle(30))
+}
+
+func TestMotorAngleMatchesLookAngles(t *testing.T) {
+	// the motor angle is the hour angle of the satellite, so it must match the one converted from azimuth and
+	// elevation computed on the ellipsoid with the horizontal to equatorial transformation of J. Meeus,
+	// Astronomical Algorithms, chapter 13. The spherical motor model differs by tenths of a degree at most
+	for _, observer := range []Observer{{Latitude: 51.5, Longitude: -0.1}, {Latitude: 60, Longitude: 30},
+		{Latitude: -33.9, Longitude: 151.2}, {Latitude: 10, Longitude: -70}} {
+		for _, position := range []float64{-60, -30, 0, 13, 19.2, 36, 140} {
+			angle := observer.LookAngles(position)
+			if angle.Elevation < 0 {
+				continue
+			}
+			azimuth, elevation, latitude := radians(angle.Azimuth), radians(angle.Elevation), radians(observer.Latitude)
+			hourAngle := degrees(math.Atan2(math.Sin(azimuth)*math.Cos(elevation),
+				math.Cos(latitude)*math.Sin(elevation)-math.Sin(latitude)*math.Cos(elevation)*math.Cos(azimuth)))
+			assert.InDelta(t, hourAngle, observer.MotorAngle(position), 0.3, "%v to %v", observer, position)
+		}
+	}
+}
+
+func TestWriteDiSEqCSlots(t *testing.T) {
+	observer := &Observer{Latitude: 51.5074, Longitude: -0.1278, MinElevation: 5}
+	list := append(testExportList(), makeBandSat("Far East", 140, "Ku"))
+	slots, err := MakeDiSEqCSlots(list, observer)
+	require.NoError(t, err)
+	require.Len(t, slots, 5, "invisible satellites do not get slots")
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteDiSEqCSlots(&buffer, slots, diseqcFormatCSV))
+	assertGolden(t, "diseqc.csv", buffer.Bytes())
+
+	buffer.Reset()
+	require.NoError(t, WriteDiSEqCSlots(&buffer, slots, diseqcFormatVDR))
+	assertGolden(t, "diseqc.conf", buffer.Bytes())
+
+	assert.Error(t, WriteDiSEqCSlots(&buffer, slots, "unknown"))
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const momentLayout = "2006-01-02 15:04:05"
+
+// SatelliteVersion is a state of a satellite which was valid since ValidFrom until ValidTo, nil ValidTo means
+// that the version is still valid.
+type SatelliteVersion struct {
+	Satellite
+	ValidFrom string  `db:"_valid_from"`
+	ValidTo   *string `db:"_valid_to"`
+}
+
+// formatMoment formats time in UTC the same way as storages keep timestamps.
+func formatMoment(moment time.Time) string {
+	return moment.UTC().Format(momentLayout)
+}
+
+// ParseMoment parses date, date with time or RFC 3339 timestamp, e.g. 2020-03-01, 2020-03-01 12:00:00 or
+// 2020-03-01T12:00:00+03:00. Values without time zone are treated as UTC, date means its midnight.
+func ParseMoment(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, momentLayout, "2006-01-02T15:04:05", "2006-01-02"} {
+		if moment, err := time.Parse(layout, value); err == nil {
+			return moment, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse time %s, expected formats: 2006-01-02, 2006-01-02 15:04:05, "+
+		"2006-01-02T15:04:05Z07:00", value)
+}
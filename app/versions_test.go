@@ -0,0 +1,179 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseMoment(t *testing.T) {
+	expected := time.Date(2020, 3, 1, 12, 30, 0, 0, time.UTC)
+	for _, value := range []string{"2020-03-01 12:30:00", "2020-03-01T12:30:00", "2020-03-01T15:30:00+03:00"} {
+		moment, err := ParseMoment(value)
+		if assert.NoError(t, err, value) {
+			assert.True(t, expected.Equal(moment), value)
+		}
+	}
+
+	moment, err := ParseMoment("2020-03-01")
+	require.NoError(t, err)
+	assert.Equal(t, "2020-03-01 00:00:00", formatMoment(moment))
+
+	_, err = ParseMoment("01.03.2020")
+	assert.Error(t, err)
+}
+
+// testLoadAsOf syncs two states of the catalogue, the first one is moved two hours back by shift function, and
+// checks the catalogue rebuilt for different moments.
+func testLoadAsOf(t *testing.T, repository SatelliteRepository, shift func()) {
+	status := syncTestRepository(t, repository, []Satellite{makeSat("one", 1), makeSat("two", 2)}, true, 100)
+	require.True(t, status.Succeeded())
+	shift()
+
+	status = syncTestRepository(t, repository, []Satellite{makeSat("two", 20), makeSat("three", 3)}, true, 100)
+	require.True(t, status.Succeeded())
+
+	names := func(moment time.Time) []string {
+		versions, err := repository.LoadAsOf(moment)
+		require.NoError(t, err)
+		var result []string
+		for _, version := range versions {
+			result = append(result, version.GetName()+"@"+formatPosition(version.GetPosition()))
+		}
+		return result
+	}
+
+	now := time.Now()
+	assert.Empty(t, names(now.Add(-3*time.Hour)), "nothing was active before the first sync")
+	assert.Equal(t, []string{"one@1", "two@2"}, names(now.Add(-time.Hour)))
+	assert.Equal(t, []string{"three@3", "two@20"}, names(now.Add(time.Minute)))
+
+	versions, err := repository.LoadAsOf(now.Add(-time.Hour))
+	require.NoError(t, err)
+	if assert.Len(t, versions, 2) {
+		assert.NotZero(t, versions[0].GetID())
+		assert.NotNil(t, versions[0].ValidTo, "replaced versions must be closed")
+	}
+
+	all, err := repository.LoadVersions()
+	require.NoError(t, err)
+	var listed []string
+	for _, version := range all {
+		listed = append(listed, version.GetName()+"@"+formatPosition(version.GetPosition()))
+	}
+	assert.Equal(t, []string{"one@1", "two@2", "two@20", "three@3"}, listed,
+		"versions must be ordered by satellite id and time")
+}
+
+func TestSQLRepositoryLoadAsOf(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	testLoadAsOf(t, repository, func() {
+		_, err := repository.db.Exec(`UPDATE "satellites_versions" SET _valid_from = datetime(_valid_from, '-2 hours')`)
+		require.NoError(t, err)
+	})
+}
+
+func TestFileRepositoryLoadAsOf(t *testing.T) {
+	repository, path, cleanup := openTestFileRepository(t, "satellites.csv")
+	defer cleanup()
+
+	testLoadAsOf(t, repository, func() {
+		for i := range repository.state.Versions {
+			repository.state.Versions[i].ValidFrom = formatMoment(time.Now().Add(-2 * time.Hour))
+		}
+	})
+
+	reopened, err := openFileRepository(&FileProperties{Path: path})
+	require.NoError(t, err)
+	assert.Equal(t, repository.state.Versions, reopened.state.Versions)
+}
+
+func TestFileRepositoryVersionsOfFilesWithoutVersions(t *testing.T) {
+	_, path, cleanup := openTestFileRepository(t, "satellites.json")
+	defer cleanup()
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"satellites": [`+
+		`{"id": 1, "name": "one", "position": 1, "active": true}, `+
+		`{"id": 2, "name": "two", "position": 2, "active": false, "closed": "2019-12-01 00:00:00"}]}`), 0644))
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(path, modified, modified))
+
+	for i := 0; i < 2; i++ {
+		repository, err := openFileRepository(&FileProperties{Path: path})
+		require.NoError(t, err)
+		versions, err := repository.LoadVersions()
+		require.NoError(t, err)
+		if assert.Len(t, versions, 1) {
+			assert.Equal(t, "2020-01-02 03:04:05", versions[0].ValidFrom,
+				"versions must be opened when the file was modified on every read")
+		}
+	}
+}
+
+func TestSQLRepositoryTimestampsOfOneSync(t *testing.T) {
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+
+	require.True(t, syncTestRepository(t, repository, []Satellite{makeSat("one", 1), makeSat("two", 2)}, true,
+		100).Succeeded())
+	before := time.Now().UTC().Add(-time.Second)
+	require.True(t, syncTestRepository(t, repository, []Satellite{makeSat("two", 20)}, true, 100).Succeeded())
+
+	var moments []string
+	require.NoError(t, repository.db.Select(&moments, `SELECT _closed FROM "satellites" WHERE _status = 0 `+
+		`UNION ALL SELECT _changed FROM "satellites_history" `+
+		`UNION ALL SELECT _detected FROM "satellites_relocations" `+
+		`UNION ALL SELECT _valid_to FROM "satellites_versions" WHERE _valid_to IS NOT NULL `+
+		`UNION ALL SELECT _valid_from FROM "satellites_versions" WHERE _satellite_id = 2 AND _valid_to IS NULL`))
+	require.Len(t, moments, 6)
+	for _, moment := range moments {
+		assert.Equal(t, normalizeMoment(moments[0]), normalizeMoment(moment),
+			"all changes of one sync must have the same moment")
+	}
+
+	moment, err := ParseMoment(moments[0])
+	require.NoError(t, err)
+	assert.False(t, moment.Before(before.Truncate(time.Second)), "moment must be UTC time of the sync")
+	assert.False(t, moment.After(time.Now().UTC()), "moment must be UTC time of the sync")
+}
+
+func TestSQLStatementsBindTimestamps(t *testing.T) {
+	for _, dialect := range []*sqlDialect{mysqlDialect, postgresDialect, sqliteDialect} {
+		stmts := newSQLStatements(dialect, defaultTable)
+		for _, statement := range []string{stmts.closeSatellites, stmts.insertHistory, stmts.insertRelocations,
+			stmts.insertNewVersions, stmts.closeVersions, stmts.insertVersions} {
+			assert.NotContains(t, statement, "CURRENT_TIMESTAMP",
+				"%s timestamps depend on the session time zone, UTC ones must be bound", dialect.name)
+		}
+	}
+}
+
+func TestVersionsMigrationOpensVersionsInUTC(t *testing.T) {
+	for dialect, now := range map[*sqlDialect]string{mysqlDialect: "UTC_TIMESTAMP()",
+		postgresDialect: "(now() AT TIME ZONE 'UTC')", sqliteDialect: "CURRENT_TIMESTAMP"} {
+		backfill := dialect.migrations[4].up[len(dialect.migrations[4].up)-1]
+		assert.Contains(t, backfill, "_valid_from) SELECT", dialect.name)
+		assert.Contains(t, backfill, now, dialect.name)
+	}
+
+	repository, cleanup := openTestRepository(t)
+	defer cleanup()
+	latest := repository.LatestSchemaVersion()
+	_, err := repository.MigrateDown(latest - 4)
+	require.NoError(t, err)
+	_, err = repository.db.Exec(`INSERT INTO "satellites" (_name, _position, _url, _band) VALUES ('one', 1, '', '')`)
+	require.NoError(t, err)
+	_, err = repository.MigrateUp()
+	require.NoError(t, err)
+
+	versions, err := repository.LoadVersions()
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	validFrom, err := ParseMoment(versions[0].ValidFrom)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), validFrom, time.Minute, "versions must be opened in UTC")
+}
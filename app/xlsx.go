@@ -0,0 +1,483 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// xlsxMaxSheetName is the maximal length of worksheet names accepted by spreadsheet applications
+	xlsxMaxSheetName = 31
+	// xlsxPositionFormat shows orbital positions as 13.0°E and 30.0°W keeping them numbers, so they sort correctly
+	xlsxPositionFormat = `0.0"°E";0.0"°W"`
+
+	xlsxStyleDefault  = 0
+	xlsxStyleHeader   = 1
+	xlsxStylePosition = 2
+
+	xlsxChangesSheet = "Changes"
+	xlsxOtherSheet   = "Other"
+)
+
+// xlsxCell is a worksheet cell, values are either strings or float64 numbers.
+type xlsxCell struct {
+	Value interface{}
+	Style int
+}
+
+// xlsxSheet is a worksheet with a frozen header row and auto-filter over all its columns.
+type xlsxSheet struct {
+	Name   string
+	Header []string
+	Widths []float64
+	Rows   [][]xlsxCell
+}
+
+// SyncChanges holds satellites inserted, closed, updated and relocated by a sync run.
+type SyncChanges struct {
+	Run         SyncRun
+	Inserts     []Satellite
+	Closes      []Satellite
+	Updates     []SyncUpdate
+	Relocations []Relocation
+}
+
+// SyncUpdate is a changed field of a satellite, the satellite is in its state after the run.
+type SyncUpdate struct {
+	Satellite Satellite
+	Record    HistoryRecord
+}
+
+var xlsxSheetNameReplacer = strings.NewReplacer("[", "(", "]", ")", ":", "-", "*", "-", "?", "-", "/", "-",
+	`\`, "-")
+
+// xlsxColumnName returns column letters of zero based column index, e.g. A, Z, AA.
+func xlsxColumnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxSheetName returns a valid worksheet name which is not used yet, names are case insensitive.
+func xlsxSheetName(name string, used map[string]bool) string {
+	name = strings.TrimSpace(xlsxSheetNameReplacer.Replace(name))
+	if name == "" {
+		name = xlsxOtherSheet
+	}
+	if len([]rune(name)) > xlsxMaxSheetName {
+		name = string([]rune(name)[:xlsxMaxSheetName])
+	}
+
+	unique := name
+	for i := 2; used[strings.ToLower(unique)]; i++ {
+		suffix := " " + strconv.Itoa(i)
+		runes := []rune(name)
+		if len(runes)+len(suffix) > xlsxMaxSheetName {
+			runes = runes[:xlsxMaxSheetName-len(suffix)]
+		}
+		unique = string(runes) + suffix
+	}
+	used[strings.ToLower(unique)] = true
+	return unique
+}
+
+// satelliteXLSXRow returns cells of the satellite for region sheets.
+func satelliteXLSXRow(sat *Satellite) []xlsxCell {
+	return []xlsxCell{
+		{Value: sat.GetPosition(), Style: xlsxStylePosition},
+		{Value: sat.GetName()},
+		{Value: sat.GetBand()},
+		{Value: sat.GetURL()},
+		{Value: formatTags(sat.GetAllTags())},
+		{Value: sat.FormatAnnotations()},
+	}
+}
+
+// MakeXLSXSheets returns a worksheet per source region with satellites sorted by position and name and the
+// changes sheet if changes are given. Satellites with unknown region are written to the Other sheet.
+func MakeXLSXSheets(list []Satellite, changes *SyncChanges) []xlsxSheet {
+	satellites := append([]Satellite(nil), list...)
+	sort.Sort(ByPosName(satellites))
+
+	byRegion := make(map[string][][]xlsxCell)
+	var regions []string
+	for i := range satellites {
+		region := satellites[i].GetRegion()
+		if _, found := byRegion[region]; !found {
+			regions = append(regions, region)
+		}
+		byRegion[region] = append(byRegion[region], satelliteXLSXRow(&satellites[i]))
+	}
+	// the sheet of unknown region goes last
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i] == "" || regions[j] == "" {
+			return regions[j] == ""
+		}
+		return regions[i] < regions[j]
+	})
+
+	used := map[string]bool{strings.ToLower(xlsxChangesSheet): changes != nil}
+	var sheets []xlsxSheet
+	for _, region := range regions {
+		sheets = append(sheets, xlsxSheet{
+			Name:   xlsxSheetName(region, used),
+			Header: []string{"Position", "Name", "Band", "URL", "Tags", "Annotations"},
+			Widths: []float64{10, 30, 10, 50, 30, 40},
+			Rows:   byRegion[region],
+		})
+	}
+
+	if changes != nil {
+		sheet := xlsxSheet{
+			Name: xlsxChangesSheet,
+			Header: []string{"Change", "Position", "Name", "Field", "Old value", "New value", "Direction", "Run",
+				"Started"},
+			Widths: []float64{10, 10, 30, 10, 30, 30, 10, 30, 20},
+		}
+		add := func(change string, position float64, name, field, oldValue, newValue, direction string) {
+			sheet.Rows = append(sheet.Rows, []xlsxCell{{Value: change},
+				{Value: position, Style: xlsxStylePosition}, {Value: name}, {Value: field},
+				{Value: oldValue}, {Value: newValue}, {Value: direction}, {Value: changes.Run.RunID},
+				{Value: normalizeMoment(changes.Run.Started)}})
+		}
+		for i := range changes.Inserts {
+			sat := &changes.Inserts[i]
+			add("inserted", sat.GetPosition(), sat.GetName(), "", "", "", "")
+		}
+		for i := range changes.Closes {
+			sat := &changes.Closes[i]
+			add("closed", sat.GetPosition(), sat.GetName(), "", "", "", "")
+		}
+		for i := range changes.Updates {
+			update := &changes.Updates[i]
+			add("updated", update.Satellite.GetPosition(), update.Satellite.GetName(), update.Record.Field,
+				update.Record.OldValue, update.Record.NewValue, "")
+		}
+		for _, relocation := range changes.Relocations {
+			add("relocated", relocation.ToPosition, relocation.Name, fieldPosition,
+				formatOrbitalPosition(relocation.FromPosition), formatOrbitalPosition(relocation.ToPosition),
+				relocation.Direction)
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sheets
+}
+
+// FindSyncChanges compares satellites active before and after the run by names and picks history records and
+// relocations of the run. Position changes of relocated satellites are listed as relocations, not as updates.
+func FindSyncChanges(run *SyncRun, before, after []SatelliteVersion, history []HistoryRecord,
+	relocations []Relocation) *SyncChanges {
+	changes := &SyncChanges{Run: *run}
+	byName := func(versions []SatelliteVersion) map[string]Satellite {
+		found := make(map[string]Satellite, len(versions))
+		for _, version := range versions {
+			found[version.GetName()] = version.Satellite
+		}
+		return found
+	}
+	beforeNames, afterNames := byName(before), byName(after)
+
+	for _, version := range after {
+		if _, found := beforeNames[version.GetName()]; !found {
+			changes.Inserts = append(changes.Inserts, version.Satellite)
+		}
+	}
+	for _, version := range before {
+		if _, found := afterNames[version.GetName()]; !found {
+			changes.Closes = append(changes.Closes, version.Satellite)
+		}
+	}
+	relocated := make(map[int64]bool)
+	for _, relocation := range relocations {
+		if relocation.RunID == run.RunID {
+			changes.Relocations = append(changes.Relocations, relocation)
+			relocated[relocation.SatelliteID] = true
+		}
+	}
+	for _, record := range history {
+		if record.RunID == run.RunID && !(record.Field == fieldPosition && relocated[record.SatelliteID]) {
+			sat, found := afterNames[record.Name]
+			if !found {
+				sat = Satellite{Name: record.Name}
+			}
+			changes.Updates = append(changes.Updates, SyncUpdate{Satellite: sat, Record: record})
+		}
+	}
+	return changes
+}
+
+// LoadLatestSyncChanges loads changes of the latest sync run which applied changes, nil if there is no such run.
+// Inserts and closes are found by catalogues as of a moment before the run start and as of the run finish.
+func LoadLatestSyncChanges() *SyncChanges {
+	for _, run := range LoadSyncRuns(20) {
+		if run.Status != runSucceeded && run.Status != runFailed {
+			continue
+		}
+
+		started, err := ParseMoment(run.Started)
+		if err != nil {
+			log.WithError(err).Fatalf("wrong start time of run %s", run.RunID)
+		}
+		finished, err := ParseMoment(run.Finished)
+		if err != nil {
+			log.WithError(err).Fatalf("wrong finish time of run %s", run.RunID)
+		}
+		history, err := getRepository().LoadAllHistory()
+		if err != nil {
+			log.WithError(err).Fatal("critical error, shutting down ...")
+		}
+
+		return FindSyncChanges(&run, LoadCatalogueAsOf(started.Add(-time.Second)), LoadCatalogueAsOf(finished),
+			history, LoadAllRelocations())
+	}
+	return nil
+}
+
+type xlsxRelationship struct {
+	ID     string `xml:"Id,attr"`
+	Type   string `xml:"Type,attr"`
+	Target string `xml:"Target,attr"`
+}
+
+type xlsxRelationships struct {
+	XMLName       xml.Name           `xml:"http://schemas.openxmlformats.org/package/2006/relationships Relationships"`
+	Relationships []xlsxRelationship `xml:"Relationship"`
+}
+
+type xlsxContentTypes struct {
+	XMLName   xml.Name `xml:"http://schemas.openxmlformats.org/package/2006/content-types Types"`
+	Defaults  []xlsxContentTypeDefault
+	Overrides []xlsxContentTypeOverride
+}
+
+type xlsxContentTypeDefault struct {
+	XMLName     xml.Name `xml:"Default"`
+	Extension   string   `xml:"Extension,attr"`
+	ContentType string   `xml:"ContentType,attr"`
+}
+
+type xlsxContentTypeOverride struct {
+	XMLName     xml.Name `xml:"Override"`
+	PartName    string   `xml:"PartName,attr"`
+	ContentType string   `xml:"ContentType,attr"`
+}
+
+type xlsxWorkbook struct {
+	XMLName      xml.Name            `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main workbook"`
+	RelNamespace string              `xml:"xmlns:r,attr"`
+	Sheets       []xlsxWorkbookSheet `xml:"sheets>sheet"`
+	DefinedNames []xlsxDefinedName   `xml:"definedNames>definedName"`
+}
+
+type xlsxWorkbookSheet struct {
+	Name    string `xml:"name,attr"`
+	SheetID int    `xml:"sheetId,attr"`
+	RelID   string `xml:"r:id,attr"`
+}
+
+type xlsxDefinedName struct {
+	Name         string `xml:"name,attr"`
+	LocalSheetID int    `xml:"localSheetId,attr"`
+	Hidden       bool   `xml:"hidden,attr"`
+	Value        string `xml:",chardata"`
+}
+
+type xlsxWorksheet struct {
+	XMLName    xml.Name       `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main worksheet"`
+	SheetView  xlsxSheetView  `xml:"sheetViews>sheetView"`
+	Columns    []xlsxColumn   `xml:"cols>col"`
+	Rows       []xlsxRow      `xml:"sheetData>row"`
+	AutoFilter xlsxAutoFilter `xml:"autoFilter"`
+}
+
+type xlsxSheetView struct {
+	WorkbookViewID int      `xml:"workbookViewId,attr"`
+	Pane           xlsxPane `xml:"pane"`
+}
+
+type xlsxPane struct {
+	YSplit      int    `xml:"ySplit,attr"`
+	TopLeftCell string `xml:"topLeftCell,attr"`
+	ActivePane  string `xml:"activePane,attr"`
+	State       string `xml:"state,attr"`
+}
+
+type xlsxColumn struct {
+	Min         int     `xml:"min,attr"`
+	Max         int     `xml:"max,attr"`
+	Width       float64 `xml:"width,attr"`
+	CustomWidth bool    `xml:"customWidth,attr"`
+}
+
+type xlsxRow struct {
+	Index int           `xml:"r,attr"`
+	Cells []xlsxRowCell `xml:"c"`
+}
+
+type xlsxRowCell struct {
+	Ref    string  `xml:"r,attr"`
+	Style  int     `xml:"s,attr,omitempty"`
+	Type   string  `xml:"t,attr,omitempty"`
+	Value  *string `xml:"v,omitempty"`
+	Inline *string `xml:"is>t,omitempty"`
+}
+
+type xlsxAutoFilter struct {
+	Ref string `xml:"ref,attr"`
+}
+
+// xlsxStyles defines a bold header style and the orbital position number format, indexes of cellXfs are the
+// xlsxStyle constants.
+var xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="` + strings.Replace(xlsxPositionFormat, `"`, "&quot;", -1) +
+	`"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>
+</styleSheet>
+`
+
+// makeXLSXWorksheet lays out the sheet with the header in the first row.
+func makeXLSXWorksheet(sheet *xlsxSheet) *xlsxWorksheet {
+	lastColumn := xlsxColumnName(len(sheet.Header) - 1)
+	worksheet := &xlsxWorksheet{
+		SheetView: xlsxSheetView{Pane: xlsxPane{YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft",
+			State: "frozen"}},
+		AutoFilter: xlsxAutoFilter{Ref: fmt.Sprintf("A1:%s%d", lastColumn, len(sheet.Rows)+1)},
+	}
+	for i, width := range sheet.Widths {
+		worksheet.Columns = append(worksheet.Columns, xlsxColumn{Min: i + 1, Max: i + 1, Width: width,
+			CustomWidth: true})
+	}
+
+	header := make([]xlsxCell, 0, len(sheet.Header))
+	for _, title := range sheet.Header {
+		header = append(header, xlsxCell{Value: title, Style: xlsxStyleHeader})
+	}
+	for i, cells := range append([][]xlsxCell{header}, sheet.Rows...) {
+		row := xlsxRow{Index: i + 1}
+		for j, cell := range cells {
+			rowCell := xlsxRowCell{Ref: xlsxColumnName(j) + strconv.Itoa(i+1), Style: cell.Style}
+			switch value := cell.Value.(type) {
+			case float64:
+				text := strconv.FormatFloat(value, 'f', -1, 64)
+				rowCell.Value = &text
+			default:
+				text := fmt.Sprint(value)
+				rowCell.Type, rowCell.Inline = "inlineStr", &text
+			}
+			row.Cells = append(row.Cells, rowCell)
+		}
+		worksheet.Rows = append(worksheet.Rows, row)
+	}
+	return worksheet
+}
+
+// WriteXLSX writes sheets as an Office Open XML workbook. Strings are written inline, so the workbook has no
+// shared strings part.
+func WriteXLSX(writer io.Writer, sheets []xlsxSheet) error {
+	if len(sheets) == 0 {
+		return fmt.Errorf("workbook must have at least one sheet")
+	}
+
+	const (
+		sheetType     = "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"
+		relationships = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	)
+	contentTypes := xlsxContentTypes{
+		Defaults: []xlsxContentTypeDefault{
+			{Extension: "rels", ContentType: "application/vnd.openxmlformats-package.relationships+xml"},
+			{Extension: "xml", ContentType: "application/xml"},
+		},
+		Overrides: []xlsxContentTypeOverride{
+			{PartName: "/xl/workbook.xml",
+				ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"},
+			{PartName: "/xl/styles.xml",
+				ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"},
+		},
+	}
+	workbook := xlsxWorkbook{RelNamespace: relationships}
+	workbookRels := xlsxRelationships{Relationships: []xlsxRelationship{
+		{ID: "rId1", Type: relationships + "/styles", Target: "styles.xml"}}}
+	parts := make(map[string]interface{})
+
+	for i := range sheets {
+		sheet := &sheets[i]
+		path := fmt.Sprintf("worksheets/sheet%d.xml", i+1)
+		relID := fmt.Sprintf("rId%d", i+2)
+		worksheet := makeXLSXWorksheet(sheet)
+
+		contentTypes.Overrides = append(contentTypes.Overrides,
+			xlsxContentTypeOverride{PartName: "/xl/" + path, ContentType: sheetType})
+		workbook.Sheets = append(workbook.Sheets, xlsxWorkbookSheet{Name: sheet.Name, SheetID: i + 1, RelID: relID})
+		workbook.DefinedNames = append(workbook.DefinedNames, xlsxDefinedName{Name: "_xlnm._FilterDatabase",
+			LocalSheetID: i, Hidden: true, Value: "'" + strings.Replace(sheet.Name, "'", "''", -1) + "'!" +
+				absoluteXLSXRange(worksheet.AutoFilter.Ref)})
+		workbookRels.Relationships = append(workbookRels.Relationships,
+			xlsxRelationship{ID: relID, Type: relationships + "/worksheet", Target: path})
+		parts["xl/"+path] = worksheet
+	}
+
+	archive := zip.NewWriter(writer)
+	write := func(name string, content interface{}) error {
+		part, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		if text, ok := content.(string); ok {
+			_, err = io.WriteString(part, text)
+			return err
+		}
+		if _, err := io.WriteString(part, xml.Header); err != nil {
+			return err
+		}
+		return xml.NewEncoder(part).Encode(content)
+	}
+
+	// content types go first as some readers expect
+	if err := write("[Content_Types].xml", contentTypes); err != nil {
+		return err
+	}
+	if err := write("_rels/.rels", xlsxRelationships{Relationships: []xlsxRelationship{
+		{ID: "rId1", Type: relationships + "/officeDocument", Target: "xl/workbook.xml"}}}); err != nil {
+		return err
+	}
+	if err := write("xl/workbook.xml", workbook); err != nil {
+		return err
+	}
+	if err := write("xl/_rels/workbook.xml.rels", workbookRels); err != nil {
+		return err
+	}
+	if err := write("xl/styles.xml", xlsxStyles); err != nil {
+		return err
+	}
+	for i := range sheets {
+		name := fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)
+		if err := write(name, parts[name]); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// absoluteXLSXRange returns range with absolute references, e.g. $A$1:$E$10 of A1:E10.
+func absoluteXLSXRange(ref string) string {
+	var refs []string
+	for _, cell := range strings.Split(ref, ":") {
+		split := strings.IndexAny(cell, "0123456789")
+		refs = append(refs, "$"+cell[:split]+"$"+cell[split:])
+	}
+	return strings.Join(refs, ":")
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// testXLSXSheet is a worksheet read back from a workbook, cells are values as they are stored.
+type testXLSXSheet struct {
+	Name       string
+	Rows       [][]string
+	Styles     [][]int
+	Frozen     string
+	AutoFilter string
+}
+
+// readTestXLSX reads worksheets of the workbook in their order.
+func readTestXLSX(t *testing.T, content []byte) (sheets []testXLSXSheet, styles string) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+	parts := make(map[string][]byte)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		parts[file.Name], err = ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		_ = reader.Close()
+	}
+	require.Contains(t, parts, "[Content_Types].xml")
+	require.Contains(t, parts, "_rels/.rels")
+
+	var workbook struct {
+		Sheets []struct {
+			Name  string `xml:"name,attr"`
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	require.NoError(t, xml.Unmarshal(parts["xl/workbook.xml"], &workbook))
+	var relationships struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	require.NoError(t, xml.Unmarshal(parts["xl/_rels/workbook.xml.rels"], &relationships))
+	targets := make(map[string]string)
+	for _, relationship := range relationships.Relationships {
+		targets[relationship.ID] = relationship.Target
+	}
+
+	for _, workbookSheet := range workbook.Sheets {
+		var worksheet struct {
+			Pane struct {
+				TopLeftCell string `xml:"topLeftCell,attr"`
+				State       string `xml:"state,attr"`
+			} `xml:"sheetViews>sheetView>pane"`
+			Rows []struct {
+				Cells []struct {
+					Style  int    `xml:"s,attr"`
+					Value  string `xml:"v"`
+					Inline string `xml:"is>t"`
+				} `xml:"c"`
+			} `xml:"sheetData>row"`
+			AutoFilter struct {
+				Ref string `xml:"ref,attr"`
+			} `xml:"autoFilter"`
+		}
+		part, found := parts["xl/"+targets[workbookSheet.RelID]]
+		require.True(t, found, "worksheet %s must be written", workbookSheet.Name)
+		require.NoError(t, xml.Unmarshal(part, &worksheet))
+
+		sheet := testXLSXSheet{Name: workbookSheet.Name, AutoFilter: worksheet.AutoFilter.Ref}
+		if worksheet.Pane.State == "frozen" {
+			sheet.Frozen = worksheet.Pane.TopLeftCell
+		}
+		for _, row := range worksheet.Rows {
+			var values []string
+			var cellStyles []int
+			for _, cell := range row.Cells {
+				values = append(values, cell.Value+cell.Inline)
+				cellStyles = append(cellStyles, cell.Style)
+			}
+			sheet.Rows = append(sheet.Rows, values)
+			sheet.Styles = append(sheet.Styles, cellStyles)
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sheets, string(parts["xl/styles.xml"])
+}
+
+func testXLSXList() []Satellite {
+	list := testExportList()
+	for i := range list {
+		list[i].SetSource("https://www.lyngsat.com/europe.html")
+	}
+	list[0].SetSource("https://www.lyngsat.com/america.html")
+	list[4].SetSource("")
+	list[2].SetTags([]string{"ku-europe"})
+	list[2].Annotations = map[string]string{"owner": "Eutelsat", "dish": "90cm"}
+	return list
+}
+
+func TestWriteXLSX(t *testing.T) {
+	list := testXLSXList()
+	changes := &SyncChanges{
+		Run:     SyncRun{RunID: "run-1", Started: "2020-01-02T03:04:05Z"},
+		Inserts: []Satellite{list[3]},
+		Closes:  []Satellite{makeSat("Old Bird", 7)},
+		Updates: []SyncUpdate{{Satellite: list[1], Record: HistoryRecord{Name: list[1].GetName(), Field: fieldBand,
+			OldValue: "C", NewValue: "Ku"}}},
+		Relocations: []Relocation{{Name: "Hot Bird 13E", FromPosition: 16, ToPosition: 13, Direction: driftWest}},
+	}
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteXLSX(&buffer, MakeXLSXSheets(list, changes)))
+	sheets, styles := readTestXLSX(t, buffer.Bytes())
+	assert.Contains(t, styles, `formatCode="0.0&quot;°E&quot;;0.0&quot;°W&quot;"`)
+
+	require.Len(t, sheets, 4)
+	names := make([]string, 0, len(sheets))
+	for _, sheet := range sheets {
+		names = append(names, sheet.Name)
+		assert.Equal(t, "A2", sheet.Frozen, "header of %s must be frozen", sheet.Name)
+	}
+	assert.Equal(t, []string{"america", "europe", "Other", "Changes"}, names)
+
+	europe := sheets[1]
+	assert.Equal(t, "A1:F4", europe.AutoFilter)
+	assert.Equal(t, [][]string{
+		{"Position", "Name", "Band", "URL", "Tags", "Annotations"},
+		{"-5", "Eutelsat 5 West B", "Ku", "", "", ""},
+		{"13", "Hot Bird 13E", "Ku", "", "ku-europe", "dish=90cm; owner=Eutelsat"},
+		{"19.2", "Astra 1KR & <1L>", "Ku", "", "", ""},
+	}, europe.Rows)
+	assert.Equal(t, []int{xlsxStyleHeader, xlsxStyleHeader, xlsxStyleHeader, xlsxStyleHeader, xlsxStyleHeader,
+		xlsxStyleHeader}, europe.Styles[0])
+	assert.Equal(t, xlsxStylePosition, europe.Styles[1][0], "positions must be formatted")
+
+	assert.Equal(t, [][]string{
+		{"Change", "Position", "Name", "Field", "Old value", "New value", "Direction", "Run", "Started"},
+		{"inserted", "19.2", "Astra 1KR & <1L>", "", "", "", "", "run-1", "2020-01-02 03:04:05"},
+		{"closed", "7", "Old Bird", "", "", "", "", "run-1", "2020-01-02 03:04:05"},
+		{"updated", "-5", "Eutelsat 5 West B", "band", "C", "Ku", "", "run-1", "2020-01-02 03:04:05"},
+		{"relocated", "13", "Hot Bird 13E", "position", "16.0°E", "13.0°E", "west", "run-1", "2020-01-02 03:04:05"},
+	}, sheets[3].Rows)
+}
+
+func TestWriteXLSXWithoutSheets(t *testing.T) {
+	assert.Error(t, WriteXLSX(&bytes.Buffer{}, nil))
+}
+
+func TestFindSyncChanges(t *testing.T) {
+	kept, moved, relocated := makeSat("kept", 1), makeSat("moved", 2), makeSat("relocated", 6)
+	movedAfter, relocatedAfter := makeSat("moved", 3), makeSat("relocated", 16)
+	relocatedAfter.SetBand("Ku")
+	before := []SatelliteVersion{{Satellite: kept}, {Satellite: moved}, {Satellite: makeSat("closed", 4)},
+		{Satellite: relocated}}
+	after := []SatelliteVersion{{Satellite: kept}, {Satellite: movedAfter}, {Satellite: makeSat("new", 5)},
+		{Satellite: relocatedAfter}}
+	history := []HistoryRecord{
+		{Name: "moved", Field: fieldPosition, OldValue: "2", NewValue: "3", RunID: "run-2"},
+		{Name: "kept", Field: fieldBand, OldValue: "", NewValue: "Ku", RunID: "run-1"},
+		{SatelliteID: 7, Name: "relocated", Field: fieldPosition, OldValue: "6", NewValue: "16", RunID: "run-2"},
+		{SatelliteID: 7, Name: "relocated", Field: fieldBand, OldValue: "", NewValue: "Ku", RunID: "run-2"},
+	}
+	relocations := []Relocation{
+		{SatelliteID: 7, Name: "relocated", FromPosition: 6, ToPosition: 16, Direction: driftEast, RunID: "run-2"},
+		{SatelliteID: 7, Name: "relocated", FromPosition: 1, ToPosition: 6, Direction: driftEast, RunID: "run-1"},
+	}
+
+	changes := FindSyncChanges(&SyncRun{RunID: "run-2"}, before, after, history, relocations)
+	assert.Equal(t, []Satellite{makeSat("new", 5)}, changes.Inserts)
+	assert.Equal(t, []Satellite{makeSat("closed", 4)}, changes.Closes)
+	assert.Equal(t, []SyncUpdate{{Satellite: movedAfter, Record: history[0]},
+		{Satellite: relocatedAfter, Record: history[3]}}, changes.Updates,
+		"position change of relocated satellite must be listed as relocation only")
+	assert.Equal(t, relocations[:1], changes.Relocations)
+}
+
+func TestXLSXNames(t *testing.T) {
+	assert.Equal(t, "A", xlsxColumnName(0))
+	assert.Equal(t, "Z", xlsxColumnName(25))
+	assert.Equal(t, "AA", xlsxColumnName(26))
+
+	used := map[string]bool{"changes": true}
+	assert.Equal(t, "changes 2", xlsxSheetName("changes", used))
+	assert.Equal(t, "a-b (c)", xlsxSheetName("a/b [c]", used))
+	assert.Equal(t, strings.Repeat("x", 31), xlsxSheetName(strings.Repeat("x", 40), used))
+	assert.Equal(t, strings.Repeat("x", 29)+" 2", xlsxSheetName(strings.Repeat("x", 35), used))
+	assert.Equal(t, "$A$1:$E$10", absoluteXLSXRange("A1:E10"))
+}
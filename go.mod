@@ -4,10 +4,12 @@ go 1.13
 
 require (
 	github.com/PuerkitoBio/goquery v1.5.1
+	github.com/artemkaxboy/configuration v0.0.0-20200109034048-a3def9c7c257
 	github.com/artemkaxboy/go-hocon v0.3.0
 	github.com/go-sql-driver/mysql v1.5.0
-	github.com/goreflect/go_hocon v0.0.2 // indirect
 	github.com/jmoiron/sqlx v1.2.0
+	github.com/lib/pq v1.3.0
+	github.com/mattn/go-sqlite3 v1.13.0
 	github.com/sirupsen/logrus v1.4.2
 	github.com/stretchr/testify v1.5.1
 	golang.org/x/net v0.0.0-20200202094626-16171245cfb2